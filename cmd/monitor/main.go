@@ -1,25 +1,41 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
 	"os/signal"
+	"sort"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/accursedgalaxy/insider-monitor/internal/alerts"
+	"github.com/accursedgalaxy/insider-monitor/internal/authority"
+	"github.com/accursedgalaxy/insider-monitor/internal/backfill"
 	"github.com/accursedgalaxy/insider-monitor/internal/config"
+	"github.com/accursedgalaxy/insider-monitor/internal/correlate"
+	"github.com/accursedgalaxy/insider-monitor/internal/holders"
 	"github.com/accursedgalaxy/insider-monitor/internal/monitor"
+	"github.com/accursedgalaxy/insider-monitor/internal/rules"
 	"github.com/accursedgalaxy/insider-monitor/internal/storage"
 	"github.com/accursedgalaxy/insider-monitor/internal/utils"
+	"github.com/accursedgalaxy/insider-monitor/internal/window"
 )
 
 // WalletScanner 接口定义了钱包监控的约定
 type WalletScanner interface {
-	ScanAllWallets() (map[string]*monitor.WalletData, error)
-	DisplayWalletOverview(walletDataMap map[string]*monitor.WalletData)
+	ScanAllWallets(ctx context.Context) (*monitor.ScanResult, error)
+	DisplayWalletOverview(walletDataMap map[string]*monitor.WalletData, statuses map[string]*monitor.WalletStatus)
+	NewStreamer(wsURL string, priceInterval time.Duration) *monitor.Streamer
+	RememberDecimals(data map[string]*monitor.WalletData)
+	AttributeChanges(ctx context.Context, changes []monitor.Change, oldData map[string]*monitor.WalletData)
+	StartHealthChecks(ctx context.Context)
+	UpdateScores(ctx context.Context, data map[string]*monitor.WalletData, changes []monitor.Change)
+	Backfill(ctx context.Context, options backfill.Options) ([]backfill.Summary, error)
+	VerifyFinality(ctx context.Context, tracker *monitor.FinalityTracker, now time.Time) []monitor.Change
+	ScanMints(ctx context.Context, mints []string, previous map[string]*holders.Snapshot, cfg config.HolderConfig) (map[string]*holders.Snapshot, []monitor.Change)
 }
 
 func main() {
@@ -27,6 +43,9 @@ func main() {
 	logger := utils.NewLogger(false)
 
 	configPath := flag.String("config", "config.json", "Path to configuration file")
+	backfillOnly := flag.Bool("backfill", false, "Backfill the transaction history of all wallets and exit")
+	replaySource := flag.String("replay", "", "Replay the wallet_data*.json snapshots in a directory (e.g. ./data), or \"history\" for backfilled transactions, and exit")
+	replayOut := flag.String("replay-out", "replay_alerts.jsonl", "File the alerts produced by -replay are written to")
 	flag.Parse()
 
 	// 打印欢迎信息
@@ -55,8 +74,22 @@ func main() {
 		logger.Fatal("Configuration validation failed:\n%v", err)
 	}
 
+	// 编译告警规则，表达式有误时在启动时报告
+	engine, err := rules.New(cfg.Alerts)
+	if err != nil {
+		logger.Fatal("Configuration validation failed:\n%v", err)
+	}
+
+	// 回放历史数据，评估当前配置会产生哪些告警
+	if *replaySource != "" {
+		if err := runReplay(cfg, engine, *replaySource, *replayOut, logger); err != nil {
+			logger.Fatal("Replay failed: %v", err)
+		}
+		return
+	}
+
 	// 初始化扫描器
-	scanner, err := monitor.NewWalletMonitor(cfg.NetworkURL, cfg.Wallets, &cfg.Scan)
+	scanner, err := monitor.NewWalletMonitorWithEndpoints(cfg.Endpoints(), cfg.Wallets, &cfg.Scan)
 	if err != nil {
 		logger.Fatal("Failed to create wallet monitor: %v\n\n"+
 			"💡 This usually means:\n"+
@@ -66,6 +99,27 @@ func main() {
 			"   Verify your wallet addresses are valid Solana addresses.", err)
 	}
 
+	// 解析回填参数
+	backfillOptions := backfill.Options{PageSize: cfg.Backfill.PageSize}
+	if cfg.Backfill.Horizon != "" {
+		backfillOptions.Horizon, _ = time.ParseDuration(cfg.Backfill.Horizon)
+	}
+
+	// 仅回填历史交易，完成或中断后退出，再次运行时从检查点继续
+	if *backfillOnly {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		scanner.StartHealthChecks(ctx)
+
+		logger.Scan("Backfilling transaction history of %d wallets...", len(cfg.Wallets))
+		if _, err := scanner.Backfill(ctx, backfillOptions); err != nil {
+			logger.Error("Backfill stopped: %v. Run again to resume from the last checkpoint.", err)
+			os.Exit(1)
+		}
+		logger.Success("Backfill complete")
+		return
+	}
+
 	// 初始化告警器
 	var alerter alerts.Alerter
 	if cfg.Discord.Enabled {
@@ -83,16 +137,52 @@ func main() {
 		scanInterval = time.Minute
 	}
 
-	runMonitor(scanner, alerter, cfg, scanInterval, logger)
+	// 解析单次扫描的截止时间，默认与扫描间隔一致
+	scanTimeout := scanInterval
+	if cfg.Scan.Timeout != "" {
+		if scanTimeout, err = time.ParseDuration(cfg.Scan.Timeout); err != nil {
+			logger.Warning("Invalid scan timeout '%s', using scan interval of %v", cfg.Scan.Timeout, scanInterval)
+			scanTimeout = scanInterval
+		}
+	}
+
+	runMonitor(scanner, alerter, engine, cfg, scanInterval, scanTimeout, backfillOptions, logger)
 }
 
-func runMonitor(scanner WalletScanner, alerter alerts.Alerter, cfg *config.Config, scanInterval time.Duration, logger *utils.Logger) {
+// 收到中断信号后等待进行中扫描退出的最长时间
+const shutdownGracePeriod = 5 * time.Second
+
+// new_wallet 告警中最多列出的持仓数
+const maxBaselineHoldings = 10
+
+// 检查暂存变化是否已最终确认的间隔，finalized 通常落后 confirmed 约 13 秒
+const finalityCheckInterval = 10 * time.Second
+
+// 未配置 correlation.window 时的关联窗口
+const defaultCorrelationWindow = 15 * time.Minute
+
+// 未配置 alerts.cooldown 时同一告警指纹的冷却期
+const defaultAlertCooldown = 15 * time.Minute
+
+// 检查冷却期已结束的被抑制告警并发送摘要的间隔
+const suppressionFlushInterval = 30 * time.Second
+
+func runMonitor(scanner WalletScanner, alerter alerts.Alerter, engine *rules.Engine, cfg *config.Config, scanInterval, scanTimeout time.Duration, backfillOptions backfill.Options, logger *utils.Logger) {
 	storage := storage.New("./data")
 
-	// 创建缓冲通道以便优雅关闭
-	interrupt := make(chan os.Signal, 1)
-	done := make(chan bool, 1)
-	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	// 收到 SIGINT/SIGTERM 时取消 ctx，以中止进行中的扫描
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// 定期探测 RPC 端点健康状况，落后或故障的端点暂不参与路由
+	scanner.StartHealthChecks(ctx)
+
+	// 每次扫描使用独立的截止时间
+	scan := func() (*monitor.ScanResult, error) {
+		scanCtx, cancel := context.WithTimeout(ctx, scanTimeout)
+		defer cancel()
+		return scanner.ScanAllWallets(scanCtx)
+	}
 
 	// 跟踪连接状态
 	var lastSuccessfulScan time.Time
@@ -105,15 +195,94 @@ func runMonitor(scanner WalletScanner, alerter alerts.Alerter, cfg *config.Confi
 	var previousData map[string]*monitor.WalletData
 	if savedData, err := storage.LoadWalletData(); err == nil {
 		previousData = savedData
+		// 铸币信息暂时无法解析时沿用上次快照中的小数位
+		scanner.RememberDecimals(previousData)
 		logger.Storage("Loaded previous wallet data from storage")
 	} else {
 		logger.Warning("Could not load previous data: %v. Will initialize after first scan.", err)
 		previousData = make(map[string]*monitor.WalletData)
 	}
 
+	// 加载各钱包的扫描健康状况
+	walletStatus, err := storage.LoadWalletStatus()
+	if err != nil {
+		logger.Warning("Could not load wallet status: %v", err)
+		walletStatus = make(map[string]*monitor.WalletStatus)
+	}
+
+	// 按钱包、铸币与告警类型对告警去重，冷却期状态在重启后保留，避免重新发送全部告警
+	suppressionState, err := storage.LoadSuppressionState()
+	if err != nil {
+		logger.Warning("Could not load alert suppression state: %v", err)
+		suppressionState = make(alerts.SuppressionState)
+	}
+	suppressor := alerts.NewSuppressor(alerter, alertCooldown(cfg.Alerts), suppressionState, storage.SaveSuppressionState)
+	alerter = suppressor
+
+	// 加载滚动窗口的余额序列，重启后继续累计
+	windowState, err := storage.LoadWindowState()
+	if err != nil {
+		logger.Warning("Could not load window state: %v", err)
+		windowState = make(window.State)
+	}
+	observeWindows := func(oldData, newData map[string]*monitor.WalletData) []monitor.Change {
+		if len(cfg.Windows) == 0 {
+			return nil
+		}
+		changes := monitor.ObserveWindows(windowState, oldData, newData, cfg.Windows, time.Now())
+		if err := storage.SaveWindowState(windowState); err != nil {
+			logger.Error("Error saving window state: %v", err)
+		}
+		return changes
+	}
+
+	// 按 alerts.finality 暂存未最终确认的变化
+	finality := monitor.NewFinalityTracker(cfg.Alerts.Finality)
+	// 跨钱包关联只处理可以告警的变化，未最终确认而暂存的变化在确认后才参与关联
+	correlator := newCorrelator(cfg.Correlation)
+	alertChanges := func(changes []monitor.Change) {
+		now := time.Now()
+		processChanges(correlateChanges(correlator, engine, finality.Hold(changes, now), now), alerter, engine, now, logger)
+	}
+
+	// 更新钱包健康状况，并对连续扫描失败的钱包发出告警。所有钱包均失败时返回错误
+	recordScan := func(result *monitor.ScanResult) error {
+		failing := monitor.UpdateWalletStatus(walletStatus, result, cfg.Alerts.WalletFailureThreshold, time.Now())
+		processChanges(failing, alerter, engine, time.Now(), logger)
+		if err := storage.SaveWalletStatus(walletStatus); err != nil {
+			logger.Error("Error saving wallet status: %v", err)
+		}
+
+		if result.AllFailed() {
+			return fmt.Errorf("all %d wallets failed to scan", len(result.Errors))
+		}
+		return nil
+	}
+
+	// 每个扫描间隔最多保存一份带时间戳的快照，供 -replay 回放
+	var lastSnapshot time.Time
+	saveSnapshot := func(data map[string]*monitor.WalletData) {
+		now := time.Now()
+		if now.Sub(lastSnapshot) < scanInterval {
+			return
+		}
+		if err := storage.SaveSnapshot(data, now); err != nil {
+			logger.Error("Error saving snapshot: %v", err)
+			return
+		}
+		lastSnapshot = now
+	}
+
 	// 立即执行初始扫描
 	logger.Scan("Performing initial wallet scan...")
-	initialResults, err := scanner.ScanAllWallets()
+	initialResults, err := scan()
+	if ctx.Err() != nil {
+		logger.Info("Shutting down gracefully...")
+		return
+	}
+	if err == nil {
+		err = recordScan(initialResults)
+	}
 	if err != nil {
 		logger.Error("Initial scan failed: %v", err)
 		logger.Error("\n💡 Common solutions:")
@@ -123,16 +292,121 @@ func runMonitor(scanner WalletScanner, alerter alerts.Alerter, cfg *config.Confi
 		logger.Error("   • Try a different RPC provider if rate limited")
 		logger.Error("\nThe monitor will continue trying in the background...")
 	} else {
-		if err := storage.SaveWalletData(initialResults); err != nil {
+		// 扫描失败的钱包保留已存储的数据
+		merged := mergeWalletData(previousData, initialResults.Wallets)
+		if err := storage.SaveWalletData(merged); err != nil {
 			logger.Error("Error saving initial data: %v", err)
 		}
+		saveSnapshot(merged)
 		lastSuccessfulScan = time.Now()
-		logger.Success("Initial scan complete. Found data for %d wallets", len(initialResults))
-		scanner.DisplayWalletOverview(initialResults)
+		logger.Success("Initial scan complete. Found data for %d wallets", len(initialResults.Wallets))
+		scanner.UpdateScores(ctx, initialResults.Wallets, nil)
+		scanner.DisplayWalletOverview(initialResults.Wallets, walletStatus)
 	}
 
+	// 处理流式订阅推送的单个钱包更新
+	handleUpdate := func(walletAddr string, oldData, newData *monitor.WalletData) {
+		// oldData 为空表示新加入监控的钱包，DetectChanges 会为其生成基线告警
+		previous := make(map[string]*monitor.WalletData)
+		if oldData != nil {
+			previous[walletAddr] = oldData
+		}
+		changes := monitor.DetectChanges(
+			previous,
+			map[string]*monitor.WalletData{walletAddr: newData},
+			engine.MinChangePercent(),
+		)
+		scanner.AttributeChanges(ctx, changes, previous)
+		// 窗口内的累计变化跨越多次扫描，不按本次扫描归因
+		changes = append(changes, observeWindows(previous, map[string]*monitor.WalletData{walletAddr: newData})...)
+		scanner.UpdateScores(ctx, map[string]*monitor.WalletData{walletAddr: newData}, changes)
+		alertChanges(changes)
+
+		if err := storage.SaveWalletData(previousData); err != nil {
+			logger.Error("Error saving data: %v", err)
+		}
+		saveSnapshot(previousData)
+	}
+
+	// 在后台回填历史交易，与监控共享 RPC 端点的速率限制
+	backfillStopped := make(chan struct{})
+	go func() {
+		defer close(backfillStopped)
+		if !cfg.Backfill.Enabled || len(cfg.Wallets) == 0 {
+			return
+		}
+		logger.Scan("Backfilling transaction history of %d wallets in the background...", len(cfg.Wallets))
+		if _, err := scanner.Backfill(ctx, backfillOptions); err != nil && ctx.Err() == nil {
+			logger.Warning("Backfill incomplete: %v. It will resume on the next start.", err)
+		}
+	}()
+
+	// 定期确认暂存的变化，发送已最终确认的告警或撤回未能最终确认的告警
+	finalityStopped := make(chan struct{})
+	go func() {
+		defer close(finalityStopped)
+		if cfg.Alerts.Finality == "" {
+			return
+		}
+		ticker := time.NewTicker(finalityCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if finality.Pending() == 0 {
+					continue
+				}
+				now := time.Now()
+				finalized := scanner.VerifyFinality(ctx, finality, now)
+				processChanges(correlateChanges(correlator, engine, finalized, now), alerter, engine, now, logger)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// 定期将冷却期已结束的被抑制告警合并为摘要发送
+	suppressionStopped := make(chan struct{})
+	go func() {
+		defer close(suppressionStopped)
+		ticker := time.NewTicker(suppressionFlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				suppressor.Flush(time.Now())
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// 按扫描间隔跟踪所配置铸币的主要持有者，与钱包监控相互独立
+	holdersStopped := make(chan struct{})
+	go func() {
+		defer close(holdersStopped)
+		if len(cfg.Mints) > 0 {
+			monitorHolders(ctx, scanner, alerter, engine, cfg, scanInterval, scanTimeout, storage, logger)
+		}
+	}()
+
 	// 在单独的 goroutine 中开始监控
+	stopped := make(chan struct{})
 	go func() {
+		defer close(stopped)
+
+		// 优先使用 WebSocket 流式订阅，不可用时回退到轮询
+		if cfg.Stream.Enabled {
+			logger.Network("Starting WebSocket streaming via %s", cfg.WebSocketURL())
+			err := scanner.NewStreamer(cfg.WebSocketURL(), scanInterval).Run(ctx, previousData, handleUpdate)
+			if ctx.Err() != nil {
+				return
+			}
+			logger.Warning("Streaming unavailable: %v. Falling back to polling every %v", err, scanInterval)
+			// 流式订阅期间不更新扫描时间，从回退时刻开始计算，避免首次轮询即判定连接丢失
+			lastSuccessfulScan = time.Now()
+		}
+
 		ticker := time.NewTicker(scanInterval)
 		defer ticker.Stop()
 
@@ -148,7 +422,13 @@ func runMonitor(scanner WalletScanner, alerter alerts.Alerter, cfg *config.Confi
 					continue
 				}
 
-				newResults, err := scanner.ScanAllWallets()
+				newResults, err := scan()
+				if ctx.Err() != nil {
+					return
+				}
+				if err == nil {
+					err = recordScan(newResults)
+				}
 				if err != nil {
 					logger.Error("Error scanning wallets: %v", err)
 					if !connectionLost {
@@ -174,23 +454,28 @@ func runMonitor(scanner WalletScanner, alerter alerts.Alerter, cfg *config.Confi
 
 				// 仅在存在历史数据时处理变化
 				if len(previousData) > 0 {
-					changes := monitor.DetectChanges(previousData, newResults, cfg.Alerts.SignificantChange)
-					processChanges(changes, alerter, cfg.Alerts, logger)
+					changes := monitor.DetectChanges(previousData, newResults.Wallets, engine.MinChangePercent())
+					scanner.AttributeChanges(ctx, changes, previousData)
+					changes = append(changes, observeWindows(previousData, newResults.Wallets)...)
+					scanner.UpdateScores(ctx, newResults.Wallets, changes)
+					alertChanges(changes)
 				} else {
 					// 第一次扫描，仅存储数据而不生成告警
 					logger.Info("Initial scan completed, storing baseline data")
+					scanner.UpdateScores(ctx, newResults.Wallets, nil)
 				}
 
-				// 保存新的结果
-				if err := storage.SaveWalletData(newResults); err != nil {
+				// 仅更新扫描成功的钱包，失败的钱包保留上一次数据以便下次比较
+				previousData = mergeWalletData(previousData, newResults.Wallets)
+				if err := storage.SaveWalletData(previousData); err != nil {
 					logger.Error("Error saving data: %v", err)
 				}
-				previousData = newResults
+				saveSnapshot(previousData)
 
 				// 展示钱包概览
-				scanner.DisplayWalletOverview(newResults)
+				scanner.DisplayWalletOverview(newResults.Wallets, walletStatus)
 
-			case <-done:
+			case <-ctx.Done():
 				logger.Info("Monitoring loop stopped")
 				return
 			}
@@ -198,16 +483,66 @@ func runMonitor(scanner WalletScanner, alerter alerts.Alerter, cfg *config.Confi
 	}()
 
 	// 等待中断信号
-	<-interrupt
+	<-ctx.Done()
 	logger.Info("Shutting down gracefully...")
 	if err := monitor.LogToFile("./data", "Monitor shutting down gracefully"); err != nil {
 		logger.Error("Failed to write shutdown log: %v", err)
 	}
-	done <- true
-	time.Sleep(time.Second) // 留出一点时间用于最后清理
+
+	// 等待进行中的扫描中止
+	deadline := time.After(shutdownGracePeriod)
+	for _, done := range []chan struct{}{stopped, holdersStopped, backfillStopped, finalityStopped, suppressionStopped} {
+		select {
+		case <-done:
+		case <-deadline:
+			logger.Warning("Monitoring loop did not stop within %v", shutdownGracePeriod)
+			return
+		}
+	}
+}
+
+// monitorHolders 定期获取所配置铸币的主要持有者快照，并对卖出、新进入前 N 名与集中度变化发出告警。
+// 首次获取的快照仅作为基线
+func monitorHolders(ctx context.Context, scanner WalletScanner, alerter alerts.Alerter, engine *rules.Engine, cfg *config.Config, scanInterval, scanTimeout time.Duration, storage *storage.Storage, logger *utils.Logger) {
+	snapshots, err := storage.LoadHolderSnapshots()
+	if err != nil {
+		logger.Warning("Could not load holder snapshots: %v", err)
+		snapshots = make(map[string]*holders.Snapshot)
+	}
+
+	scan := func() {
+		scanCtx, cancel := context.WithTimeout(ctx, scanTimeout)
+		defer cancel()
+
+		updated, changes := scanner.ScanMints(scanCtx, cfg.Mints, snapshots, cfg.Holders)
+		if ctx.Err() != nil {
+			return
+		}
+		processChanges(changes, alerter, engine, time.Now(), logger)
+
+		snapshots = updated
+		if err := storage.SaveHolderSnapshots(snapshots); err != nil {
+			logger.Error("Error saving holder snapshots: %v", err)
+		}
+	}
+
+	logger.Scan("Tracking top holders of %d mints...", len(cfg.Mints))
+	scan()
+
+	ticker := time.NewTicker(scanInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			scan()
+		case <-ctx.Done():
+			return
+		}
+	}
 }
 
-func processChanges(changes []monitor.Change, alerter alerts.Alerter, alertCfg config.AlertConfig, logger *utils.Logger) {
+// processChanges 为变化生成告警，告警级别由规则引擎决定，now 为告警时间（回放时为模拟时间）
+func processChanges(changes []monitor.Change, alerter alerts.Alerter, engine *rules.Engine, now time.Time, logger *utils.Logger) {
 	for _, change := range changes {
 		var msg string
 		var level alerts.AlertLevel
@@ -215,62 +550,347 @@ func processChanges(changes []monitor.Change, alerter alerts.Alerter, alertCfg c
 
 		switch change.ChangeType {
 		case "new_wallet":
-			// 为所有代币创建汇总消息
+			// 按美元价值从高到低列出持仓，价值相同时按数量排序
+			mints := make([]string, 0, len(change.TokenBalances))
+			for mint := range change.TokenBalances {
+				mints = append(mints, mint)
+			}
+			sort.Slice(mints, func(i, j int) bool {
+				vi, vj := change.TokenValues[mints[i]], change.TokenValues[mints[j]]
+				if vi != vj {
+					return vi > vj
+				}
+				return change.TokenBalances[mints[i]] > change.TokenBalances[mints[j]]
+			})
+
 			var tokenDetails []string
-			tokenData := make(map[string]uint64)
-			tokenDecimals := make(map[string]uint8)
-			for mint, balance := range change.TokenBalances {
-				tokenDetails = append(tokenDetails, fmt.Sprintf("%s: %d", mint, balance))
-				tokenData[mint] = balance
-				tokenDecimals[mint] = 9 // 默认小数位；如有实际小数位请调整
+			for i, mint := range mints {
+				if i == maxBaselineHoldings {
+					tokenDetails = append(tokenDetails, fmt.Sprintf("... and %d more tokens", len(mints)-maxBaselineHoldings))
+					break
+				}
+				label := change.TokenSymbols[mint]
+				if label == "" {
+					label = mint
+				}
+				detail := fmt.Sprintf("%s: %s", label, utils.FormatTokenAmount(change.TokenBalances[mint], change.TokenDecimalsMap[mint]))
+				if value := change.TokenValues[mint]; value > 0 {
+					detail += fmt.Sprintf(" ($%.2f)", value)
+				}
+				tokenDetails = append(tokenDetails, detail)
 			}
-			msg = fmt.Sprintf("New wallet %s detected with %d tokens:\n%s",
+
+			msg = fmt.Sprintf("New wallet %s detected with %d tokens worth $%.2f:\n%s",
 				change.WalletAddress,
 				len(change.TokenBalances),
+				change.TotalUSDValue(),
 				strings.Join(tokenDetails, "\n"))
 			level = alerts.Warning
 			alertData = map[string]interface{}{
-				"token_balances": tokenData,
-				"token_decimals": tokenDecimals,
+				"token_balances":  change.TokenBalances,
+				"token_decimals":  change.TokenDecimalsMap,
+				"token_symbols":   change.TokenSymbols,
+				"token_values":    change.TokenValues,
+				"total_usd_value": change.TotalUSDValue(),
+			}
+
+		case monitor.ChangeRetracted:
+			msg = fmt.Sprintf("Retracted %s alert for %s (%s): the change seen at %s slot %d never finalized",
+				change.RetractedType, change.TokenSymbol, change.TokenMint, change.Commitment, change.Slot)
+			level = alerts.Warning
+			alertData = map[string]interface{}{
+				"retracted_type": change.RetractedType,
+				"old_balance":    change.OldBalance,
+				"new_balance":    change.NewBalance,
+				"decimals":       change.TokenDecimals,
+				"symbol":         change.TokenSymbol,
+				"name":           change.TokenName,
+				"image":          change.TokenImage,
+			}
+
+		case "wallet_failing":
+			msg = fmt.Sprintf("Wallet %s has failed %d consecutive scans: %s",
+				change.WalletAddress, change.FailureCount, change.LastError)
+			level = alerts.Warning
+			alertData = map[string]interface{}{
+				"failure_count": change.FailureCount,
+				"last_error":    change.LastError,
+				"last_success":  change.LastSuccess,
 			}
 
 		case "new_token":
-			msg = fmt.Sprintf("New token %s (%s) detected in wallet with initial balance %d",
-				change.TokenSymbol, change.TokenMint, change.NewBalance)
+			msg = fmt.Sprintf("New token %s (%s) detected in wallet with initial balance %s",
+				change.TokenSymbol, change.TokenMint,
+				utils.FormatTokenAmount(change.NewBalance, change.TokenDecimals))
 			level = alerts.Warning
 			alertData = map[string]interface{}{
-				"balance":  change.NewBalance,
-				"decimals": change.TokenDecimals,
-				"symbol":   change.TokenSymbol,
+				"balance":      change.NewBalance,
+				"decimals":     change.TokenDecimals,
+				"symbol":       change.TokenSymbol,
+				"name":         change.TokenName,
+				"image":        change.TokenImage,
+				"program":      change.TokenProgram,
+				"extensions":   change.TokenFlags,
+				"usd_value":    change.USDValue(),
+				"supply_share": change.SupplyShare,
 			}
 
-		case "balance_change":
-			msg = fmt.Sprintf("Balance change for %s (%s): from %d to %d (%.2f%%)",
+			// 附带铸币与冻结权限的风险摘要
+			if change.Authorities != nil {
+				msg = fmt.Sprintf("%s (risk: %s)", msg, change.Authorities.Summary(now))
+				addAuthorityData(alertData, change.Authorities, now)
+			}
+
+		case "authority_change":
+			msg = fmt.Sprintf("Authorities of %s (%s) changed:\n%s",
+				change.TokenSymbol, change.TokenMint, strings.Join(change.AuthorityChanges, "\n"))
+
+			// 放弃权限降低风险；转移给新地址意味着仍可增发或冻结持仓
+			level = alerts.Warning
+			if change.PreviousAuthorities != nil && authority.OnlyRenounced(*change.PreviousAuthorities, *change.Authorities) {
+				level = alerts.Info
+			}
+
+			alertData = map[string]interface{}{
+				"details":    change.AuthorityChanges,
+				"balance":    change.NewBalance,
+				"decimals":   change.TokenDecimals,
+				"symbol":     change.TokenSymbol,
+				"name":       change.TokenName,
+				"image":      change.TokenImage,
+				"program":    change.TokenProgram,
+				"extensions": change.TokenFlags,
+				"usd_value":  change.USDValue(),
+			}
+			addAuthorityData(alertData, change.Authorities, now)
+
+		case "token_exit":
+			// 全部清仓是最重要的事件，始终以最高级别告警
+			msg = fmt.Sprintf("Full exit from %s (%s): entire balance of %s sold, moved or burned (last value $%.2f)",
 				change.TokenSymbol, change.TokenMint,
-				change.OldBalance, change.NewBalance, change.ChangePercent)
+				utils.FormatTokenAmount(change.OldBalance, change.TokenDecimals),
+				change.LastUSDValue)
+			level = alerts.Critical
+			alertData = map[string]interface{}{
+				"old_balance":    change.OldBalance,
+				"new_balance":    change.NewBalance,
+				"decimals":       change.TokenDecimals,
+				"symbol":         change.TokenSymbol,
+				"name":           change.TokenName,
+				"image":          change.TokenImage,
+				"change_percent": change.ChangePercent,
+				"program":        change.TokenProgram,
+				"extensions":     change.TokenFlags,
+				"last_usd_value": change.LastUSDValue,
+				"supply_share":   change.SupplyShare,
+			}
+
+		case "account_state":
+			state := change.AccountState
+			details := state.Describe(change.TokenDecimals)
+			msg = fmt.Sprintf("Token account %s for %s (%s) changed state:\n%s",
+				state.Address, change.TokenSymbol, change.TokenMint, strings.Join(details, "\n"))
 
-			absChange := abs(change.ChangePercent)
+			// 冻结意味着资产无法转出，委托与关闭权限变化可能是抛售或被盗的前兆
 			switch {
-			case absChange >= (alertCfg.SignificantChange * 5):
+			case state.HasEvent(monitor.EventFrozen):
 				level = alerts.Critical
-			case absChange >= (alertCfg.SignificantChange * 2):
-				level = alerts.Warning
-			default:
+			case state.Benign():
 				level = alerts.Info
+			default:
+				level = alerts.Warning
+			}
+
+			alertData = map[string]interface{}{
+				"account":          state.Address,
+				"events":           state.Events,
+				"details":          details,
+				"delegate":         state.New.Delegate,
+				"delegated_amount": state.New.DelegatedAmount,
+				"close_authority":  state.New.CloseAuthority,
+				"state":            state.New.State,
+				"balance":          change.NewBalance,
+				"decimals":         change.TokenDecimals,
+				"symbol":           change.TokenSymbol,
+				"name":             change.TokenName,
+				"image":            change.TokenImage,
+				"program":          change.TokenProgram,
+				"extensions":       change.TokenFlags,
+				"usd_value":        change.USDValue(),
+			}
+
+		case holders.EventHolderSell:
+			msg = fmt.Sprintf("Top holder #%d of %s (%s) sold %.2f%% of their position: from %s to %s",
+				change.PreviousRank, change.TokenSymbol, change.TokenMint, -change.ChangePercent,
+				utils.FormatTokenAmount(change.OldBalance, change.TokenDecimals),
+				utils.FormatTokenAmount(change.NewBalance, change.TokenDecimals))
+
+			// 主要持有者清仓以最高级别告警
+			level = alerts.Warning
+			if change.NewBalance == 0 {
+				level = alerts.Critical
+			}
+			alertData = holderAlertData(change)
+
+		case holders.EventNewTopHolder:
+			msg = fmt.Sprintf("New wallet entered the top holders of %s (%s) at rank #%d with %s (%.4f%% of supply)",
+				change.TokenSymbol, change.TokenMint, change.HolderRank,
+				utils.FormatTokenAmount(change.NewBalance, change.TokenDecimals),
+				change.SupplyShare)
+			level = alerts.Warning
+			alertData = holderAlertData(change)
+
+		case holders.EventConcentration:
+			direction := "rose"
+			if change.Concentration < change.PreviousConcentration {
+				direction = "fell"
+			}
+			msg = fmt.Sprintf("Top-%d holder concentration of %s (%s) %s past the threshold: from %.2f%% to %.2f%%",
+				holders.ConcentrationHolders, change.TokenSymbol, change.TokenMint,
+				direction, change.PreviousConcentration, change.Concentration)
+			level = alerts.Warning
+			alertData = holderAlertData(change)
+
+		case window.EventAccumulation, window.EventDistribution:
+			verb := "accumulated"
+			if change.ChangeType == window.EventDistribution {
+				verb = "distributed"
+			}
+			msg = fmt.Sprintf("%s (%s) %s over %s: from %s to %s (%.2f%%) in %d movements",
+				change.TokenSymbol, change.TokenMint, verb, utils.FormatDuration(change.Window),
+				utils.FormatTokenAmount(change.OldBalance, change.TokenDecimals),
+				utils.FormatTokenAmount(change.NewBalance, change.TokenDecimals),
+				change.ChangePercent, change.Movements)
+			level = alerts.Warning
+			alertData = map[string]interface{}{
+				"window":         utils.FormatDuration(change.Window),
+				"window_start":   change.WindowStart,
+				"inflow":         change.Inflow,
+				"outflow":        change.Outflow,
+				"movements":      change.Movements,
+				"old_balance":    change.OldBalance,
+				"new_balance":    change.NewBalance,
+				"decimals":       change.TokenDecimals,
+				"symbol":         change.TokenSymbol,
+				"name":           change.TokenName,
+				"image":          change.TokenImage,
+				"change_percent": change.ChangePercent,
+				"program":        change.TokenProgram,
+				"usd_value":      change.USDValue(),
+				"supply_share":   change.SupplyShare,
+			}
+
+		case monitor.ChangeCoordinatedBuy, monitor.ChangeCoordinatedSell:
+			verb := "bought"
+			level = alerts.Warning
+			if change.ChangeType == monitor.ChangeCoordinatedSell {
+				verb = "sold"
+				level = alerts.Critical
 			}
 
+			// 按开始变动的先后列出参与钱包
+			wallets := make([]string, len(change.Participants))
+			details := make([]string, len(change.Participants))
+			for i, participant := range change.Participants {
+				wallets[i] = participant.Wallet
+				amount := utils.FormatTokenAmount(participant.NewBalance, change.TokenDecimals)
+				if participant.Action != correlate.ActionEntered {
+					amount = fmt.Sprintf("%s → %s",
+						utils.FormatTokenAmount(participant.OldBalance, change.TokenDecimals), amount)
+				}
+				details[i] = fmt.Sprintf("%d. %s %s %s", i+1, participant.Wallet, participant.Action, amount)
+				if value := participant.USDValue(); value > 0 {
+					details[i] += fmt.Sprintf(" ($%.2f)", value)
+				}
+				details[i] += " at " + participant.Time.UTC().Format("15:04:05 MST")
+			}
+
+			msg = fmt.Sprintf("%d wallets %s %s (%s) within %s:\n%s",
+				len(change.Participants), verb, change.TokenSymbol, change.TokenMint,
+				utils.FormatDuration(change.Window), strings.Join(details, "\n"))
+			alertData = map[string]interface{}{
+				"window":         utils.FormatDuration(change.Window),
+				"window_start":   change.WindowStart,
+				"wallets":        wallets,
+				"participants":   details,
+				"old_balance":    change.OldBalance,
+				"new_balance":    change.NewBalance,
+				"decimals":       change.TokenDecimals,
+				"symbol":         change.TokenSymbol,
+				"name":           change.TokenName,
+				"image":          change.TokenImage,
+				"change_percent": change.ChangePercent,
+				"program":        change.TokenProgram,
+				"usd_value":      change.USDValue(),
+			}
+
+		case "balance_change":
+			msg = fmt.Sprintf("Balance change for %s (%s): from %s to %s (%.2f%%)",
+				change.TokenSymbol, change.TokenMint,
+				utils.FormatTokenAmount(change.OldBalance, change.TokenDecimals),
+				utils.FormatTokenAmount(change.NewBalance, change.TokenDecimals),
+				change.ChangePercent)
+
+			// 级别由规则引擎按 significant_change 的倍数计算
+			level = alerts.Info
 			alertData = map[string]interface{}{
 				"old_balance":    change.OldBalance,
 				"new_balance":    change.NewBalance,
 				"decimals":       change.TokenDecimals,
 				"symbol":         change.TokenSymbol,
+				"name":           change.TokenName,
+				"image":          change.TokenImage,
 				"change_percent": change.ChangePercent,
+				"program":        change.TokenProgram,
+				"extensions":     change.TokenFlags,
+				"usd_value":      change.USDValue(),
+				"supply_share":   change.SupplyShare,
 			}
 		}
 
-		if level >= alerts.Warning {
+		// 按规则、忽略列表与阈值决定告警级别，未达到阈值的告警仅记录日志
+		decision := engine.Evaluate(change, level, now)
+		if decision.Ignore {
+			continue
+		}
+		level = decision.Level
+		if decision.Reason != "" {
+			msg = fmt.Sprintf("%s (%s)", msg, decision.Reason)
+		}
+		if alertData != nil && decision.Rule != "" {
+			alertData["rule"] = decision.Rule
+		}
+		if alertData != nil && change.InsiderScore > 0 {
+			alertData["insider_score"] = change.InsiderScore
+		}
+
+		// 记录检测到变化的 slot，尚未最终确认的变化在消息中注明
+		if alertData != nil && change.Slot > 0 {
+			alertData["slot"] = change.Slot
+			alertData["commitment"] = change.Commitment
+		}
+		if change.Unfinalized {
+			msg = fmt.Sprintf("%s (unfinalized: seen at %s slot %d)", msg, change.Commitment, change.Slot)
+			if alertData != nil {
+				alertData["unfinalized"] = true
+			}
+		}
+
+		addAttribution(alertData, change)
+		if alertData != nil && len(change.AccountChanges) > 0 {
+			moved := make([]string, len(change.AccountChanges))
+			for i, account := range change.AccountChanges {
+				moved[i] = account.Address
+			}
+			alertData["moved_accounts"] = moved
+		}
+		if activity := change.Activity(); activity != "" {
+			msg = fmt.Sprintf("%s [%s]", msg, activity)
+		}
+
+		if level.AtLeast(alerts.Warning) {
 			alert := alerts.Alert{
-				Timestamp:     time.Now(),
+				Timestamp:     now,
 				WalletAddress: change.WalletAddress,
 				TokenMint:     change.TokenMint,
 				AlertType:     change.ChangeType,
@@ -288,9 +908,122 @@ func processChanges(changes []monitor.Change, alerter alerts.Alerter, alertCfg c
 	}
 }
 
-func abs(x float64) float64 {
-	if x < 0 {
-		return -x
+// newCorrelator 按配置创建跨钱包关联器，未启用时返回 nil
+func newCorrelator(cfg config.CorrelationConfig) *correlate.Correlator {
+	if cfg.MinWallets == 0 {
+		return nil
+	}
+	duration := defaultCorrelationWindow
+	if cfg.Window != "" {
+		// 配置已通过 Validate 校验
+		duration, _ = time.ParseDuration(cfg.Window)
+	}
+	return correlate.New(cfg.MinWallets, duration)
+}
+
+// alertCooldown 返回同一告警指纹的冷却期，0 表示不去重
+func alertCooldown(cfg config.AlertConfig) time.Duration {
+	if cfg.Cooldown == "" {
+		return defaultAlertCooldown
+	}
+	// 配置已通过 Validate 校验
+	cooldown, _ := time.ParseDuration(cfg.Cooldown)
+	return cooldown
+}
+
+// correlateChanges 将未被规则忽略的变化交给关联器，返回追加了新形成集群的变化列表。
+// 被忽略的钱包、代币与未达到 significant_change 的余额变化不计入集群
+func correlateChanges(correlator *correlate.Correlator, engine *rules.Engine, changes []monitor.Change, now time.Time) []monitor.Change {
+	if correlator == nil || len(changes) == 0 {
+		return changes
+	}
+	var counted []monitor.Change
+	for _, change := range changes {
+		if !engine.Evaluate(change, alerts.Info, now).Ignore {
+			counted = append(counted, change)
+		}
+	}
+	return append(changes, monitor.CorrelateChanges(correlator, counted, now)...)
+}
+
+// mergeWalletData 返回 base 的副本，并以 updates 中的钱包数据覆盖
+func mergeWalletData(base, updates map[string]*monitor.WalletData) map[string]*monitor.WalletData {
+	merged := make(map[string]*monitor.WalletData, len(base)+len(updates))
+	for walletAddr, data := range base {
+		merged[walletAddr] = data
+	}
+	for walletAddr, data := range updates {
+		merged[walletAddr] = data
+	}
+	return merged
+}
+
+// holderAlertData 构建主要持有者告警的数据
+func holderAlertData(change monitor.Change) map[string]interface{} {
+	return map[string]interface{}{
+		"old_balance":            change.OldBalance,
+		"new_balance":            change.NewBalance,
+		"decimals":               change.TokenDecimals,
+		"symbol":                 change.TokenSymbol,
+		"name":                   change.TokenName,
+		"image":                  change.TokenImage,
+		"change_percent":         change.ChangePercent,
+		"supply_share":           change.SupplyShare,
+		"rank":                   change.HolderRank,
+		"previous_rank":          change.PreviousRank,
+		"concentration":          change.Concentration,
+		"previous_concentration": change.PreviousConcentration,
 	}
-	return x
+}
+
+// addAuthorityData 将铸币与冻结权限及其风险写入告警数据
+func addAuthorityData(alertData map[string]interface{}, authorities *authority.Authorities, now time.Time) {
+	alertData["mint_authority"] = authorities.MintAuthority
+	alertData["freeze_authority"] = authorities.FreezeAuthority
+	alertData["authority_risks"] = authorities.Risks(now)
+}
+
+// addAttribution 将导致变化的交易信息写入告警数据
+func addAttribution(alertData map[string]interface{}, change monitor.Change) {
+	if alertData == nil || len(change.Transactions) == 0 {
+		return
+	}
+
+	var signatures, counterparties, programs []string
+	seen := make(map[string]bool)
+	for _, tx := range change.Transactions {
+		signatures = append(signatures, tx.Signature)
+		for _, address := range tx.Counterparties {
+			if !seen["c:"+address] {
+				seen["c:"+address] = true
+				counterparties = append(counterparties, address)
+			}
+		}
+		for _, program := range tx.Programs {
+			if !seen["p:"+program] {
+				seen["p:"+program] = true
+				programs = append(programs, program)
+			}
+		}
+	}
+
+	if change.Kind != "" {
+		alertData["kind"] = change.Kind
+		alertData["venue"] = change.Venue
+		alertData["activity"] = change.Activity()
+		if change.QuoteMint != "" {
+			alertData["quote_mint"] = change.QuoteMint
+			alertData["quote_symbol"] = change.QuoteSymbol()
+			alertData["quote_amount"] = change.QuoteAmount
+			alertData["quote_decimals"] = change.QuoteDecimals
+		}
+	}
+
+	latest := change.Transactions[0]
+	alertData["signature"] = latest.Signature
+	alertData["tx_slot"] = latest.Slot
+	alertData["block_time"] = latest.BlockTime
+	alertData["signatures"] = signatures
+	alertData["counterparties"] = counterparties
+	alertData["programs"] = programs
 }
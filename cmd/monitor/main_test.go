@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/accursedgalaxy/insider-monitor/internal/alerts"
+	"github.com/accursedgalaxy/insider-monitor/internal/attribution"
+	"github.com/accursedgalaxy/insider-monitor/internal/authority"
+	"github.com/accursedgalaxy/insider-monitor/internal/config"
+	"github.com/accursedgalaxy/insider-monitor/internal/correlate"
+	"github.com/accursedgalaxy/insider-monitor/internal/holders"
+	"github.com/accursedgalaxy/insider-monitor/internal/monitor"
+	"github.com/accursedgalaxy/insider-monitor/internal/rules"
+	"github.com/accursedgalaxy/insider-monitor/internal/utils"
+	"github.com/accursedgalaxy/insider-monitor/internal/window"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingAlerter struct {
+	alerts []alerts.Alert
+}
+
+func (r *recordingAlerter) SendAlert(alert alerts.Alert) error {
+	r.alerts = append(r.alerts, alert)
+	return nil
+}
+
+// TestAlertDataSurvivesSuppressionState 确认 processChanges 写入的每一项附加数据
+// 在保存并重新读取告警抑制状态后保持原有的值与类型
+func TestAlertDataSurvivesSuppressionState(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	base := monitor.Change{
+		WalletAddress: "wallet",
+		TokenMint:     "mint",
+		TokenSymbol:   "TKN",
+		TokenName:     "Token",
+		TokenImage:    "https://example.com/tkn.png",
+		TokenDecimals: 6,
+		TokenProgram:  monitor.ProgramToken2022,
+		TokenFlags:    []string{"transfer-fee"},
+		OldBalance:    1_000_000,
+		NewBalance:    2_500_000,
+		ChangePercent: 150,
+		USDPrice:      2,
+		SupplyShare:   0.5,
+		InsiderScore:  80,
+		Slot:          200,
+		Commitment:    "confirmed",
+		Unfinalized:   true,
+		Kind:          "buy",
+		Venue:         "Jupiter",
+		QuoteMint:     "So11111111111111111111111111111111111111112",
+		QuoteAmount:   1_000_000_000,
+		QuoteDecimals: 9,
+		Transactions: []attribution.Attribution{{
+			Signature:      "sig",
+			Slot:           199,
+			BlockTime:      now.Add(-time.Minute),
+			Counterparties: []string{"seller"},
+			Programs:       []string{"JUP6LkbZbjS1jKKwapdHNy74zcZ3tLUZoi5QNyVTaV4"},
+		}},
+		AccountChanges: []monitor.AccountChange{{Address: "account"}},
+	}
+	with := func(changeType string, modify func(*monitor.Change)) monitor.Change {
+		change := base
+		change.ChangeType = changeType
+		if modify != nil {
+			modify(&change)
+		}
+		return change
+	}
+	authorities := &authority.Authorities{MintAuthority: "new-authority", FreezeAuthority: "freezer"}
+
+	changes := []monitor.Change{
+		with("balance_change", nil),
+		with("new_token", func(c *monitor.Change) { c.Authorities = authorities }),
+		with("token_exit", func(c *monitor.Change) { c.NewBalance, c.LastUSDValue = 0, 2 }),
+		with("authority_change", func(c *monitor.Change) {
+			c.Authorities = authorities
+			c.PreviousAuthorities = &authority.Authorities{MintAuthority: "old-authority"}
+			c.AuthorityChanges = []string{"mint authority: old-authority → new-authority"}
+		}),
+		with("account_state", func(c *monitor.Change) {
+			c.AccountState = &monitor.AccountStateChange{
+				Address: "account",
+				Events:  []string{monitor.EventFrozen},
+				New:     monitor.TokenAccountDetail{Address: "account", State: monitor.AccountStateFrozen, Delegate: "delegate", DelegatedAmount: 5, CloseAuthority: "closer"},
+			}
+		}),
+		with("new_wallet", func(c *monitor.Change) {
+			c.TokenBalances = map[string]uint64{"mint": 2_500_000}
+			c.TokenDecimalsMap = map[string]uint8{"mint": 6}
+			c.TokenSymbols = map[string]string{"mint": "TKN"}
+			c.TokenValues = map[string]float64{"mint": 5}
+		}),
+		with("wallet_failing", func(c *monitor.Change) {
+			c.FailureCount, c.LastError, c.LastSuccess = 3, "rpc unavailable", now.Add(-time.Hour)
+		}),
+		with(monitor.ChangeRetracted, func(c *monitor.Change) { c.RetractedType = "balance_change" }),
+		with(holders.EventHolderSell, func(c *monitor.Change) {
+			c.NewBalance, c.ChangePercent, c.HolderRank, c.PreviousRank = 500_000, -50, 3, 2
+		}),
+		with(holders.EventConcentration, func(c *monitor.Change) { c.Concentration, c.PreviousConcentration = 60, 40 }),
+		with(window.EventAccumulation, func(c *monitor.Change) {
+			c.Window, c.WindowStart, c.Inflow, c.Outflow, c.Movements = time.Hour, now.Add(-time.Hour), 2_000_000, 500_000, 3
+		}),
+		with(monitor.ChangeCoordinatedSell, func(c *monitor.Change) {
+			c.Window, c.WindowStart = 10*time.Minute, now.Add(-10*time.Minute)
+			c.Participants = []correlate.Participant{
+				{Wallet: "a", Action: correlate.ActionExited, OldBalance: 100, Decimals: 6, Time: now.Add(-5 * time.Minute)},
+				{Wallet: "b", Action: correlate.ActionExited, OldBalance: 200, Decimals: 6, Time: now.Add(-2 * time.Minute)},
+			}
+		}),
+	}
+
+	engine, err := rules.New(config.AlertConfig{SignificantChange: 0.2})
+	require.NoError(t, err)
+	recorder := &recordingAlerter{}
+	processChanges(changes, recorder, engine, now, utils.NewLogger(true))
+	require.Len(t, recorder.alerts, len(changes))
+
+	for _, alert := range recorder.alerts {
+		content, err := json.Marshal(alerts.SuppressionState{"key": {Suppressed: 1, Latest: &alert}})
+		require.NoError(t, err)
+		var restored alerts.SuppressionState
+		require.NoError(t, json.Unmarshal(content, &restored))
+		require.NotNil(t, restored["key"].Latest)
+		assert.Equal(t, alert.Data, restored["key"].Latest.Data, "alert data of %s", alert.AlertType)
+	}
+}
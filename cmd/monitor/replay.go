@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/accursedgalaxy/insider-monitor/internal/alerts"
+	"github.com/accursedgalaxy/insider-monitor/internal/backfill"
+	"github.com/accursedgalaxy/insider-monitor/internal/config"
+	"github.com/accursedgalaxy/insider-monitor/internal/monitor"
+	"github.com/accursedgalaxy/insider-monitor/internal/replay"
+	"github.com/accursedgalaxy/insider-monitor/internal/rules"
+	"github.com/accursedgalaxy/insider-monitor/internal/score"
+	"github.com/accursedgalaxy/insider-monitor/internal/storage"
+	"github.com/accursedgalaxy/insider-monitor/internal/utils"
+)
+
+// replayHistory 作为 -replay 的参数时，由回填的交易记录重建快照
+const replayHistory = "history"
+
+// runReplay 将保存的快照或回填的交易按模拟时间送入变化检测与告警流程，
+// 把本应发送的告警写入 out，不访问 RPC，也不改写监控状态
+func runReplay(cfg *config.Config, engine *rules.Engine, source, out string, logger *utils.Logger) error {
+	const dataDir = "./data"
+
+	var frames []replay.Frame
+	if source == replayHistory {
+		scanInterval, err := time.ParseDuration(cfg.ScanInterval)
+		if err != nil {
+			scanInterval = time.Minute
+		}
+		current, err := storage.New(dataDir).LoadWalletData()
+		if err != nil {
+			return fmt.Errorf("failed to load wallet data: %w", err)
+		}
+		histories, err := backfill.NewStore(dataDir).LoadAll()
+		if err != nil {
+			return err
+		}
+		frames = replay.FromHistory(current, histories, scanInterval)
+		if len(frames) == 0 {
+			return fmt.Errorf("no backfilled transactions found in %s, run with -backfill first", dataDir)
+		}
+	} else {
+		var err error
+		if frames, err = replay.LoadSnapshots(source); err != nil {
+			return err
+		}
+	}
+
+	alerter, err := alerts.NewFileAlerter(out)
+	if err != nil {
+		return err
+	}
+	defer alerter.Close()
+	// 按模拟时间应用告警冷却期，不读取也不保存实时监控的去重状态
+	suppressor := alerts.NewSuppressor(alerter, alertCooldown(cfg.Alerts), nil, nil)
+
+	// 按模拟时间取当时的 InsiderScore，使 min_insider_score 的效果与实时监控一致
+	scorer := score.NewScorer(nil, dataDir)
+	histories := make(map[string][]score.Point)
+
+	// 未达到告警级别的变化只记录日志，回放时不输出到终端
+	quiet := utils.NewLogger(true)
+	correlator := newCorrelator(cfg.Correlation)
+	summary, err := replay.Run(frames, engine.MinChangePercent(), func(now time.Time, changes []monitor.Change) {
+		for i := range changes {
+			wallet := changes[i].WalletAddress
+			if _, loaded := histories[wallet]; !loaded {
+				histories[wallet] = scorer.History(wallet)
+			}
+			changes[i].InsiderScore = scoreAt(histories[wallet], now)
+		}
+		suppressor.Flush(now)
+		processChanges(correlateChanges(correlator, engine, changes, now), suppressor, engine, now, quiet)
+	})
+	if err != nil {
+		return err
+	}
+	// 回放结束时发送仍在冷却期内被抑制的告警摘要
+	suppressor.Flush(summary.End.Add(alertCooldown(cfg.Alerts)))
+
+	logger.Success("Replayed %d snapshots from %s to %s: %d changes, %d alerts written to %s",
+		summary.Frames, summary.Start.Format(time.RFC3339), summary.End.Format(time.RFC3339),
+		summary.Changes, alerter.Count(), out)
+	types := make([]string, 0, len(summary.ByType))
+	for changeType := range summary.ByType {
+		types = append(types, changeType)
+	}
+	sort.Strings(types)
+	for _, changeType := range types {
+		logger.Info("  %s: %d", changeType, summary.ByType[changeType])
+	}
+	return nil
+}
+
+// scoreAt 返回 at 时刻之前最近一次的评分，没有评分时返回 0
+func scoreAt(history []score.Point, at time.Time) float64 {
+	var value float64
+	for _, point := range history {
+		if point.Time.After(at) {
+			break
+		}
+		value = point.Score
+	}
+	return value
+}
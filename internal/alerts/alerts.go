@@ -12,6 +12,17 @@ const (
 	Critical AlertLevel = "CRITICAL"
 )
 
+// AtLeast 判断告警级别是否不低于 other。级别以字符串表示，不能直接比较大小
+func (l AlertLevel) AtLeast(other AlertLevel) bool {
+	return levelRank[l] >= levelRank[other]
+}
+
+var levelRank = map[AlertLevel]int{
+	Info:     0,
+	Warning:  1,
+	Critical: 2,
+}
+
 type Alert struct {
 	Timestamp     time.Time
 	WalletAddress string
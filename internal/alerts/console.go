@@ -36,6 +36,28 @@ func (a *ConsoleAlerter) SendAlert(alert Alert) error {
 		alertType = "NEW TOKEN"
 	} else if alertType == "NEW_WALLET" {
 		alertType = "NEW WALLET"
+	} else if alertType == "WALLET_FAILING" {
+		alertType = "WALLET FAILING"
+	} else if alertType == "TOKEN_EXIT" {
+		alertType = "TOKEN EXIT"
+	} else if alertType == "ACCOUNT_STATE" {
+		alertType = "ACCOUNT STATE"
+	} else if alertType == "AUTHORITY_CHANGE" {
+		alertType = "AUTHORITY CHANGE"
+	} else if alertType == "HOLDER_SELL" {
+		alertType = "HOLDER SELL"
+	} else if alertType == "NEW_TOP_HOLDER" {
+		alertType = "NEW TOP HOLDER"
+	} else if alertType == "HOLDER_CONCENTRATION" {
+		alertType = "HOLDER CONCENTRATION"
+	} else if alertType == "CHANGE_RETRACTED" {
+		alertType = "CHANGE RETRACTED"
+	} else if alertType == "ACCUMULATION" || alertType == "DISTRIBUTION" {
+		alertType = "SLOW " + alertType
+	} else if alertType == "COORDINATED_BUY" {
+		alertType = "COORDINATED BUY"
+	} else if alertType == "COORDINATED_SELL" {
+		alertType = "COORDINATED SELL"
 	}
 
 	// 为告警绘制框线
@@ -59,7 +81,9 @@ func (a *ConsoleAlerter) SendAlert(alert Alert) error {
 		shortWallet = shortWallet[:8] + "..." + shortWallet[len(shortWallet)-8:]
 	}
 
-	fmt.Printf("Wallet: %s%s%s\n", utils.ColorBold, shortWallet, utils.ColorReset)
+	if shortWallet != "" {
+		fmt.Printf("Wallet: %s%s%s\n", utils.ColorBold, shortWallet, utils.ColorReset)
+	}
 
 	// 格式化消息内容
 	lines := strings.Split(alert.Message, "\n")
@@ -84,6 +108,54 @@ func (a *ConsoleAlerter) SendAlert(alert Alert) error {
 		}
 	}
 
+	if value, ok := alert.Data["usd_value"].(float64); ok && value > 0 {
+		fmt.Printf("Value: %s$%.2f%s\n", utils.ColorGreen, value, utils.ColorReset)
+	}
+	if value, ok := alert.Data["total_usd_value"].(float64); ok && value > 0 {
+		fmt.Printf("Total value: %s$%.2f%s\n", utils.ColorGreen, value, utils.ColorReset)
+	}
+	if value, ok := alert.Data["last_usd_value"].(float64); ok && value > 0 {
+		fmt.Printf("Last value: %s$%.2f%s\n", utils.ColorRed, value, utils.ColorReset)
+	}
+	if insiderScore, ok := alert.Data["insider_score"].(float64); ok && insiderScore > 0 {
+		fmt.Printf("Insider score: %s%.0f%s\n", utils.ColorBold, insiderScore, utils.ColorReset)
+	}
+
+	// 非旧版 SPL Token 程序的持仓需要额外标注
+	if program, ok := alert.Data["program"].(string); ok && program != "" && program != "spl-token" {
+		programLine := program
+		if flags, ok := alert.Data["extensions"].([]string); ok && len(flags) > 0 {
+			programLine = fmt.Sprintf("%s (%s)", program, strings.Join(flags, ", "))
+		}
+		fmt.Printf("Program: %s%s%s\n", utils.ColorCyan, programLine, utils.ColorReset)
+	}
+
+	if risks, ok := alert.Data["authority_risks"].([]string); ok {
+		for _, risk := range risks {
+			fmt.Printf("Risk: %s%s%s\n", utils.ColorYellow, risk, utils.ColorReset)
+		}
+	}
+
+	if concentration, ok := alert.Data["concentration"].(float64); ok && concentration > 0 {
+		fmt.Printf("Top-10 concentration: %.2f%%\n", concentration)
+	}
+
+	if moved, ok := alert.Data["moved_accounts"].([]string); ok && len(moved) > 0 {
+		fmt.Printf("Accounts: %s\n", strings.Join(moved, ", "))
+	}
+
+	if activity, ok := alert.Data["activity"].(string); ok && activity != "" {
+		fmt.Printf("Activity: %s%s%s\n", utils.ColorBold, activity, utils.ColorReset)
+	}
+
+	// 输出导致变化的交易
+	if signature, ok := alert.Data["signature"].(string); ok && signature != "" {
+		fmt.Printf("Tx: %s%s%s\n", utils.ColorCyan, signature, utils.ColorReset)
+		if counterparties, ok := alert.Data["counterparties"].([]string); ok && len(counterparties) > 0 {
+			fmt.Printf("Counterparties: %s\n", strings.Join(counterparties, ", "))
+		}
+	}
+
 	fmt.Println(bottomBorder)
 
 	return nil
@@ -7,11 +7,21 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/accursedgalaxy/insider-monitor/internal/utils"
 )
 
+// 区块浏览器链接
+const (
+	explorerTxURL      = "https://solscan.io/tx/%s"
+	explorerAccountURL = "https://solscan.io/account/%s"
+	maxLinkedAddresses = 3  // 每个字段最多展示的地址数
+	maxEmbedHoldings   = 10 // new_wallet 告警最多展示的持仓数
+)
+
 type DiscordAlerter struct {
 	WebhookURL string
 	ChannelID  string
@@ -25,10 +35,15 @@ type discordMessage struct {
 }
 
 type embed struct {
-	Title       string  `json:"title"`
-	Description string  `json:"description"`
-	Color       int     `json:"color"` // 颜色代码
-	Fields      []field `json:"fields,omitempty"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Color       int        `json:"color"` // 颜色代码
+	Fields      []field    `json:"fields,omitempty"`
+	Thumbnail   *thumbnail `json:"thumbnail,omitempty"`
+}
+
+type thumbnail struct {
+	URL string `json:"url"`
 }
 
 type field struct {
@@ -61,6 +76,15 @@ func (d *DiscordAlerter) SendAlert(alert Alert) error {
 		return alert.Data[key]
 	}
 
+	// 代币展示名称：优先使用元数据中的名称
+	tokenLabel := func() string {
+		symbol, _ := safeGet("symbol").(string)
+		if name, ok := safeGet("name").(string); ok && name != "" && name != symbol {
+			return fmt.Sprintf("%s (%s)", name, symbol)
+		}
+		return symbol
+	}
+
 	var description string
 	var fields []field
 
@@ -71,7 +95,6 @@ func (d *DiscordAlerter) SendAlert(alert Alert) error {
 				if decimals, ok := safeGet("decimals").(uint8); ok {
 					oldFormatted := utils.FormatTokenAmount(oldBal, decimals)
 					newFormatted := utils.FormatTokenAmount(newBal, decimals)
-					symbol := safeGet("symbol").(string)
 					changePercent := safeGet("change_percent").(float64)
 
 					description = fmt.Sprintf("```diff\n- Old: %s\n+ New: %s\nChange: %+.2f%%```",
@@ -83,7 +106,7 @@ func (d *DiscordAlerter) SendAlert(alert Alert) error {
 					fields = append(fields, field{
 						Name: "Token",
 						Value: fmt.Sprintf("%s\n`%s`",
-							symbol,
+							tokenLabel(),
 							alert.TokenMint),
 						Inline: false,
 					})
@@ -91,11 +114,242 @@ func (d *DiscordAlerter) SendAlert(alert Alert) error {
 			}
 		}
 
+	case "new_wallet":
+		if balances, ok := safeGet("token_balances").(map[string]uint64); ok {
+			decimals, _ := safeGet("token_decimals").(map[string]uint8)
+			symbols, _ := safeGet("token_symbols").(map[string]string)
+			values, _ := safeGet("token_values").(map[string]float64)
+			total, _ := safeGet("total_usd_value").(float64)
+
+			description = fmt.Sprintf("```ini\n[Baseline]\n%d tokens worth $%.2f```", len(balances), total)
+
+			// 按美元价值从高到低展示主要持仓
+			mints := make([]string, 0, len(balances))
+			for mint := range balances {
+				mints = append(mints, mint)
+			}
+			sort.Slice(mints, func(i, j int) bool {
+				if values[mints[i]] != values[mints[j]] {
+					return values[mints[i]] > values[mints[j]]
+				}
+				return balances[mints[i]] > balances[mints[j]]
+			})
+
+			var lines []string
+			for i, mint := range mints {
+				if i == maxEmbedHoldings {
+					lines = append(lines, fmt.Sprintf("+%d more", len(mints)-maxEmbedHoldings))
+					break
+				}
+				label := symbols[mint]
+				if label == "" {
+					label = shortAddress(mint)
+				}
+				line := fmt.Sprintf("**%s** %s", label, utils.FormatTokenAmount(balances[mint], decimals[mint]))
+				if values[mint] > 0 {
+					line += fmt.Sprintf(" ($%.2f)", values[mint])
+				}
+				lines = append(lines, line)
+			}
+			if len(lines) > 0 {
+				fields = append(fields, field{
+					Name:   "Holdings",
+					Value:  strings.Join(lines, "\n"),
+					Inline: false,
+				})
+			}
+		}
+
+	case "token_exit":
+		if oldBal, ok := safeGet("old_balance").(uint64); ok {
+			if decimals, ok := safeGet("decimals").(uint8); ok {
+				description = fmt.Sprintf("```diff\n- Exited: %s\n+ Remaining: 0```",
+					utils.FormatTokenAmount(oldBal, decimals))
+
+				fields = append(fields, field{
+					Name: "Token",
+					Value: fmt.Sprintf("%s\n`%s`",
+						tokenLabel(),
+						alert.TokenMint),
+					Inline: false,
+				})
+			}
+		}
+		if lastValue, ok := safeGet("last_usd_value").(float64); ok && lastValue > 0 {
+			fields = append(fields, field{
+				Name:   "Last Value",
+				Value:  fmt.Sprintf("$%.2f", lastValue),
+				Inline: true,
+			})
+		}
+
+	case "account_state":
+		if details, ok := safeGet("details").([]string); ok && len(details) > 0 {
+			description = fmt.Sprintf("```%s```", strings.Join(details, "\n"))
+
+			fields = append(fields, field{
+				Name: "Token",
+				Value: fmt.Sprintf("%s\n`%s`",
+					tokenLabel(),
+					alert.TokenMint),
+				Inline: false,
+			})
+		}
+		if account, ok := safeGet("account").(string); ok && account != "" {
+			fields = append(fields, field{
+				Name:   "Token Account",
+				Value:  linkAddresses([]string{account}),
+				Inline: true,
+			})
+		}
+		if delegate, ok := safeGet("delegate").(string); ok && delegate != "" {
+			fields = append(fields, field{
+				Name:   "Delegate",
+				Value:  linkAddresses([]string{delegate}),
+				Inline: true,
+			})
+		}
+
+	case "holder_sell", "new_top_holder":
+		if newBal, ok := safeGet("new_balance").(uint64); ok {
+			oldBal, _ := safeGet("old_balance").(uint64)
+			decimals, _ := safeGet("decimals").(uint8)
+			rank, _ := safeGet("rank").(int)
+			previousRank, _ := safeGet("previous_rank").(int)
+
+			if alert.AlertType == "holder_sell" {
+				description = fmt.Sprintf("```diff\n- Old: %s (rank #%d)\n+ New: %s (%s)```",
+					utils.FormatTokenAmount(oldBal, decimals), previousRank,
+					utils.FormatTokenAmount(newBal, decimals), holderRank(rank))
+			} else {
+				description = fmt.Sprintf("```ini\n[Entered Top Holders]\n%s at rank #%d```",
+					utils.FormatTokenAmount(newBal, decimals), rank)
+			}
+
+			fields = append(fields, field{
+				Name: "Token",
+				Value: fmt.Sprintf("%s\n`%s`",
+					tokenLabel(),
+					alert.TokenMint),
+				Inline: false,
+			})
+		}
+		if concentration, ok := safeGet("concentration").(float64); ok && concentration > 0 {
+			fields = append(fields, field{
+				Name:   "Top-10 Concentration",
+				Value:  fmt.Sprintf("%.2f%%", concentration),
+				Inline: true,
+			})
+		}
+
+	case "accumulation", "distribution":
+		if oldBal, ok := safeGet("old_balance").(uint64); ok {
+			newBal, _ := safeGet("new_balance").(uint64)
+			decimals, _ := safeGet("decimals").(uint8)
+			changePercent, _ := safeGet("change_percent").(float64)
+			window, _ := safeGet("window").(string)
+			movements, _ := safeGet("movements").(int)
+			description = fmt.Sprintf("```diff\n- %s ago: %s\n+ Now: %s\nChange: %+.2f%% in %d movements```",
+				window,
+				utils.FormatTokenAmount(oldBal, decimals),
+				utils.FormatTokenAmount(newBal, decimals),
+				changePercent, movements)
+
+			fields = append(fields, field{
+				Name: "Token",
+				Value: fmt.Sprintf("%s\n`%s`",
+					tokenLabel(),
+					alert.TokenMint),
+				Inline: false,
+			})
+
+			inflow, _ := safeGet("inflow").(uint64)
+			outflow, _ := safeGet("outflow").(uint64)
+			fields = append(fields, field{
+				Name:   "Net Flow",
+				Value:  fmt.Sprintf("+%s / -%s", utils.FormatTokenAmount(inflow, decimals), utils.FormatTokenAmount(outflow, decimals)),
+				Inline: true,
+			})
+		}
+
+	case "coordinated_buy", "coordinated_sell":
+		if participants, ok := safeGet("participants").([]string); ok && len(participants) > 0 {
+			window, _ := safeGet("window").(string)
+			description = fmt.Sprintf("```%d wallets within %s, in the order they moved:\n%s```",
+				len(participants), window, strings.Join(participants, "\n"))
+
+			fields = append(fields, field{
+				Name: "Token",
+				Value: fmt.Sprintf("%s\n`%s`",
+					tokenLabel(),
+					alert.TokenMint),
+				Inline: false,
+			})
+		}
+
+	case "holder_concentration":
+		if concentration, ok := safeGet("concentration").(float64); ok {
+			previous, _ := safeGet("previous_concentration").(float64)
+			description = fmt.Sprintf("```diff\n- Old: %.2f%%\n+ New: %.2f%%```", previous, concentration)
+
+			fields = append(fields, field{
+				Name: "Token",
+				Value: fmt.Sprintf("%s\n`%s`",
+					tokenLabel(),
+					alert.TokenMint),
+				Inline: false,
+			})
+		}
+
+	case "authority_change":
+		if details, ok := safeGet("details").([]string); ok && len(details) > 0 {
+			description = fmt.Sprintf("```%s```", strings.Join(details, "\n"))
+
+			fields = append(fields, field{
+				Name: "Token",
+				Value: fmt.Sprintf("%s\n`%s`",
+					tokenLabel(),
+					alert.TokenMint),
+				Inline: false,
+			})
+		}
+
+	case "change_retracted":
+		if retracted, ok := safeGet("retracted_type").(string); ok {
+			slot, _ := safeGet("slot").(uint64)
+			commitment, _ := safeGet("commitment").(string)
+			description = fmt.Sprintf("```The %s alert seen at %s slot %d never finalized and should be disregarded```",
+				retracted, commitment, slot)
+
+			fields = append(fields, field{
+				Name: "Token",
+				Value: fmt.Sprintf("%s\n`%s`",
+					tokenLabel(),
+					alert.TokenMint),
+				Inline: false,
+			})
+		}
+
+	case "wallet_failing":
+		if count, ok := safeGet("failure_count").(int); ok {
+			lastError, _ := safeGet("last_error").(string)
+			description = fmt.Sprintf("```Failed %d consecutive scans\nLast error: %s```", count, lastError)
+
+			lastSuccess := "never"
+			if ts, ok := safeGet("last_success").(time.Time); ok && !ts.IsZero() {
+				lastSuccess = fmt.Sprintf("%s (%s ago)", ts.Format("2006-01-02 15:04:05 MST"), time.Since(ts).Round(time.Second))
+			}
+			fields = append(fields, field{
+				Name:   "Last Successful Scan",
+				Value:  lastSuccess,
+				Inline: false,
+			})
+		}
+
 	case "new_token":
 		if balance, ok := safeGet("balance").(uint64); ok {
 			if decimals, ok := safeGet("decimals").(uint8); ok {
 				formatted := utils.FormatTokenAmount(balance, decimals)
-				symbol := safeGet("symbol").(string)
 				description = fmt.Sprintf("```ini\n[Initial Balance]\n%s```",
 					formatted)
 
@@ -103,7 +357,7 @@ func (d *DiscordAlerter) SendAlert(alert Alert) error {
 				fields = append(fields, field{
 					Name: "Token",
 					Value: fmt.Sprintf("%s\n`%s`",
-						symbol,
+						tokenLabel(),
 						alert.TokenMint),
 					Inline: false,
 				})
@@ -111,17 +365,131 @@ func (d *DiscordAlerter) SendAlert(alert Alert) error {
 		}
 	}
 
+	// 添加持仓美元价值与供应量占比
+	if usdValue, ok := safeGet("usd_value").(float64); ok && usdValue > 0 {
+		fields = append(fields, field{
+			Name:   "Value",
+			Value:  fmt.Sprintf("$%.2f", usdValue),
+			Inline: true,
+		})
+	}
+	if share, ok := safeGet("supply_share").(float64); ok && share > 0 {
+		fields = append(fields, field{
+			Name:   "Supply Share",
+			Value:  fmt.Sprintf("%.4f%%", share),
+			Inline: true,
+		})
+	}
+
+	if insiderScore, ok := safeGet("insider_score").(float64); ok && insiderScore > 0 {
+		fields = append(fields, field{
+			Name:   "Insider Score",
+			Value:  fmt.Sprintf("%.0f / 100", insiderScore),
+			Inline: true,
+		})
+	}
+
+	if unfinalized, ok := safeGet("unfinalized").(bool); ok && unfinalized {
+		slot, _ := safeGet("slot").(uint64)
+		commitment, _ := safeGet("commitment").(string)
+		fields = append(fields, field{
+			Name:   "Finality",
+			Value:  fmt.Sprintf("⏳ Seen at %s slot %d, not yet finalized", commitment, slot),
+			Inline: false,
+		})
+	}
+
+	// 未放弃或近期变更的铸币与冻结权限
+	if risks, ok := safeGet("authority_risks").([]string); ok && len(risks) > 0 {
+		fields = append(fields, field{
+			Name:   "Authority Risk",
+			Value:  strings.Join(risks, "\n"),
+			Inline: false,
+		})
+	}
+
+	// 标注代币所属程序及 Token-2022 扩展
+	if program, ok := safeGet("program").(string); ok && program != "" {
+		programValue := program
+		if flags, ok := safeGet("extensions").([]string); ok && len(flags) > 0 {
+			programValue = fmt.Sprintf("%s (%s)", program, strings.Join(flags, ", "))
+		}
+		fields = append(fields, field{
+			Name:   "Program",
+			Value:  programValue,
+			Inline: true,
+		})
+	}
+
+	// 标注买入、卖出、转账等变化类型
+	if activity, ok := safeGet("activity").(string); ok && activity != "" {
+		fields = append(fields, field{
+			Name:   "Activity",
+			Value:  activity,
+			Inline: false,
+		})
+	}
+
+	// 添加导致变化的交易及其对手方
+	if signature, ok := safeGet("signature").(string); ok && signature != "" {
+		txValue := fmt.Sprintf("[%s](%s)", shortAddress(signature), fmt.Sprintf(explorerTxURL, signature))
+		if slot, ok := safeGet("tx_slot").(uint64); ok && slot > 0 {
+			txValue += fmt.Sprintf("\nSlot %d", slot)
+		}
+		if signatures, ok := safeGet("signatures").([]string); ok && len(signatures) > 1 {
+			txValue += fmt.Sprintf(" (+%d more)", len(signatures)-1)
+		}
+		fields = append(fields, field{
+			Name:   "Transaction",
+			Value:  txValue,
+			Inline: true,
+		})
+	}
+	if counterparties, ok := safeGet("counterparties").([]string); ok && len(counterparties) > 0 {
+		fields = append(fields, field{
+			Name:   "Counterparties",
+			Value:  linkAddresses(counterparties),
+			Inline: true,
+		})
+	}
+	if moved, ok := safeGet("moved_accounts").([]string); ok && len(moved) > 0 {
+		fields = append(fields, field{
+			Name:   "Token Accounts",
+			Value:  linkAddresses(moved),
+			Inline: true,
+		})
+	}
+	if programs, ok := safeGet("programs").([]string); ok && len(programs) > 0 {
+		fields = append(fields, field{
+			Name:   "Programs",
+			Value:  linkAddresses(programs),
+			Inline: true,
+		})
+	}
+
 	// 若生成描述失败，则使用备用内容
 	if description == "" {
 		description = fmt.Sprintf("```%s```", alert.Message)
 	}
 
-	// 将钱包地址作为一个字段
-	fields = append(fields, field{
-		Name:   "Wallet",
-		Value:  fmt.Sprintf("`%s`", alert.WalletAddress),
-		Inline: false,
-	})
+	// 冷却期内被合并的相似告警
+	if suppressed, ok := safeGet("suppressed").(int); ok && suppressed > 0 {
+		since, _ := safeGet("suppressed_since").(time.Time)
+		fields = append(fields, field{
+			Name:   "Similar Changes",
+			Value:  fmt.Sprintf("+%d more since %s", suppressed, since.UTC().Format("15:04:05 MST")),
+			Inline: true,
+		})
+	}
+
+	// 将钱包地址作为一个字段（集中度告警不涉及具体钱包）
+	if alert.WalletAddress != "" {
+		fields = append(fields, field{
+			Name:   "Wallet",
+			Value:  fmt.Sprintf("`%s`", alert.WalletAddress),
+			Inline: false,
+		})
+	}
 
 	// 添加时间戳
 	fields = append(fields, field{
@@ -130,14 +498,26 @@ func (d *DiscordAlerter) SendAlert(alert Alert) error {
 		Inline: true,
 	})
 
+	title := fmt.Sprintf("%s Alert", strings.ToUpper(alert.AlertType))
+	if kind, ok := safeGet("kind").(string); ok && kind != "" {
+		title = fmt.Sprintf("%s Alert (%s)", strings.ToUpper(alert.AlertType), strings.ToUpper(kind))
+	}
+
+	alertEmbed := embed{
+		Title:       title,
+		Description: description,
+		Color:       color,
+		Fields:      fields,
+	}
+
+	// 若解析到代币图片则作为缩略图展示
+	if image, ok := safeGet("image").(string); ok && image != "" {
+		alertEmbed.Thumbnail = &thumbnail{URL: image}
+	}
+
 	msg := discordMessage{
 		Username: "Solana Wallet Monitor",
-		Embeds: []embed{{
-			Title:       fmt.Sprintf("%s Alert", strings.ToUpper(alert.AlertType)),
-			Description: description,
-			Color:       color,
-			Fields:      fields,
-		}},
+		Embeds:   []embed{alertEmbed},
 	}
 
 	payload, err := json.Marshal(msg)
@@ -161,3 +541,32 @@ func (d *DiscordAlerter) SendAlert(alert Alert) error {
 	log.Printf("Successfully sent Discord alert (status: %d)", resp.StatusCode)
 	return nil
 }
+
+// shortAddress 将地址或签名缩写为首尾各 4 个字符
+func shortAddress(address string) string {
+	if len(address) <= 12 {
+		return address
+	}
+	return address[:4] + "…" + address[len(address)-4:]
+}
+
+// holderRank 返回持有者当前排名的描述，0 表示已跌出前 N 名
+func holderRank(rank int) string {
+	if rank == 0 {
+		return "left top holders"
+	}
+	return fmt.Sprintf("rank #%d", rank)
+}
+
+// linkAddresses 将地址列表渲染为区块浏览器链接，超出部分以数量表示
+func linkAddresses(addresses []string) string {
+	links := make([]string, 0, maxLinkedAddresses+1)
+	for i, address := range addresses {
+		if i == maxLinkedAddresses {
+			links = append(links, fmt.Sprintf("+%d more", len(addresses)-maxLinkedAddresses))
+			break
+		}
+		links = append(links, fmt.Sprintf("[%s](%s)", shortAddress(address), fmt.Sprintf(explorerAccountURL, address)))
+	}
+	return strings.Join(links, "\n")
+}
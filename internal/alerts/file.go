@@ -0,0 +1,71 @@
+package alerts
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// fileRecord 为写入文件的一条告警
+type fileRecord struct {
+	Timestamp     time.Time              `json:"timestamp"`
+	Level         AlertLevel             `json:"level"`
+	AlertType     string                 `json:"alert_type"`
+	WalletAddress string                 `json:"wallet_address,omitempty"`
+	TokenMint     string                 `json:"token_mint,omitempty"`
+	Message       string                 `json:"message"`
+	Data          map[string]interface{} `json:"data,omitempty"`
+}
+
+// FileAlerter 将告警以 JSON Lines 格式写入文件，每行一条，便于离线比较
+type FileAlerter struct {
+	file  *os.File
+	count int
+	mutex sync.Mutex
+}
+
+// NewFileAlerter 创建（或清空）告警文件
+func NewFileAlerter(path string) (*FileAlerter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create alert file: %w", err)
+	}
+	return &FileAlerter{file: file}, nil
+}
+
+func (a *FileAlerter) SendAlert(alert Alert) error {
+	line, err := json.Marshal(fileRecord{
+		Timestamp:     alert.Timestamp,
+		Level:         alert.Level,
+		AlertType:     alert.AlertType,
+		WalletAddress: alert.WalletAddress,
+		TokenMint:     alert.TokenMint,
+		Message:       alert.Message,
+		Data:          alert.Data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal alert: %w", err)
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if _, err := a.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write alert: %w", err)
+	}
+	a.count++
+	return nil
+}
+
+// Count 返回已写入的告警数
+func (a *FileAlerter) Count() int {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.count
+}
+
+// Close 关闭告警文件
+func (a *FileAlerter) Close() error {
+	return a.file.Close()
+}
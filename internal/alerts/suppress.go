@@ -0,0 +1,276 @@
+package alerts
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 从不抑制的告警类型：撤回告警用于更正已发送的告警
+var unsuppressedTypes = map[string]bool{
+	"change_retracted": true,
+}
+
+// dataTypes 为告警附加数据中出现的值类型，以类型名为键。保存状态时每个值都记录其类型名，
+// 读取时按类型名恢复，使冷却期结束后发送的摘要与原告警的格式一致
+var dataTypes = typesOf(
+	"", false, 0, uint8(0), uint64(0), float64(0), time.Time{},
+	[]string(nil), map[string]uint64(nil), map[string]uint8(nil), map[string]string(nil), map[string]float64(nil),
+)
+
+func typesOf(values ...interface{}) map[string]reflect.Type {
+	types := make(map[string]reflect.Type, len(values))
+	for _, value := range values {
+		dataType := reflect.TypeOf(value)
+		types[dataType.String()] = dataType
+	}
+	return types
+}
+
+// typedValue 为保存的附加数据值及其类型名
+type typedValue struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// storedFingerprint 为指纹保存到磁盘的格式，被抑制告警的附加数据带有类型名
+type storedFingerprint struct {
+	Fingerprint
+	Latest *storedAlert `json:"latest,omitempty"`
+}
+
+type storedAlert struct {
+	Alert
+	Data map[string]json.RawMessage
+}
+
+// Fingerprint 记录同一钱包、铸币与告警类型最近一次发送的告警，以及冷却期内被抑制的告警
+type Fingerprint struct {
+	LastSent  time.Time  `json:"last_sent"`
+	LastLevel AlertLevel `json:"last_level"`
+	// 冷却期内被抑制的告警数、第一条的时间与最新一条告警，冷却期结束后合并为一条摘要发送
+	Suppressed      int       `json:"suppressed,omitempty"`
+	SuppressedSince time.Time `json:"suppressed_since,omitempty"`
+	Latest          *Alert    `json:"latest,omitempty"`
+}
+
+// MarshalJSON 保存指纹，被抑制告警的附加数据按值记录类型名
+func (f Fingerprint) MarshalJSON() ([]byte, error) {
+	type plain Fingerprint
+	stored := struct {
+		plain
+		Latest *storedAlert `json:"latest,omitempty"`
+	}{plain: plain(f)}
+	if f.Latest != nil {
+		stored.Latest = &storedAlert{Alert: *f.Latest}
+		if f.Latest.Data != nil {
+			stored.Latest.Data = make(map[string]json.RawMessage, len(f.Latest.Data))
+		}
+		for key, value := range f.Latest.Data {
+			raw, err := json.Marshal(value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal alert data %s: %w", key, err)
+			}
+			tagged, err := json.Marshal(typedValue{Type: reflect.TypeOf(value).String(), Value: raw})
+			if err != nil {
+				return nil, err
+			}
+			stored.Latest.Data[key] = tagged
+		}
+	}
+	return json.Marshal(stored)
+}
+
+// UnmarshalJSON 读取保存的指纹，并按记录的类型名恢复被抑制告警的附加数据。
+// 类型未知或没有类型名的值按 JSON 的默认类型读取
+func (f *Fingerprint) UnmarshalJSON(content []byte) error {
+	type plain Fingerprint
+	stored := struct {
+		*plain
+		Latest *storedAlert `json:"latest,omitempty"`
+	}{plain: (*plain)(f)}
+	if err := json.Unmarshal(content, &stored); err != nil {
+		return err
+	}
+
+	f.Latest = nil
+	if stored.Latest == nil {
+		return nil
+	}
+	latest := stored.Latest.Alert
+	if stored.Latest.Data != nil {
+		latest.Data = make(map[string]interface{}, len(stored.Latest.Data))
+	}
+	for key, raw := range stored.Latest.Data {
+		value, err := decodeDataValue(raw)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal alert data %s: %w", key, err)
+		}
+		latest.Data[key] = value
+	}
+	f.Latest = &latest
+	return nil
+}
+
+// decodeDataValue 按类型名解码附加数据的值
+func decodeDataValue(raw json.RawMessage) (interface{}, error) {
+	var tagged typedValue
+	if err := json.Unmarshal(raw, &tagged); err == nil && tagged.Type != "" && tagged.Value != nil {
+		if dataType, known := dataTypes[tagged.Type]; known {
+			value := reflect.New(dataType)
+			if err := json.Unmarshal(tagged.Value, value.Interface()); err != nil {
+				return nil, err
+			}
+			return value.Elem().Interface(), nil
+		}
+		raw = tagged.Value
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// SuppressionState 为全部告警指纹的状态：指纹 -> 状态
+type SuppressionState map[string]*Fingerprint
+
+// Suppressor 位于告警流程与实际的 Alerter 之间，按钱包、铸币与告警类型对告警去重。
+// 同一指纹在冷却期内只发送一次，级别升高的告警立即发送；被抑制的告警在冷却期结束后
+// 合并为"N more similar changes"摘要。可在多个 goroutine 中使用
+type Suppressor struct {
+	next     Alerter
+	cooldown time.Duration
+	state    SuppressionState
+	save     func(SuppressionState) error // 状态变化后调用，用于持久化，可为空
+	mutex    sync.Mutex
+}
+
+// NewSuppressor 创建告警抑制层。state 为上次保存的状态，为空时重新开始
+func NewSuppressor(next Alerter, cooldown time.Duration, state SuppressionState, save func(SuppressionState) error) *Suppressor {
+	if state == nil {
+		state = make(SuppressionState)
+	}
+	return &Suppressor{next: next, cooldown: cooldown, state: state, save: save}
+}
+
+// SendAlert 发送或抑制告警。冷却期以告警的 Timestamp 计算，使回放时按模拟时间生效
+func (s *Suppressor) SendAlert(alert Alert) error {
+	if s.cooldown <= 0 || unsuppressedTypes[alert.AlertType] {
+		return s.next.SendAlert(alert)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key := fingerprint(alert)
+	entry, exists := s.state[key]
+	// 级别高于上次发送的告警不受冷却期限制
+	escalated := exists && levelRank[alert.Level] > levelRank[entry.LastLevel]
+	if exists && alert.Timestamp.Sub(entry.LastSent) < s.cooldown && !escalated {
+		if entry.Suppressed == 0 {
+			entry.SuppressedSince = alert.Timestamp
+		}
+		entry.Suppressed++
+		latest := alert
+		entry.Latest = &latest
+		s.persist()
+		return nil
+	}
+	if !exists {
+		entry = &Fingerprint{}
+		s.state[key] = entry
+	}
+
+	if err := s.next.SendAlert(summarize(alert, entry.Suppressed, entry.SuppressedSince)); err != nil {
+		return err
+	}
+	s.sent(entry, alert)
+	s.persist()
+	return nil
+}
+
+// Flush 将冷却期已结束的被抑制告警合并为摘要发送，并清理冷却期已过且无待发告警的指纹
+func (s *Suppressor) Flush(now time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	keys := make([]string, 0, len(s.state))
+	for key := range s.state {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	changed := false
+	for _, key := range keys {
+		entry := s.state[key]
+		if now.Sub(entry.LastSent) < s.cooldown {
+			continue
+		}
+		if entry.Suppressed == 0 || entry.Latest == nil {
+			delete(s.state, key)
+			changed = true
+			continue
+		}
+
+		// 摘要即最新一条被抑制的告警，并注明其余被抑制的数量
+		latest := *entry.Latest
+		latest.Timestamp = now
+		if err := s.next.SendAlert(summarize(latest, entry.Suppressed-1, entry.SuppressedSince)); err != nil {
+			log.Printf("⚠️  Warning: failed to send alert summary: %v", err)
+			continue
+		}
+		s.sent(entry, latest)
+		changed = true
+	}
+	if changed {
+		s.persist()
+	}
+}
+
+// sent 记录指纹的告警已发送，清空被抑制的告警
+func (s *Suppressor) sent(entry *Fingerprint, alert Alert) {
+	entry.LastSent = alert.Timestamp
+	entry.LastLevel = alert.Level
+	entry.Suppressed = 0
+	entry.SuppressedSince = time.Time{}
+	entry.Latest = nil
+}
+
+func (s *Suppressor) persist() {
+	if s.save == nil {
+		return
+	}
+	if err := s.save(s.state); err != nil {
+		log.Printf("⚠️  Warning: failed to save alert suppression state: %v", err)
+	}
+}
+
+// fingerprint 返回告警的指纹，集群与集中度等告警的钱包为空
+func fingerprint(alert Alert) string {
+	return alert.WalletAddress + "/" + alert.TokenMint + "/" + alert.AlertType
+}
+
+// summarize 在告警中注明冷却期内被抑制的相似告警数
+func summarize(alert Alert, suppressed int, since time.Time) Alert {
+	if suppressed <= 0 {
+		return alert
+	}
+	alert.Message = fmt.Sprintf("%s\n(%d more similar changes since %s)",
+		alert.Message, suppressed, since.UTC().Format("15:04:05 MST"))
+
+	// 复制附加数据，避免修改调用方的映射
+	data := make(map[string]interface{}, len(alert.Data)+2)
+	for key, value := range alert.Data {
+		data[key] = value
+	}
+	data["suppressed"] = suppressed
+	data["suppressed_since"] = since
+	alert.Data = data
+	return alert
+}
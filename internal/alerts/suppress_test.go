@@ -0,0 +1,161 @@
+package alerts
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingAlerter 记录收到的告警
+type recordingAlerter struct {
+	alerts []Alert
+}
+
+func (r *recordingAlerter) SendAlert(alert Alert) error {
+	r.alerts = append(r.alerts, alert)
+	return nil
+}
+
+func TestSuppressorCooldown(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	alert := func(minute int, wallet, alertType string, level AlertLevel) Alert {
+		return Alert{
+			Timestamp:     start.Add(time.Duration(minute) * time.Minute),
+			WalletAddress: wallet,
+			TokenMint:     "x",
+			AlertType:     alertType,
+			Level:         level,
+			Message:       "change",
+			Data:          map[string]interface{}{"change_percent": float64(minute)},
+		}
+	}
+
+	next := &recordingAlerter{}
+	suppressor := NewSuppressor(next, 10*time.Minute, nil, nil)
+
+	// 余额在阈值附近反复波动，冷却期内只发送第一条
+	for minute := 0; minute < 4; minute++ {
+		require.NoError(t, suppressor.SendAlert(alert(minute, "w", "balance_change", Warning)))
+	}
+	require.Len(t, next.alerts, 1)
+
+	// 其他钱包、类型与撤回告警不受影响
+	require.NoError(t, suppressor.SendAlert(alert(4, "v", "balance_change", Warning)))
+	require.NoError(t, suppressor.SendAlert(alert(4, "w", "token_exit", Warning)))
+	require.NoError(t, suppressor.SendAlert(alert(4, "w", "change_retracted", Info)))
+	require.NoError(t, suppressor.SendAlert(alert(4, "w", "change_retracted", Info)))
+	assert.Len(t, next.alerts, 5)
+
+	// 级别升高的告警立即发送，并合并之前被抑制的告警
+	require.NoError(t, suppressor.SendAlert(alert(5, "w", "balance_change", Critical)))
+	require.Len(t, next.alerts, 6)
+	escalated := next.alerts[5]
+	assert.Equal(t, Critical, escalated.Level)
+	assert.Equal(t, 3, escalated.Data["suppressed"])
+	assert.Equal(t, start.Add(time.Minute), escalated.Data["suppressed_since"])
+	assert.Contains(t, escalated.Message, "3 more similar changes since 00:01:00 UTC")
+
+	// 同级别的重复告警在冷却期内继续被抑制，冷却期结束后合并为摘要
+	require.NoError(t, suppressor.SendAlert(alert(6, "w", "balance_change", Critical)))
+	require.NoError(t, suppressor.SendAlert(alert(7, "w", "balance_change", Critical)))
+	suppressor.Flush(start.Add(14 * time.Minute))
+	require.Len(t, next.alerts, 6)
+
+	suppressor.Flush(start.Add(15 * time.Minute))
+	require.Len(t, next.alerts, 7)
+	summary := next.alerts[6]
+	assert.Equal(t, start.Add(15*time.Minute), summary.Timestamp)
+	assert.Equal(t, float64(7), summary.Data["change_percent"], "summary carries the latest suppressed alert")
+	assert.Equal(t, 1, summary.Data["suppressed"])
+
+	// 冷却期结束后再次发送
+	require.NoError(t, suppressor.SendAlert(alert(26, "w", "balance_change", Warning)))
+	require.Len(t, next.alerts, 8)
+	assert.NotContains(t, next.alerts[7].Data, "suppressed")
+	assert.Equal(t, "change", next.alerts[7].Message)
+}
+
+func TestSuppressorPersistsState(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	alert := Alert{Timestamp: start, WalletAddress: "w", TokenMint: "x", AlertType: "balance_change", Level: Warning, Message: "change",
+		Data: map[string]interface{}{
+			"old_balance":    uint64(1_000_000),
+			"new_balance":    uint64(2_500_000),
+			"decimals":       uint8(6),
+			"change_percent": 150.0,
+			"symbol":         "TKN",
+			"extensions":     []string{"transfer-fee"},
+			"window_start":   start,
+			"token_values":   map[string]float64{"x": 2.5},
+			"custom":         struct{ A int }{A: 1}, // 未登记的类型按 JSON 的默认类型恢复
+		}}
+
+	var saved []byte
+	save := func(state SuppressionState) error {
+		var err error
+		saved, err = json.Marshal(state)
+		return err
+	}
+	load := func() SuppressionState {
+		var state SuppressionState
+		require.NoError(t, json.Unmarshal(saved, &state))
+		return state
+	}
+
+	next := &recordingAlerter{}
+	require.NoError(t, NewSuppressor(next, time.Hour, nil, save).SendAlert(alert))
+	require.Len(t, next.alerts, 1)
+
+	// 重启后读取保存的状态，冷却期内的告警不会重新发送
+	suppressor := NewSuppressor(next, time.Hour, load(), save)
+	alert.Timestamp = start.Add(30 * time.Minute)
+	require.NoError(t, suppressor.SendAlert(alert))
+	assert.Len(t, next.alerts, 1)
+
+	state := load()
+	require.Contains(t, state, "w/x/balance_change")
+	assert.Equal(t, 1, state["w/x/balance_change"].Suppressed)
+	assert.Equal(t, "change", state["w/x/balance_change"].Latest.Message)
+
+	// 重启后附加数据全部保留并恢复原有类型，摘要可以被 Discord 完整格式化
+	restored := state["w/x/balance_change"].Latest.Data
+	assert.Equal(t, map[string]interface{}{"A": 1.0}, restored["custom"])
+	delete(restored, "custom")
+	delete(alert.Data, "custom")
+	assert.Equal(t, alert.Data, restored)
+
+	restarted := NewSuppressor(next, time.Hour, load(), save)
+	restarted.Flush(start.Add(2 * time.Hour))
+	require.Len(t, next.alerts, 2)
+
+	var payload discordMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	require.NoError(t, NewDiscordAlerter(server.URL, "").SendAlert(next.alerts[1]))
+	require.Len(t, payload.Embeds, 1)
+	assert.Contains(t, payload.Embeds[0].Description, "- Old: 1")
+	assert.Contains(t, payload.Embeds[0].Description, "+ New: 2.5")
+	assert.Contains(t, payload.Embeds[0].Description, "Change: +150.00%")
+	var names []string
+	for _, field := range payload.Embeds[0].Fields {
+		names = append(names, field.Name)
+	}
+	assert.Contains(t, names, "Token")
+	suppressor = restarted
+	suppressor.Flush(start.Add(4 * time.Hour))
+	assert.Empty(t, load())
+
+	// 冷却期为 0 时不去重
+	disabled := NewSuppressor(next, 0, nil, nil)
+	require.NoError(t, disabled.SendAlert(alert))
+	require.NoError(t, disabled.SendAlert(alert))
+	assert.Len(t, next.alerts, 4)
+}
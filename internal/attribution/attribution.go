@@ -0,0 +1,367 @@
+package attribution
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+const (
+	maxSignaturesPerAddress = 20               // 每个地址最多回溯的签名数
+	maxTransactionsPerCall  = 25               // 单次查找最多拉取的交易数
+	cacheTTL                = 30 * time.Minute // 已解析交易的缓存时间
+)
+
+// 原生 SOL 以 wSOL 铸币地址表示，与 monitor 包保持一致
+var nativeMint = solana.SolMint.String()
+
+// Transaction 为解析后的交易，仅保留余额变化与调用的程序
+type Transaction struct {
+	Signature     string
+	Slot          uint64
+	BlockTime     time.Time
+	FeePayer      string
+	Fee           uint64
+	Signers       []string
+	Programs      []string                    // 顶层及内部指令调用的程序（按出现顺序去重）
+	TokenDeltas   map[string]map[string]int64 // 所有者 -> 铸币 -> 原始数量变化
+	LamportDeltas map[string]int64            // 账户 -> lamports 变化（含手续费）
+	Decimals      map[string]uint8            // 铸币 -> 小数位
+}
+
+// Attribution 描述一笔改变了钱包某代币余额的交易
+type Attribution struct {
+	Signature      string    `json:"signature"`
+	Slot           uint64    `json:"slot"`
+	BlockTime      time.Time `json:"block_time"`
+	Delta          int64     `json:"delta"` // 该交易造成的钱包余额变化（原始数量）
+	Counterparties []string  `json:"counterparties,omitempty"`
+	Programs       []string  `json:"programs,omitempty"`
+	Classification
+}
+
+type cachedTransaction struct {
+	tx        *Transaction
+	fetchedAt time.Time
+}
+
+// Resolver 查询钱包的近期交易并缓存解析结果
+type Resolver struct {
+	client *rpc.Client
+	cache  map[string]cachedTransaction
+	mutex  sync.Mutex
+}
+
+func NewResolver(client *rpc.Client) *Resolver {
+	return &Resolver{
+		client: client,
+		cache:  make(map[string]cachedTransaction),
+	}
+}
+
+// Recent 返回涉及给定地址、且区块时间不早于 since 的成功交易，按时间从新到旧排列。
+// since 为零值时仅返回每个地址最近的若干笔交易
+func (r *Resolver) Recent(ctx context.Context, addresses []solana.PublicKey, since time.Time) ([]*Transaction, error) {
+	limit := maxSignaturesPerAddress
+	cutoff := since.Truncate(time.Second)
+
+	seen := make(map[string]bool)
+	var signatures []*rpc.TransactionSignature
+	for _, address := range addresses {
+		results, err := r.client.GetSignaturesForAddressWithOpts(ctx, address, &rpc.GetSignaturesForAddressOpts{
+			Limit:      &limit,
+			Commitment: rpc.CommitmentConfirmed,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get signatures for %s: %w", address.String(), err)
+		}
+
+		for _, sig := range results {
+			if sig.Err != nil || seen[sig.Signature.String()] {
+				continue
+			}
+			// 签名按时间倒序返回，早于上次扫描的部分无需继续
+			if !since.IsZero() && sig.BlockTime != nil && sig.BlockTime.Time().Before(cutoff) {
+				break
+			}
+			seen[sig.Signature.String()] = true
+			signatures = append(signatures, sig)
+		}
+	}
+
+	sort.SliceStable(signatures, func(i, j int) bool {
+		return signatures[i].Slot > signatures[j].Slot
+	})
+	if len(signatures) > maxTransactionsPerCall {
+		signatures = signatures[:maxTransactionsPerCall]
+	}
+
+	transactions := make([]*Transaction, 0, len(signatures))
+	for _, sig := range signatures {
+		tx, err := r.fetch(ctx, sig.Signature)
+		if err != nil {
+			return nil, err
+		}
+		if tx != nil {
+			transactions = append(transactions, tx)
+		}
+	}
+	return transactions, nil
+}
+
+// fetch 读取并解析单笔交易，优先使用缓存
+func (r *Resolver) fetch(ctx context.Context, signature solana.Signature) (*Transaction, error) {
+	key := signature.String()
+
+	r.mutex.Lock()
+	cached, exists := r.cache[key]
+	r.mutex.Unlock()
+	if exists {
+		return cached.tx, nil
+	}
+
+	tx, err := FetchTransaction(ctx, r.client, signature)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	r.mutex.Lock()
+	for sig, entry := range r.cache {
+		if now.Sub(entry.fetchedAt) > cacheTTL {
+			delete(r.cache, sig)
+		}
+	}
+	r.cache[key] = cachedTransaction{tx: tx, fetchedAt: now}
+	r.mutex.Unlock()
+
+	return tx, nil
+}
+
+// FetchTransaction 读取并解析单笔交易，不经过缓存
+func FetchTransaction(ctx context.Context, client *rpc.Client, signature solana.Signature) (*Transaction, error) {
+	maxVersion := uint64(0)
+	result, err := client.GetTransaction(ctx, signature, &rpc.GetTransactionOpts{
+		Encoding:                       solana.EncodingBase64,
+		Commitment:                     rpc.CommitmentConfirmed,
+		MaxSupportedTransactionVersion: &maxVersion,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction %s: %w", signature.String(), err)
+	}
+	return parseTransaction(signature.String(), result)
+}
+
+// parseTransaction 从交易元数据中提取余额变化与调用的程序
+func parseTransaction(signature string, result *rpc.GetTransactionResult) (*Transaction, error) {
+	if result == nil || result.Meta == nil || result.Transaction == nil {
+		return nil, fmt.Errorf("transaction %s has no metadata", signature)
+	}
+
+	decoded, err := result.Transaction.GetTransaction()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode transaction %s: %w", signature, err)
+	}
+
+	// v0 交易通过地址查找表加载的账户排在静态账户之后
+	keys := make(solana.PublicKeySlice, 0, len(decoded.Message.AccountKeys))
+	keys = append(keys, decoded.Message.AccountKeys...)
+	keys = append(keys, result.Meta.LoadedAddresses.Writable...)
+	keys = append(keys, result.Meta.LoadedAddresses.ReadOnly...)
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("transaction %s has no accounts", signature)
+	}
+
+	tx := &Transaction{
+		Signature:     signature,
+		Slot:          result.Slot,
+		FeePayer:      keys[0].String(),
+		Fee:           result.Meta.Fee,
+		TokenDeltas:   make(map[string]map[string]int64),
+		LamportDeltas: make(map[string]int64),
+		Decimals:      make(map[string]uint8),
+	}
+	for i := 0; i < int(decoded.Message.Header.NumRequiredSignatures) && i < len(keys); i++ {
+		tx.Signers = append(tx.Signers, keys[i].String())
+	}
+	if result.BlockTime != nil {
+		tx.BlockTime = result.BlockTime.Time()
+	}
+
+	// 记录调用的程序
+	seenPrograms := make(map[string]bool)
+	addProgram := func(index uint16) {
+		if int(index) >= len(keys) {
+			return
+		}
+		program := keys[index].String()
+		if !seenPrograms[program] {
+			seenPrograms[program] = true
+			tx.Programs = append(tx.Programs, program)
+		}
+	}
+	for _, instruction := range decoded.Message.Instructions {
+		addProgram(instruction.ProgramIDIndex)
+	}
+	for _, inner := range result.Meta.InnerInstructions {
+		for _, instruction := range inner.Instructions {
+			addProgram(instruction.ProgramIDIndex)
+		}
+	}
+
+	// lamports 变化
+	for i := 0; i < len(keys) && i < len(result.Meta.PreBalances) && i < len(result.Meta.PostBalances); i++ {
+		delta := int64(result.Meta.PostBalances[i]) - int64(result.Meta.PreBalances[i])
+		if delta != 0 {
+			tx.LamportDeltas[keys[i].String()] += delta
+		}
+	}
+
+	// 代币余额变化：按账户索引配对前后余额后再按所有者汇总
+	type tokenBalance struct {
+		owner string
+		mint  string
+		pre   int64
+		post  int64
+	}
+	balances := make(map[uint16]*tokenBalance)
+	collect := func(entries []rpc.TokenBalance, post bool) {
+		for _, entry := range entries {
+			if entry.UiTokenAmount == nil || int(entry.AccountIndex) >= len(keys) {
+				continue
+			}
+			amount, err := strconv.ParseInt(entry.UiTokenAmount.Amount, 10, 64)
+			if err != nil {
+				continue
+			}
+
+			tx.Decimals[entry.Mint.String()] = entry.UiTokenAmount.Decimals
+
+			balance, exists := balances[entry.AccountIndex]
+			if !exists {
+				// 旧交易可能缺少所有者字段，此时以代币账户地址代替
+				owner := keys[entry.AccountIndex].String()
+				if entry.Owner != nil {
+					owner = entry.Owner.String()
+				}
+				balance = &tokenBalance{owner: owner, mint: entry.Mint.String()}
+				balances[entry.AccountIndex] = balance
+			}
+			if post {
+				balance.post = amount
+			} else {
+				balance.pre = amount
+			}
+		}
+	}
+	collect(result.Meta.PreTokenBalances, false)
+	collect(result.Meta.PostTokenBalances, true)
+
+	for _, balance := range balances {
+		delta := balance.post - balance.pre
+		if delta == 0 {
+			continue
+		}
+		if tx.TokenDeltas[balance.owner] == nil {
+			tx.TokenDeltas[balance.owner] = make(map[string]int64)
+		}
+		tx.TokenDeltas[balance.owner][balance.mint] += delta
+	}
+
+	return tx, nil
+}
+
+// Delta 返回交易对 owner 持有的 mint 余额造成的变化。
+// 原生 SOL 将 lamports 与 wSOL 合并计算，并扣除交易手续费的影响
+func (tx *Transaction) Delta(owner, mint string) int64 {
+	delta := tx.TokenDeltas[owner][mint]
+	if mint == nativeMint {
+		delta += tx.LamportDeltas[owner]
+		if tx.FeePayer == owner {
+			delta += int64(tx.Fee)
+		}
+	}
+	return delta
+}
+
+// Mints 返回交易改变了 owner 余额的铸币，原生 SOL 以 wSOL 铸币地址表示，按地址排序
+func (tx *Transaction) Mints(owner string) []string {
+	var mints []string
+	for mint := range tx.TokenDeltas[owner] {
+		if mint != nativeMint {
+			mints = append(mints, mint)
+		}
+	}
+	if tx.Delta(owner, nativeMint) != 0 {
+		mints = append(mints, nativeMint)
+	}
+	sort.Strings(mints)
+	return mints
+}
+
+// Counterparties 返回与 owner 在该铸币上反向变化的其他地址
+func (tx *Transaction) Counterparties(owner, mint string) []string {
+	delta := tx.Delta(owner, mint)
+	if delta == 0 {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var counterparties []string
+	add := func(address string, other int64) {
+		if address == owner || seen[address] || other == 0 || (other > 0) == (delta > 0) {
+			return
+		}
+		seen[address] = true
+		counterparties = append(counterparties, address)
+	}
+
+	for address, mints := range tx.TokenDeltas {
+		add(address, mints[mint])
+	}
+	if mint == nativeMint {
+		for address, lamports := range tx.LamportDeltas {
+			add(address, lamports)
+		}
+	}
+
+	sort.Strings(counterparties)
+	return counterparties
+}
+
+// Attribute 从交易列表中找出改变了 owner 的 mint 余额的交易
+func Attribute(transactions []*Transaction, owner, mint string) []Attribution {
+	var attributions []Attribution
+	for _, tx := range transactions {
+		delta := tx.Delta(owner, mint)
+		if delta == 0 {
+			continue
+		}
+		attributions = append(attributions, Attribution{
+			Signature:      tx.Signature,
+			Slot:           tx.Slot,
+			BlockTime:      tx.BlockTime,
+			Delta:          delta,
+			Counterparties: tx.Counterparties(owner, mint),
+			Programs:       tx.Programs,
+			Classification: tx.Classify(owner, mint),
+		})
+	}
+	return attributions
+}
+
+// TokenAccountAddress 派生钱包在指定代币程序下的关联代币账户地址
+func TokenAccountAddress(wallet, mint, program solana.PublicKey) (solana.PublicKey, error) {
+	address, _, err := solana.FindProgramAddress([][]byte{
+		wallet[:],
+		program[:],
+		mint[:],
+	}, solana.SPLAssociatedTokenAccountProgramID)
+	return address, err
+}
@@ -0,0 +1,215 @@
+package attribution
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// buildSwapResult 构造一笔钱包支付 SOL 并收到代币的交易结果
+func buildSwapResult(t *testing.T, wallet, seller, mint solana.PublicKey) *rpc.GetTransactionResult {
+	walletATA := solana.NewWallet().PublicKey()
+	sellerATA := solana.NewWallet().PublicKey()
+
+	tx, err := solana.NewTransaction([]solana.Instruction{
+		system.NewTransferInstruction(1000, wallet, seller).Build(),
+		solana.NewInstruction(solana.TokenProgramID, solana.AccountMetaSlice{
+			solana.Meta(sellerATA).WRITE(),
+			solana.Meta(walletATA).WRITE(),
+			solana.Meta(seller).SIGNER(),
+		}, []byte{3}),
+	}, solana.Hash{}, solana.TransactionPayer(wallet))
+	require.NoError(t, err)
+
+	raw, err := tx.MarshalBinary()
+	require.NoError(t, err)
+
+	var envelope rpc.TransactionResultEnvelope
+	payload := fmt.Sprintf(`[%q,"base64"]`, base64.StdEncoding.EncodeToString(raw))
+	require.NoError(t, json.Unmarshal([]byte(payload), &envelope))
+
+	index := func(key solana.PublicKey) int {
+		for i, k := range tx.Message.AccountKeys {
+			if k.Equals(key) {
+				return i
+			}
+		}
+		t.Fatalf("account %s not in message", key)
+		return -1
+	}
+
+	keys := len(tx.Message.AccountKeys)
+	pre := make([]uint64, keys)
+	post := make([]uint64, keys)
+	pre[index(wallet)], post[index(wallet)] = 1_000_000, 1_000_000-1000-5000
+	pre[index(seller)], post[index(seller)] = 0, 1000
+
+	balance := func(account, owner solana.PublicKey, amount string) rpc.TokenBalance {
+		return rpc.TokenBalance{
+			AccountIndex:  uint16(index(account)),
+			Owner:         &owner,
+			Mint:          mint,
+			UiTokenAmount: &rpc.UiTokenAmount{Amount: amount, Decimals: 6},
+		}
+	}
+
+	blockTime := solana.UnixTimeSeconds(1_700_000_000)
+	return &rpc.GetTransactionResult{
+		Slot:        250_000_000,
+		BlockTime:   &blockTime,
+		Transaction: &envelope,
+		Meta: &rpc.TransactionMeta{
+			Fee:               5000,
+			PreBalances:       pre,
+			PostBalances:      post,
+			PreTokenBalances:  []rpc.TokenBalance{balance(sellerATA, seller, "800")},
+			PostTokenBalances: []rpc.TokenBalance{balance(sellerATA, seller, "300"), balance(walletATA, wallet, "500")},
+		},
+	}
+}
+
+func TestAttribute(t *testing.T) {
+	wallet := solana.NewWallet().PublicKey()
+	seller := solana.NewWallet().PublicKey()
+	mint := solana.NewWallet().PublicKey()
+
+	tx, err := parseTransaction("sig1", buildSwapResult(t, wallet, seller, mint))
+	require.NoError(t, err)
+	assert.Equal(t, wallet.String(), tx.FeePayer)
+	assert.Equal(t, []string{solana.SystemProgramID.String(), solana.TokenProgramID.String()}, tx.Programs)
+
+	t.Run("Token received", func(t *testing.T) {
+		attributions := Attribute([]*Transaction{tx}, wallet.String(), mint.String())
+		require.Len(t, attributions, 1)
+		assert.Equal(t, "sig1", attributions[0].Signature)
+		assert.Equal(t, uint64(250_000_000), attributions[0].Slot)
+		assert.Equal(t, int64(1_700_000_000), attributions[0].BlockTime.Unix())
+		assert.Equal(t, int64(500), attributions[0].Delta)
+		assert.Equal(t, []string{seller.String()}, attributions[0].Counterparties)
+	})
+
+	t.Run("SOL paid excludes fee", func(t *testing.T) {
+		attributions := Attribute([]*Transaction{tx}, wallet.String(), nativeMint)
+		require.Len(t, attributions, 1)
+		assert.Equal(t, int64(-1000), attributions[0].Delta)
+		assert.Equal(t, []string{seller.String()}, attributions[0].Counterparties)
+	})
+
+	t.Run("Counterparty view", func(t *testing.T) {
+		assert.Equal(t, int64(-500), tx.Delta(seller.String(), mint.String()))
+	})
+
+	t.Run("Unrelated mint", func(t *testing.T) {
+		assert.Empty(t, Attribute([]*Transaction{tx}, wallet.String(), solana.NewWallet().PublicKey().String()))
+	})
+}
+
+func TestParseTransactionWithoutMetadata(t *testing.T) {
+	_, err := parseTransaction("sig", &rpc.GetTransactionResult{})
+	assert.Error(t, err)
+}
+
+func TestClassify(t *testing.T) {
+	wallet := "Wallet1111111111111111111111111111111111111"
+	other := "Other11111111111111111111111111111111111111"
+	third := "Third11111111111111111111111111111111111111"
+	mint := "Mint111111111111111111111111111111111111111"
+	usdc := quotePreference[1]
+
+	tests := []struct {
+		name     string
+		tx       *Transaction
+		expected Classification
+	}{
+		{
+			name: "Buy on Jupiter with SOL",
+			tx: &Transaction{
+				FeePayer:      wallet,
+				Fee:           5000,
+				Signers:       []string{wallet},
+				Programs:      []string{"JUP6LkbZbjS1jKKwapdHNy74zcZ3tLUZoi5QNyVTaV4"},
+				TokenDeltas:   map[string]map[string]int64{wallet: {mint: 500}, other: {mint: -500}},
+				LamportDeltas: map[string]int64{wallet: -1_005_000, other: 1_000_000},
+			},
+			expected: Classification{Kind: KindBuy, Venue: "Jupiter", QuoteMint: nativeMint, QuoteAmount: 1_000_000, QuoteDecimals: 9},
+		},
+		{
+			name: "Sell on Raydium for USDC",
+			tx: &Transaction{
+				Signers:     []string{wallet},
+				Programs:    []string{"675kPX9MHTjS2zt1qfr1NYHuzeLXfQM9H24wFSUt1Mp8"},
+				TokenDeltas: map[string]map[string]int64{wallet: {mint: -500, usdc: 2_000_000}, other: {mint: 500, usdc: -2_000_000}},
+				Decimals:    map[string]uint8{usdc: 6},
+			},
+			expected: Classification{Kind: KindSell, Venue: "Raydium", QuoteMint: usdc, QuoteAmount: 2_000_000, QuoteDecimals: 6},
+		},
+		{
+			name: "Mint signed by wallet",
+			tx: &Transaction{
+				Signers:     []string{wallet},
+				TokenDeltas: map[string]map[string]int64{wallet: {mint: 100}},
+			},
+			expected: Classification{Kind: KindMint},
+		},
+		{
+			name: "Mint to wallet without its signature",
+			tx: &Transaction{
+				Signers:     []string{other},
+				TokenDeltas: map[string]map[string]int64{wallet: {mint: 100}},
+			},
+			expected: Classification{Kind: KindAirdrop},
+		},
+		{
+			name: "Burn",
+			tx: &Transaction{
+				Signers:     []string{wallet},
+				TokenDeltas: map[string]map[string]int64{wallet: {mint: -100}},
+			},
+			expected: Classification{Kind: KindBurn},
+		},
+		{
+			name: "Transfer out",
+			tx: &Transaction{
+				Signers:     []string{wallet},
+				TokenDeltas: map[string]map[string]int64{wallet: {mint: -100}, other: {mint: 100}},
+			},
+			expected: Classification{Kind: KindTransferOut},
+		},
+		{
+			name: "Transfer in",
+			tx: &Transaction{
+				Signers:     []string{other},
+				TokenDeltas: map[string]map[string]int64{wallet: {mint: 100}, other: {mint: -100}},
+			},
+			expected: Classification{Kind: KindTransferIn},
+		},
+		{
+			name: "Batch airdrop",
+			tx: &Transaction{
+				Signers:     []string{other},
+				TokenDeltas: map[string]map[string]int64{wallet: {mint: 100}, third: {mint: 100}, "x": {mint: 100}, other: {mint: -300}},
+			},
+			expected: Classification{Kind: KindAirdrop},
+		},
+		{
+			name: "Unrelated",
+			tx: &Transaction{
+				TokenDeltas: map[string]map[string]int64{other: {mint: 100}},
+			},
+			expected: Classification{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.tx.Classify(wallet, mint))
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package attribution
+
+import "sort"
+
+// 变化类型
+const (
+	KindBuy         = "buy"
+	KindSell        = "sell"
+	KindTransferIn  = "transfer_in"
+	KindTransferOut = "transfer_out"
+	KindMint        = "mint"
+	KindBurn        = "burn"
+	KindAirdrop     = "airdrop"
+)
+
+// 已知 DEX 与聚合器程序 -> 展示名称
+var dexPrograms = map[string]string{
+	"JUP6LkbZbjS1jKKwapdHNy74zcZ3tLUZoi5QNyVTaV4":  "Jupiter",
+	"JUP4Fb2cqiRUcaTHdrPC8h2gNsA2ETXiPDD33WcGuJB":  "Jupiter",
+	"675kPX9MHTjS2zt1qfr1NYHuzeLXfQM9H24wFSUt1Mp8": "Raydium",
+	"CAMMCzo5YL8w4VFF8KVHrK22GGUsp5VTaW7grrKgrWqK": "Raydium CLMM",
+	"CPMMoo8L3F4NbTegBCKVNunggL7H1ZpdTHKxQB5qKP1C": "Raydium CPMM",
+	"LanMV9sAd7wArD4vJFi2qDdfnVhFxYSUg6eADduJ3uj":  "Raydium LaunchLab",
+	"whirLbMiicVdio4qvUfM5KAg6Ct8VwpYzGff3uctyCc":  "Orca",
+	"9W959DqEETiGZocYWCQPaJ6sBmUzgfxXfqGeTEdp3aQP": "Orca",
+	"LBUZKhRxPF3XUpBCjp4YzTKgLccjZhTSDM9YuVaPwxo":  "Meteora DLMM",
+	"Eo7WjKq67rjJQSZxS6z3YkapzY3eMj6Xy8X5EQVn5UaB": "Meteora",
+	"cpamdpZCGKUy5JxQXB4dcpGPiikHawvSWAd6mEn1sGG":  "Meteora DAMM",
+	"6EF8rrecthR5Dkzon8Nwu78hRvfCKubJ14M5uBEwF6P":  "pump.fun",
+	"pAMMBay6oceH9fJKBRHGP5D4bD4sWpmSwMn52FMfXEA":  "PumpSwap",
+}
+
+// 报价资产的优先顺序：SOL、USDC、USDT
+var quotePreference = []string{
+	nativeMint,
+	"EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v",
+	"Es9vMFrzaCERmJfrF4H2FYD4KCoNkY11McCe8BenwNYB",
+}
+
+// 同一交易中至少有这么多其他地址收到该代币时视为空投
+const airdropMinRecipients = 2
+
+// Classification 描述一笔交易对钱包某代币余额的影响类型
+type Classification struct {
+	Kind          string `json:"kind,omitempty"`
+	Venue         string `json:"venue,omitempty"`      // 兑换所经过的 DEX 或聚合器
+	QuoteMint     string `json:"quote_mint,omitempty"` // 买入时支付、卖出时收到的资产
+	QuoteAmount   uint64 `json:"quote_amount,omitempty"`
+	QuoteDecimals uint8  `json:"quote_decimals,omitempty"`
+}
+
+// Venue 返回交易调用的第一个已知 DEX 或聚合器名称
+func (tx *Transaction) Venue() string {
+	for _, program := range tx.Programs {
+		if name, exists := dexPrograms[program]; exists {
+			return name
+		}
+	}
+	return ""
+}
+
+// Signed 判断 owner 是否签署了交易
+func (tx *Transaction) Signed(owner string) bool {
+	for _, signer := range tx.Signers {
+		if signer == owner {
+			return true
+		}
+	}
+	return false
+}
+
+// Classify 判断交易对 owner 持有的 mint 属于买入、卖出、转账、铸造、销毁还是空投
+func (tx *Transaction) Classify(owner, mint string) Classification {
+	delta := tx.Delta(owner, mint)
+	if delta == 0 {
+		return Classification{}
+	}
+
+	// 经过 DEX 且钱包在另一资产上有反向变化，视为兑换
+	if venue := tx.Venue(); venue != "" {
+		if quoteMint, quoteDelta := tx.quote(owner, mint, delta); quoteMint != "" {
+			kind := KindBuy
+			if delta < 0 {
+				kind = KindSell
+			}
+			return Classification{
+				Kind:          kind,
+				Venue:         venue,
+				QuoteMint:     quoteMint,
+				QuoteAmount:   uint64(absInt(quoteDelta)),
+				QuoteDecimals: tx.decimals(quoteMint),
+			}
+		}
+	}
+
+	signed := tx.Signed(owner)
+
+	// 总供应量的变化说明发生了铸造或销毁
+	if mint != nativeMint {
+		var supplyDelta int64
+		for _, mints := range tx.TokenDeltas {
+			supplyDelta += mints[mint]
+		}
+		switch {
+		case supplyDelta > 0 && delta > 0:
+			if !signed {
+				return Classification{Kind: KindAirdrop}
+			}
+			return Classification{Kind: KindMint}
+		case supplyDelta < 0 && delta < 0:
+			return Classification{Kind: KindBurn}
+		}
+	}
+
+	if delta < 0 {
+		return Classification{Kind: KindTransferOut}
+	}
+
+	// 未经钱包签名、且同时分发给多个地址的转入视为空投
+	if !signed {
+		recipients := 0
+		for address, mints := range tx.TokenDeltas {
+			if address != owner && mints[mint] > 0 {
+				recipients++
+			}
+		}
+		if recipients >= airdropMinRecipients {
+			return Classification{Kind: KindAirdrop}
+		}
+	}
+	return Classification{Kind: KindTransferIn}
+}
+
+// quote 返回与 mint 反向变化的报价资产及其变化量
+func (tx *Transaction) quote(owner, mint string, delta int64) (string, int64) {
+	opposite := func(candidate string) int64 {
+		if candidate == mint {
+			return 0
+		}
+		other := tx.Delta(owner, candidate)
+		if other == 0 || (other > 0) == (delta > 0) {
+			return 0
+		}
+		return other
+	}
+
+	for _, candidate := range quotePreference {
+		if other := opposite(candidate); other != 0 {
+			return candidate, other
+		}
+	}
+
+	// 其他代币之间的兑换，按地址排序保证结果稳定
+	candidates := make([]string, 0, len(tx.TokenDeltas[owner]))
+	for candidate := range tx.TokenDeltas[owner] {
+		candidates = append(candidates, candidate)
+	}
+	sort.Strings(candidates)
+	for _, candidate := range candidates {
+		if other := opposite(candidate); other != 0 {
+			return candidate, other
+		}
+	}
+	return "", 0
+}
+
+// decimals 返回交易中记录的铸币小数位
+func (tx *Transaction) decimals(mint string) uint8 {
+	if mint == nativeMint {
+		return 9
+	}
+	return tx.Decimals[mint]
+}
+
+func absInt(x int64) int64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,202 @@
+package authority
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	cacheFileName = "mint_authorities.json"
+	RecentWindow  = 24 * time.Hour // 权限在该时间内发生过变化视为近期变更
+)
+
+// Authorities 描述铸币当前的铸币权限与冻结权限，空字符串表示已放弃
+type Authorities struct {
+	MintAuthority   string `json:"mint_authority,omitempty"`
+	FreezeAuthority string `json:"freeze_authority,omitempty"`
+	// 权限地址是否为受监控的钱包
+	MintAuthorityMonitored   bool `json:"mint_authority_monitored,omitempty"`
+	FreezeAuthorityMonitored bool `json:"freeze_authority_monitored,omitempty"`
+	// 最近一次观察到权限变化的时间，零值表示未观察到变化
+	ChangedAt time.Time `json:"changed_at,omitempty"`
+}
+
+// Renounced 判断铸币权限与冻结权限是否均已放弃
+func (a Authorities) Renounced() bool {
+	return a.MintAuthority == "" && a.FreezeAuthority == ""
+}
+
+// RecentlyChanged 判断权限是否在 RecentWindow 内发生过变化
+func (a Authorities) RecentlyChanged(now time.Time) bool {
+	return !a.ChangedAt.IsZero() && now.Sub(a.ChangedAt) < RecentWindow
+}
+
+// Risks 返回未放弃或近期变更的权限描述，全部放弃且未变更时返回空
+func (a Authorities) Risks(now time.Time) []string {
+	var risks []string
+	if a.MintAuthority != "" {
+		risks = append(risks, fmt.Sprintf("Mint authority active: %s%s",
+			a.MintAuthority, monitoredSuffix(a.MintAuthorityMonitored)))
+	}
+	if a.FreezeAuthority != "" {
+		risks = append(risks, fmt.Sprintf("Freeze authority active: %s%s",
+			a.FreezeAuthority, monitoredSuffix(a.FreezeAuthorityMonitored)))
+	}
+	if a.RecentlyChanged(now) {
+		risks = append(risks, fmt.Sprintf("Authorities changed %s ago", now.Sub(a.ChangedAt).Round(time.Minute)))
+	}
+	return risks
+}
+
+// Summary 返回一行风险摘要
+func (a Authorities) Summary(now time.Time) string {
+	var parts []string
+	if a.MintAuthority != "" {
+		parts = append(parts, "mint authority active"+monitoredSuffix(a.MintAuthorityMonitored))
+	}
+	if a.FreezeAuthority != "" {
+		parts = append(parts, "freeze authority active"+monitoredSuffix(a.FreezeAuthorityMonitored))
+	}
+	if a.RecentlyChanged(now) {
+		parts = append(parts, "changed recently")
+	}
+	if len(parts) == 0 {
+		return "authorities renounced"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func monitoredSuffix(monitored bool) string {
+	if monitored {
+		return " (monitored wallet)"
+	}
+	return ""
+}
+
+// Diff 返回两次观察之间权限变化的描述
+func Diff(old, new Authorities) []string {
+	var lines []string
+	if old.MintAuthority != new.MintAuthority {
+		lines = append(lines, fmt.Sprintf("Mint authority: %s → %s", orRenounced(old.MintAuthority), orRenounced(new.MintAuthority)))
+	}
+	if old.FreezeAuthority != new.FreezeAuthority {
+		lines = append(lines, fmt.Sprintf("Freeze authority: %s → %s", orRenounced(old.FreezeAuthority), orRenounced(new.FreezeAuthority)))
+	}
+	return lines
+}
+
+// OnlyRenounced 判断变化是否仅为放弃权限，属于风险降低的操作
+func OnlyRenounced(old, new Authorities) bool {
+	if old.MintAuthority != new.MintAuthority && new.MintAuthority != "" {
+		return false
+	}
+	if old.FreezeAuthority != new.FreezeAuthority && new.FreezeAuthority != "" {
+		return false
+	}
+	return true
+}
+
+func orRenounced(address string) string {
+	if address == "" {
+		return "renounced"
+	}
+	return address
+}
+
+// record 为某个铸币最近一次观察到的权限
+type record struct {
+	MintAuthority   string    `json:"mint_authority,omitempty"`
+	FreezeAuthority string    `json:"freeze_authority,omitempty"`
+	FirstSeen       time.Time `json:"first_seen"`
+	ChangedAt       time.Time `json:"changed_at,omitempty"`
+}
+
+// Tracker 记录各铸币的权限及其最近一次变化的时间，并持久化到磁盘
+type Tracker struct {
+	path      string
+	records   map[string]record
+	dirty     bool
+	mutex     sync.Mutex
+	saveMutex sync.Mutex // 并发扫描时串行化文件写入
+}
+
+func NewTracker(dataDir string) *Tracker {
+	t := &Tracker{
+		path:    filepath.Join(dataDir, cacheFileName),
+		records: make(map[string]record),
+	}
+
+	if err := t.load(); err != nil {
+		log.Printf("⚠️  Warning: failed to load mint authority history: %v", err)
+	}
+	return t
+}
+
+// Observe 记录铸币当前的权限，返回附带最近变化时间的 Authorities
+func (t *Tracker) Observe(mint, mintAuthority, freezeAuthority string, now time.Time) Authorities {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	rec, exists := t.records[mint]
+	switch {
+	case !exists:
+		rec = record{MintAuthority: mintAuthority, FreezeAuthority: freezeAuthority, FirstSeen: now}
+		t.dirty = true
+	case rec.MintAuthority != mintAuthority || rec.FreezeAuthority != freezeAuthority:
+		rec.MintAuthority = mintAuthority
+		rec.FreezeAuthority = freezeAuthority
+		rec.ChangedAt = now
+		t.dirty = true
+	}
+	t.records[mint] = rec
+
+	return Authorities{
+		MintAuthority:   mintAuthority,
+		FreezeAuthority: freezeAuthority,
+		ChangedAt:       rec.ChangedAt,
+	}
+}
+
+// Save 在有新记录时将权限历史写入磁盘
+func (t *Tracker) Save() error {
+	t.saveMutex.Lock()
+	defer t.saveMutex.Unlock()
+
+	t.mutex.Lock()
+	if !t.dirty {
+		t.mutex.Unlock()
+		return nil
+	}
+	file, err := json.MarshalIndent(t.records, "", "  ")
+	t.dirty = false
+	t.mutex.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to marshal mint authority history: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(t.path), 0755); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+	return os.WriteFile(t.path, file, 0644)
+}
+
+// load 从磁盘读取权限历史
+func (t *Tracker) load() error {
+	file, err := os.ReadFile(t.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return json.Unmarshal(file, &t.records)
+}
@@ -0,0 +1,110 @@
+package authority
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrackerObserve(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tracker := NewTracker(dir)
+	first := tracker.Observe("mint", "dev", "", now)
+	assert.True(t, first.ChangedAt.IsZero())
+
+	same := tracker.Observe("mint", "dev", "", now.Add(time.Hour))
+	assert.True(t, same.ChangedAt.IsZero())
+
+	changed := tracker.Observe("mint", "", "", now.Add(2*time.Hour))
+	assert.Equal(t, now.Add(2*time.Hour), changed.ChangedAt)
+	assert.NoError(t, tracker.Save())
+
+	// 重启后保留变化时间
+	reloaded := NewTracker(dir).Observe("mint", "", "", now.Add(3*time.Hour))
+	assert.True(t, reloaded.ChangedAt.Equal(now.Add(2*time.Hour)))
+}
+
+func TestRisks(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		authorities Authorities
+		risks       []string
+		summary     string
+	}{
+		{
+			name:    "renounced",
+			summary: "authorities renounced",
+		},
+		{
+			name:        "renounced long ago",
+			authorities: Authorities{ChangedAt: now.Add(-48 * time.Hour)},
+			summary:     "authorities renounced",
+		},
+		{
+			name:        "active authority held by a monitored wallet",
+			authorities: Authorities{MintAuthority: "dev", MintAuthorityMonitored: true, FreezeAuthority: "freezer"},
+			risks: []string{
+				"Mint authority active: dev (monitored wallet)",
+				"Freeze authority active: freezer",
+			},
+			summary: "mint authority active (monitored wallet), freeze authority active",
+		},
+		{
+			name:        "recently changed",
+			authorities: Authorities{FreezeAuthority: "freezer", ChangedAt: now.Add(-90 * time.Minute)},
+			risks: []string{
+				"Freeze authority active: freezer",
+				"Authorities changed 1h30m0s ago",
+			},
+			summary: "freeze authority active, changed recently",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.risks, tt.authorities.Risks(now))
+			assert.Equal(t, tt.summary, tt.authorities.Summary(now))
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name          string
+		old, new      Authorities
+		lines         []string
+		onlyRenounced bool
+	}{
+		{
+			name:          "unchanged",
+			old:           Authorities{MintAuthority: "dev"},
+			new:           Authorities{MintAuthority: "dev"},
+			onlyRenounced: true,
+		},
+		{
+			name:          "renounced",
+			old:           Authorities{MintAuthority: "dev", FreezeAuthority: "dev"},
+			new:           Authorities{FreezeAuthority: "dev"},
+			lines:         []string{"Mint authority: dev → renounced"},
+			onlyRenounced: true,
+		},
+		{
+			name:  "transferred",
+			old:   Authorities{MintAuthority: "dev", FreezeAuthority: "dev"},
+			new:   Authorities{FreezeAuthority: "other"},
+			lines: []string{"Mint authority: dev → renounced", "Freeze authority: dev → other"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.lines, Diff(tt.old, tt.new))
+			assert.Equal(t, tt.onlyRenounced, OnlyRenounced(tt.old, tt.new))
+		})
+	}
+}
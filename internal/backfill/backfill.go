@@ -0,0 +1,308 @@
+package backfill
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/accursedgalaxy/insider-monitor/internal/attribution"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+const (
+	DefaultHorizon   = 30 * 24 * time.Hour
+	DefaultPageSize  = 100  // 每页签名数
+	maxPageSize      = 1000 // getSignaturesForAddress 单页上限
+	savePageInterval = 10   // 每处理该数量的页保存一次进度，避免每页重写整个历史文件
+)
+
+// Signature 为签名列表中的一项
+type Signature struct {
+	Signature string
+	Slot      uint64
+	BlockTime time.Time
+	Failed    bool
+}
+
+// Source 提供钱包的签名分页与交易解析
+type Source interface {
+	// Signatures 返回早于 before、晚于 until 的签名（均可为空），按时间从新到旧排列
+	Signatures(ctx context.Context, wallet, before, until string, limit int) ([]Signature, error)
+	Transaction(ctx context.Context, signature string) (*attribution.Transaction, error)
+}
+
+// Options 为回填参数
+type Options struct {
+	Horizon  time.Duration // 回溯的时间范围
+	PageSize int
+}
+
+// Summary 为一次回填的结果
+type Summary struct {
+	Wallet       string
+	Processed    int // 本次新处理的交易数
+	Movements    int // 本次新记录的代币变化数
+	Transactions int // 累计处理的交易数
+	Complete     bool
+}
+
+// Worker 回填钱包的历史交易，并将其中的代币变化按签名幂等地保存
+type Worker struct {
+	source  Source
+	store   *Store
+	options Options
+	now     func() time.Time
+}
+
+func NewWorker(source Source, store *Store, options Options) *Worker {
+	if options.Horizon <= 0 {
+		options.Horizon = DefaultHorizon
+	}
+	if options.PageSize <= 0 || options.PageSize > maxPageSize {
+		options.PageSize = DefaultPageSize
+	}
+	return &Worker{source: source, store: store, options: options, now: time.Now}
+}
+
+// Run 依次回填所有钱包，单个钱包失败不影响其他钱包；ctx 被取消时立即返回
+func (w *Worker) Run(ctx context.Context, wallets []string) ([]Summary, error) {
+	var summaries []Summary
+	var failed int
+	for _, wallet := range wallets {
+		summary, err := w.Backfill(ctx, wallet)
+		if ctx.Err() != nil {
+			return summaries, ctx.Err()
+		}
+		if err != nil {
+			failed++
+			log.Printf("❌ Backfill of wallet %s failed: %v", wallet, err)
+			continue
+		}
+		summaries = append(summaries, summary)
+		log.Printf("✅ Backfilled wallet %s: %d new transactions, %d token movements (%d transactions stored)",
+			wallet, summary.Processed, summary.Movements, summary.Transactions)
+	}
+	if failed > 0 {
+		return summaries, fmt.Errorf("backfill failed for %d of %d wallets", failed, len(wallets))
+	}
+	return summaries, nil
+}
+
+// Backfill 先补齐检查点之后的新交易，再从最早处理过的签名继续向前回溯到时间范围起点。
+// 每处理 savePageInterval 页以及出错时保存一次进度，中断后重新运行会从检查点继续，已处理的签名不会重复拉取；
+// 时间范围比上次回溯到的位置更早时继续向前回溯
+func (w *Worker) Backfill(ctx context.Context, wallet string) (Summary, error) {
+	history, err := w.store.Load(wallet)
+	if err != nil {
+		return Summary{}, err
+	}
+	summary := Summary{Wallet: wallet}
+	horizon := w.now().Add(-w.options.Horizon)
+	pages := 0
+
+	// 补齐上次运行之后的新交易，全部完成后才推进 Newest
+	if history.Checkpoint.Newest != "" {
+		var newest, before string
+		for {
+			page, err := w.source.Signatures(ctx, wallet, before, history.Checkpoint.Newest, w.options.PageSize)
+			if err != nil {
+				return summary, w.interrupted(history, err)
+			}
+			if len(page) == 0 {
+				break
+			}
+			if newest == "" {
+				newest = page[0].Signature
+			}
+			if err := w.processPage(ctx, history, page, &summary); err != nil {
+				return summary, w.interrupted(history, err)
+			}
+			if err := w.checkpoint(history, &pages); err != nil {
+				return summary, err
+			}
+			if len(page) < w.options.PageSize {
+				break
+			}
+			before = page[len(page)-1].Signature
+		}
+		if newest != "" {
+			history.Checkpoint.Newest = newest
+		}
+	}
+
+	// 时间范围延长到上次回溯到的位置之前时，从最早处理过的签名继续回溯
+	if history.Checkpoint.Complete && horizon.Before(history.Checkpoint.Horizon) {
+		history.Checkpoint.Complete = false
+	}
+
+	// 向前回溯
+	for !history.Checkpoint.Complete {
+		page, err := w.source.Signatures(ctx, wallet, history.Checkpoint.Oldest, "", w.options.PageSize)
+		if err != nil {
+			return summary, w.interrupted(history, err)
+		}
+
+		// 超出时间范围的签名不再处理
+		inRange := page
+		for i, sig := range page {
+			if !sig.BlockTime.IsZero() && sig.BlockTime.Before(horizon) {
+				inRange = page[:i]
+				history.Checkpoint.Complete = true
+				history.Checkpoint.Horizon = horizon
+				break
+			}
+		}
+		if !history.Checkpoint.Complete && len(page) < w.options.PageSize {
+			history.Checkpoint.Complete = true
+			history.Checkpoint.Horizon = time.Time{}
+		}
+
+		if err := w.processPage(ctx, history, inRange, &summary); err != nil {
+			return summary, w.interrupted(history, err)
+		}
+		if len(inRange) > 0 {
+			if history.Checkpoint.Newest == "" {
+				history.Checkpoint.Newest = inRange[0].Signature
+			}
+			history.Checkpoint.Oldest = inRange[len(inRange)-1].Signature
+		}
+		if err := w.checkpoint(history, &pages); err != nil {
+			return summary, err
+		}
+	}
+
+	if err := w.save(history); err != nil {
+		return summary, err
+	}
+	summary.Transactions = len(history.Transactions)
+	summary.Complete = history.Checkpoint.Complete
+	return summary, nil
+}
+
+// processPage 拉取并解析一页中尚未处理的交易
+func (w *Worker) processPage(ctx context.Context, history *History, page []Signature, summary *Summary) error {
+	for _, sig := range page {
+		if _, done := history.Transactions[sig.Signature]; done {
+			continue
+		}
+
+		record := &Record{Slot: sig.Slot, BlockTime: sig.BlockTime, Failed: sig.Failed}
+		if !sig.Failed {
+			tx, err := w.source.Transaction(ctx, sig.Signature)
+			if err != nil {
+				return err
+			}
+			record.Slot = tx.Slot
+			if !tx.BlockTime.IsZero() {
+				record.BlockTime = tx.BlockTime
+			}
+			record.Movements = movements(tx, history.Wallet)
+		}
+
+		history.Transactions[sig.Signature] = record
+		summary.Processed++
+		summary.Movements += len(record.Movements)
+	}
+	return nil
+}
+
+// interrupted 保存中断前已处理的交易，检查点不变，重新运行时这些签名会被跳过
+func (w *Worker) interrupted(history *History, err error) error {
+	if saveErr := w.save(history); saveErr != nil {
+		log.Printf("⚠️ %v", saveErr)
+	}
+	return err
+}
+
+// checkpoint 记录处理完的一页，每 savePageInterval 页保存一次进度
+func (w *Worker) checkpoint(history *History, pages *int) error {
+	*pages++
+	if *pages%savePageInterval != 0 {
+		return nil
+	}
+	return w.save(history)
+}
+
+func (w *Worker) save(history *History) error {
+	history.Checkpoint.UpdatedAt = w.now()
+	if err := w.store.Save(history); err != nil {
+		return fmt.Errorf("failed to save backfill checkpoint: %w", err)
+	}
+	return nil
+}
+
+// movements 提取交易对钱包各代币余额造成的变化
+func movements(tx *attribution.Transaction, wallet string) []Movement {
+	var result []Movement
+	for _, mint := range tx.Mints(wallet) {
+		classification := tx.Classify(wallet, mint)
+		result = append(result, Movement{
+			Mint:           mint,
+			Delta:          tx.Delta(wallet, mint),
+			Decimals:       tx.Decimals[mint],
+			Kind:           classification.Kind,
+			Venue:          classification.Venue,
+			Counterparties: tx.Counterparties(wallet, mint),
+		})
+	}
+	return result
+}
+
+// RPCSource 通过 RPC 分页读取签名并解析交易
+type RPCSource struct {
+	client *rpc.Client
+}
+
+func NewRPCSource(client *rpc.Client) *RPCSource {
+	return &RPCSource{client: client}
+}
+
+func (s *RPCSource) Signatures(ctx context.Context, wallet, before, until string, limit int) ([]Signature, error) {
+	address, err := solana.PublicKeyFromBase58(wallet)
+	if err != nil {
+		return nil, fmt.Errorf("invalid wallet address %s: %w", wallet, err)
+	}
+
+	opts := &rpc.GetSignaturesForAddressOpts{
+		Limit:      &limit,
+		Commitment: rpc.CommitmentFinalized,
+	}
+	if before != "" {
+		if opts.Before, err = solana.SignatureFromBase58(before); err != nil {
+			return nil, fmt.Errorf("invalid checkpoint signature %s: %w", before, err)
+		}
+	}
+	if until != "" {
+		if opts.Until, err = solana.SignatureFromBase58(until); err != nil {
+			return nil, fmt.Errorf("invalid checkpoint signature %s: %w", until, err)
+		}
+	}
+
+	results, err := s.client.GetSignaturesForAddressWithOpts(ctx, address, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get signatures for %s: %w", wallet, err)
+	}
+
+	signatures := make([]Signature, len(results))
+	for i, result := range results {
+		signatures[i] = Signature{
+			Signature: result.Signature.String(),
+			Slot:      result.Slot,
+			Failed:    result.Err != nil,
+		}
+		if result.BlockTime != nil {
+			signatures[i].BlockTime = result.BlockTime.Time()
+		}
+	}
+	return signatures, nil
+}
+
+func (s *RPCSource) Transaction(ctx context.Context, signature string) (*attribution.Transaction, error) {
+	sig, err := solana.SignatureFromBase58(signature)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature %s: %w", signature, err)
+	}
+	return attribution.FetchTransaction(ctx, s.client, sig)
+}
@@ -0,0 +1,264 @@
+package backfill
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/accursedgalaxy/insider-monitor/internal/attribution"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testWallet = "wallet"
+	testMint   = "mint"
+)
+
+// fakeSource 按从新到旧的顺序提供签名，与 getSignaturesForAddress 一致
+type fakeSource struct {
+	signatures []Signature
+	fetched    map[string]int
+	failAfter  int // 拉取该数量的交易后返回错误，0 表示不失败
+	pageLimit  int // 返回该数量的页后签名查询返回错误，0 表示不失败
+	pages      int
+}
+
+func newFakeSource(start time.Time, count int, interval time.Duration) *fakeSource {
+	source := &fakeSource{fetched: make(map[string]int)}
+	for i := count - 1; i >= 0; i-- {
+		source.signatures = append(source.signatures, Signature{
+			Signature: fmt.Sprintf("sig-%03d", i),
+			Slot:      uint64(100 + i),
+			BlockTime: start.Add(time.Duration(i) * interval),
+		})
+	}
+	return source
+}
+
+// prepend 在最前面追加更新的签名
+func (f *fakeSource) prepend(sigs ...Signature) {
+	f.signatures = append(sigs, f.signatures...)
+}
+
+func (f *fakeSource) Signatures(ctx context.Context, wallet, before, until string, limit int) ([]Signature, error) {
+	if f.pageLimit > 0 && f.pages >= f.pageLimit {
+		return nil, errors.New("rpc unavailable")
+	}
+	f.pages++
+
+	start := 0
+	if before != "" {
+		start = len(f.signatures)
+		for i, sig := range f.signatures {
+			if sig.Signature == before {
+				start = i + 1
+				break
+			}
+		}
+	}
+
+	var page []Signature
+	for _, sig := range f.signatures[start:] {
+		if sig.Signature == until || len(page) == limit {
+			break
+		}
+		page = append(page, sig)
+	}
+	return page, nil
+}
+
+func (f *fakeSource) Transaction(ctx context.Context, signature string) (*attribution.Transaction, error) {
+	if f.failAfter > 0 && len(f.fetched) >= f.failAfter {
+		return nil, errors.New("rpc unavailable")
+	}
+	f.fetched[signature]++
+
+	for _, sig := range f.signatures {
+		if sig.Signature == signature {
+			return &attribution.Transaction{
+				Signature:     signature,
+				Slot:          sig.Slot,
+				BlockTime:     sig.BlockTime,
+				TokenDeltas:   map[string]map[string]int64{testWallet: {testMint: 1000}, "seller": {testMint: -1000}},
+				LamportDeltas: map[string]int64{},
+				Decimals:      map[string]uint8{testMint: 6},
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("transaction %s not found", signature)
+}
+
+func newTestWorker(source Source, dir string, now time.Time, options Options) *Worker {
+	worker := NewWorker(source, NewStore(dir), options)
+	worker.now = func() time.Time { return now }
+	return worker
+}
+
+func TestBackfillStopsAtHorizon(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	source := newFakeSource(start, 10, time.Hour)
+	now := start.Add(10 * time.Hour)
+	worker := newTestWorker(source, t.TempDir(), now, Options{Horizon: 5*time.Hour + time.Minute, PageSize: 3})
+
+	summary, err := worker.Backfill(context.Background(), testWallet)
+	require.NoError(t, err)
+
+	// 仅 sig-005 至 sig-009 位于时间范围内
+	assert.True(t, summary.Complete)
+	assert.Equal(t, 5, summary.Processed)
+	assert.Equal(t, 5, summary.Movements)
+	assert.Len(t, source.fetched, 5)
+	assert.NotContains(t, source.fetched, "sig-004")
+
+	history, err := worker.store.Load(testWallet)
+	require.NoError(t, err)
+	assert.Equal(t, "sig-009", history.Checkpoint.Newest)
+	assert.Equal(t, "sig-005", history.Checkpoint.Oldest)
+
+	record := history.Transactions["sig-007"]
+	require.NotNil(t, record)
+	require.Len(t, record.Movements, 1)
+	assert.Equal(t, Movement{
+		Mint:           testMint,
+		Delta:          1000,
+		Decimals:       6,
+		Kind:           record.Movements[0].Kind,
+		Venue:          record.Movements[0].Venue,
+		Counterparties: []string{"seller"},
+	}, record.Movements[0])
+}
+
+func TestBackfillResumesAfterInterruption(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	dir := t.TempDir()
+	source := newFakeSource(start, 10, time.Hour)
+	source.failAfter = 4
+	now := start.Add(10 * time.Hour)
+
+	_, err := newTestWorker(source, dir, now, Options{PageSize: 3}).Backfill(context.Background(), testWallet)
+	require.Error(t, err)
+
+	// 中断前完成的整页已保存
+	history, err := NewStore(dir).Load(testWallet)
+	require.NoError(t, err)
+	assert.False(t, history.Checkpoint.Complete)
+	assert.Equal(t, "sig-007", history.Checkpoint.Oldest)
+
+	source.failAfter = 0
+	summary, err := newTestWorker(source, dir, now, Options{PageSize: 3}).Backfill(context.Background(), testWallet)
+	require.NoError(t, err)
+	assert.True(t, summary.Complete)
+	assert.Equal(t, 10, summary.Transactions)
+	for sig, count := range source.fetched {
+		assert.Equal(t, 1, count, "transaction %s fetched more than once", sig)
+	}
+}
+
+func TestBackfillSavesProgressWhenPagingFails(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	dir := t.TempDir()
+	source := newFakeSource(start, 30, time.Hour)
+	source.pageLimit = 3
+	now := start.Add(30 * time.Hour)
+
+	// 未到保存间隔的页在出错时同样保存
+	_, err := newTestWorker(source, dir, now, Options{PageSize: 2}).Backfill(context.Background(), testWallet)
+	require.Error(t, err)
+	history, err := NewStore(dir).Load(testWallet)
+	require.NoError(t, err)
+	assert.Len(t, history.Transactions, 6)
+	assert.Equal(t, "sig-024", history.Checkpoint.Oldest)
+
+	source.pageLimit = 0
+	summary, err := newTestWorker(source, dir, now, Options{PageSize: 2}).Backfill(context.Background(), testWallet)
+	require.NoError(t, err)
+	assert.True(t, summary.Complete)
+	assert.Equal(t, 24, summary.Processed)
+	assert.Equal(t, 30, summary.Transactions)
+}
+
+func TestBackfillExtendsHorizon(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	dir := t.TempDir()
+	source := newFakeSource(start, 10, time.Hour)
+	now := start.Add(10 * time.Hour)
+
+	summary, err := newTestWorker(source, dir, now, Options{Horizon: 3*time.Hour + time.Minute, PageSize: 3}).Backfill(context.Background(), testWallet)
+	require.NoError(t, err)
+	assert.True(t, summary.Complete)
+	assert.Equal(t, 3, summary.Transactions)
+
+	// 相同的时间范围不再回溯
+	summary, err = newTestWorker(source, dir, now, Options{Horizon: 3*time.Hour + time.Minute, PageSize: 3}).Backfill(context.Background(), testWallet)
+	require.NoError(t, err)
+	assert.Zero(t, summary.Processed)
+
+	// 延长时间范围后从上次最早的签名继续回溯到钱包的第一笔交易
+	summary, err = newTestWorker(source, dir, now, Options{Horizon: 30 * time.Hour, PageSize: 3}).Backfill(context.Background(), testWallet)
+	require.NoError(t, err)
+	assert.True(t, summary.Complete)
+	assert.Equal(t, 7, summary.Processed)
+	assert.Equal(t, 10, summary.Transactions)
+	for sig, count := range source.fetched {
+		assert.Equal(t, 1, count, "transaction %s fetched more than once", sig)
+	}
+
+	// 已到达第一笔交易时不再查询签名
+	pages := source.pages
+	summary, err = newTestWorker(source, dir, now, Options{Horizon: 60 * time.Hour, PageSize: 3}).Backfill(context.Background(), testWallet)
+	require.NoError(t, err)
+	assert.True(t, summary.Complete)
+	assert.Equal(t, pages+1, source.pages, "only the catch-up page is requested")
+}
+
+func TestBackfillCatchesUpNewSignatures(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	dir := t.TempDir()
+	source := newFakeSource(start, 4, time.Hour)
+	now := start.Add(4 * time.Hour)
+
+	_, err := newTestWorker(source, dir, now, Options{PageSize: 2}).Backfill(context.Background(), testWallet)
+	require.NoError(t, err)
+
+	// 上次运行之后出现了新交易
+	source.prepend(
+		Signature{Signature: "new-2", Slot: 202, BlockTime: now.Add(2 * time.Hour)},
+		Signature{Signature: "new-1", Slot: 201, BlockTime: now.Add(time.Hour), Failed: true},
+		Signature{Signature: "new-0", Slot: 200, BlockTime: now},
+	)
+	summary, err := newTestWorker(source, dir, now.Add(2*time.Hour), Options{PageSize: 2}).Backfill(context.Background(), testWallet)
+	require.NoError(t, err)
+	assert.Equal(t, 3, summary.Processed)
+	assert.Equal(t, 2, summary.Movements, "failed transactions have no movements")
+	assert.Equal(t, 7, summary.Transactions)
+	assert.NotContains(t, source.fetched, "new-1")
+
+	history, err := NewStore(dir).Load(testWallet)
+	require.NoError(t, err)
+	assert.Equal(t, "new-2", history.Checkpoint.Newest)
+	assert.True(t, history.Transactions["new-1"].Failed)
+
+	// 没有新交易时不做任何处理
+	summary, err = newTestWorker(source, dir, now.Add(2*time.Hour), Options{PageSize: 2}).Backfill(context.Background(), testWallet)
+	require.NoError(t, err)
+	assert.Zero(t, summary.Processed)
+	assert.Equal(t, 7, summary.Transactions)
+}
+
+func TestProcessPageIsIdempotent(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	source := newFakeSource(start, 3, time.Hour)
+	worker := newTestWorker(source, t.TempDir(), start, Options{})
+	history := &History{Wallet: testWallet, Transactions: make(map[string]*Record)}
+
+	var summary Summary
+	require.NoError(t, worker.processPage(context.Background(), history, source.signatures, &summary))
+	require.NoError(t, worker.processPage(context.Background(), history, source.signatures, &summary))
+
+	assert.Equal(t, 3, summary.Processed)
+	assert.Equal(t, 3, history.Movements())
+	assert.Len(t, source.fetched, 3)
+}
@@ -0,0 +1,135 @@
+package backfill
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Movement 为一笔交易对钱包某代币余额造成的变化
+type Movement struct {
+	Mint           string   `json:"mint"`
+	Delta          int64    `json:"delta"` // 原始数量变化，原生 SOL 不含手续费
+	Decimals       uint8    `json:"decimals"`
+	Kind           string   `json:"kind,omitempty"`
+	Venue          string   `json:"venue,omitempty"`
+	Counterparties []string `json:"counterparties,omitempty"`
+}
+
+// Record 为一笔已处理的交易，没有余额变化的交易同样记录以免重复拉取
+type Record struct {
+	Slot      uint64     `json:"slot"`
+	BlockTime time.Time  `json:"block_time"`
+	Failed    bool       `json:"failed,omitempty"`
+	Movements []Movement `json:"movements,omitempty"`
+}
+
+// Checkpoint 记录回填进度，中断后从此处继续
+type Checkpoint struct {
+	Newest    string    `json:"newest,omitempty"` // 已处理的最新签名，之后的新交易在下次运行时补齐
+	Oldest    string    `json:"oldest,omitempty"` // 已处理的最早签名，向前回溯从此处继续
+	Complete  bool      `json:"complete"`         // 已回溯到时间范围起点或钱包的第一笔交易
+	Horizon   time.Time `json:"horizon"`          // 回溯到的时间范围起点，已到达钱包的第一笔交易时为空
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// History 为单个钱包的回填结果，交易以签名为键保证幂等
+type History struct {
+	Wallet       string             `json:"wallet"`
+	Checkpoint   Checkpoint         `json:"checkpoint"`
+	Transactions map[string]*Record `json:"transactions"`
+}
+
+// Movements 返回全部代币变化的数量
+func (h *History) Movements() int {
+	count := 0
+	for _, record := range h.Transactions {
+		count += len(record.Movements)
+	}
+	return count
+}
+
+// Store 将各钱包的回填结果保存在数据目录下的 history 子目录中
+type Store struct {
+	dir string
+}
+
+func NewStore(dataDir string) *Store {
+	return &Store{dir: filepath.Join(dataDir, "history")}
+}
+
+func (s *Store) path(wallet string) string {
+	return filepath.Join(s.dir, wallet+".json")
+}
+
+// Load 读取钱包的回填结果，文件不存在时返回空记录
+func (s *Store) Load(wallet string) (*History, error) {
+	history := &History{Wallet: wallet, Transactions: make(map[string]*Record)}
+
+	file, err := os.ReadFile(s.path(wallet))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return history, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(file, history); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal history of %s: %w", wallet, err)
+	}
+	if history.Transactions == nil {
+		history.Transactions = make(map[string]*Record)
+	}
+	return history, nil
+}
+
+// LoadAll 读取全部已回填的钱包，按钱包地址排序
+func (s *Store) LoadAll() ([]*History, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read history directory: %w", err)
+	}
+
+	var wallets []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+			wallets = append(wallets, strings.TrimSuffix(entry.Name(), ".json"))
+		}
+	}
+	sort.Strings(wallets)
+
+	histories := make([]*History, 0, len(wallets))
+	for _, wallet := range wallets {
+		history, err := s.Load(wallet)
+		if err != nil {
+			return nil, err
+		}
+		histories = append(histories, history)
+	}
+	return histories, nil
+}
+
+// Save 写入钱包的回填结果，先写临时文件再重命名，避免中断时损坏已有数据
+func (s *Store) Save(history *History) error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	file, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal history of %s: %w", history.Wallet, err)
+	}
+
+	path := s.path(history.Wallet)
+	if err := os.WriteFile(path+".tmp", file, 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
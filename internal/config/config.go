@@ -6,27 +6,118 @@ import (
 	"log"
 	"os"
 	"strings"
+	"time"
 )
 
 type Config struct {
-	NetworkURL   string        `json:"network_url"`
-	Wallets      []string      `json:"wallets"`
-	ScanInterval string        `json:"scan_interval"`
-	Alerts       AlertConfig   `json:"alerts"`
-	Discord      DiscordConfig `json:"discord"`
-	Scan         ScanConfig    `json:"scan"`
+	NetworkURL   string              `json:"network_url"`
+	RPCEndpoints []RPCEndpointConfig `json:"rpc_endpoints"` // 多个 RPC 端点，配置后取代 network_url
+	Wallets      []string            `json:"wallets"`
+	Mints        []string            `json:"mints"` // 以代币为中心监控其主要持有者
+	ScanInterval string              `json:"scan_interval"`
+	Alerts       AlertConfig         `json:"alerts"`
+	Discord      DiscordConfig       `json:"discord"`
+	Scan         ScanConfig          `json:"scan"`
+	Stream       StreamConfig        `json:"stream"`
+	Holders      HolderConfig        `json:"holders"`
+	Backfill     BackfillConfig      `json:"backfill"`
+	Windows      []WindowConfig      `json:"windows"` // 滚动窗口内的累计变化检测
+	Correlation  CorrelationConfig   `json:"correlation"`
+}
+
+type CorrelationConfig struct {
+	MinWallets int    `json:"min_wallets"` // 窗口内同向变动同一代币的钱包数达到该值时告警，0 表示不检测
+	Window     string `json:"window"`      // 关联窗口，例如 "10m"，默认 15 分钟
+}
+
+type WindowConfig struct {
+	Duration  string  `json:"duration"`  // 窗口长度，例如 "15m"、"1h"、"24h"
+	Threshold float64 `json:"threshold"` // 窗口内累计变化达到该比例时告警，例如 0.20 表示 20%
+	MinUSD    float64 `json:"min_usd"`   // 累计变化的美元价值下限，0 表示不限制
+}
+
+type BackfillConfig struct {
+	Enabled  bool   `json:"enabled"`   // 启动时在后台回填各钱包的历史交易
+	Horizon  string `json:"horizon"`   // 回溯的时间范围，例如 "720h"，默认 30 天
+	PageSize int    `json:"page_size"` // 每页签名数，每页处理完保存一次进度，默认 100
+}
+
+type HolderConfig struct {
+	TopN                   int     `json:"top_n"`                   // 跟踪的主要持有者数量，默认且最多 20
+	SellThreshold          float64 `json:"sell_threshold"`          // 主要持有者余额下降达到该比例时告警，例如 0.20 表示 20%，默认 0.20
+	ConcentrationThreshold float64 `json:"concentration_threshold"` // 前 10 名持仓占比（%）越过该值时告警，0 表示不检查
+}
+
+type RPCEndpointConfig struct {
+	URL               string  `json:"url"`
+	Weight            int     `json:"weight"`              // 加权轮询权重，默认 1
+	RequestsPerSecond float64 `json:"requests_per_second"` // 该端点的速率上限，为空时使用 scan.requests_per_second
 }
 
 type AlertConfig struct {
 	MinimumBalance    uint64   `json:"minimum_balance"`    // 触发告警的最小余额
 	SignificantChange float64  `json:"significant_change"` // 例如 0.20 表示 20% 变化
 	IgnoreTokens      []string `json:"ignore_tokens"`      // 需要忽略的代币
+	// 钱包连续扫描失败达到该次数时发出告警，默认 3
+	WalletFailureThreshold int `json:"wallet_failure_threshold"`
+	// 变化的最终确认方式："wait" 在变化达到 finalized 后才告警，
+	// "retract" 立即告警并撤回未能最终确认的变化，为空表示不检查
+	Finality string `json:"finality"`
+	// 钱包 InsiderScore 低于该值时，余额变化、新代币与清仓告警仅记录日志，0 表示不限制
+	MinInsiderScore float64 `json:"min_insider_score"`
+	// 余额变化的美元价值低于该值时仅记录日志，0 表示不限制
+	MinUSDChange  float64  `json:"min_usd_change"`
+	IgnoreWallets []string `json:"ignore_wallets"` // 需要忽略的钱包
+	// 针对单个钱包或铸币覆盖默认阈值，同时设置时铸币的设置优先
+	WalletOverrides map[string]AlertOverride `json:"wallet_overrides"`
+	MintOverrides   map[string]AlertOverride `json:"mint_overrides"`
+	// 按顺序匹配的告警规则，第一条匹配的规则决定告警级别
+	Rules []AlertRule `json:"rules"`
+	// 同一钱包、铸币与告警类型的冷却期，例如 "15m"，期间的重复告警合并为摘要。默认 15 分钟，"0s" 表示不去重
+	Cooldown string `json:"cooldown"`
+}
+
+// AlertOverride 覆盖单个钱包或铸币的默认阈值，未设置的字段沿用上一级的值
+type AlertOverride struct {
+	MinimumBalance    *uint64  `json:"minimum_balance,omitempty"`
+	SignificantChange *float64 `json:"significant_change,omitempty"`
+	MinUSDChange      *float64 `json:"min_usd_change,omitempty"`
+	Ignore            bool     `json:"ignore"` // 忽略该钱包或铸币的全部变化
+}
+
+// AlertRule 为一条告警规则，所有已设置的条件均满足时匹配。数值条件为 0 表示不限制
+type AlertRule struct {
+	Name             string   `json:"name"`
+	Wallets          []string `json:"wallets"`            // 为空时匹配全部钱包
+	Mints            []string `json:"mints"`              // 为空时匹配全部铸币
+	ChangeTypes      []string `json:"change_types"`       // 为空时匹配全部变化类型
+	MinTokenDelta    float64  `json:"min_token_delta"`    // 按小数位换算后的余额变化量
+	MinUSDDelta      float64  `json:"min_usd_delta"`      // 余额变化的美元价值
+	MinChangePercent float64  `json:"min_change_percent"` // 余额变化百分比，例如 50 表示 50%
+	MinPositionValue float64  `json:"min_position_value"` // 变化后持仓的美元价值
+	// 表达式条件（expr 语法），例如 "usd_delta < -10000 && insider_score > 70"，变量见 rules.Env
+	Expression string `json:"expression"`
+	// 匹配时的告警级别："info"、"warning"、"critical"，或 "ignore" 表示丢弃
+	Level string `json:"level"`
 }
 
 type ScanConfig struct {
 	IncludeTokens []string `json:"include_tokens"` // 指定需要包含的代币（为空则包含全部）
 	ExcludeTokens []string `json:"exclude_tokens"` // 指定需要排除的代币
 	ScanMode      string   `json:"scan_mode"`      // "all"、"whitelist" 或 "blacklist"
+	// 是否请求元数据 URI 指向的链下 JSON 以获取代币图片
+	FetchOffChainMetadata bool `json:"fetch_offchain_metadata"`
+	// 并发扫描设置
+	Workers           int     `json:"workers"`             // 并发扫描的钱包数，默认 4
+	RequestsPerSecond float64 `json:"requests_per_second"` // 所有工作协程共享的 RPC 速率上限，默认 4
+	Timeout           string  `json:"timeout"`             // 单次扫描的截止时间，为空时使用 scan_interval
+	// 扫描与订阅使用的承诺级别："processed"、"confirmed" 或 "finalized"，默认 "confirmed"
+	Commitment string `json:"commitment"`
+}
+
+type StreamConfig struct {
+	Enabled      bool   `json:"enabled"` // 通过 WebSocket 订阅实时更新，不可用时回退到轮询
+	WebSocketURL string `json:"ws_url"`  // 为空时由 network_url 推导
 }
 
 type DiscordConfig struct {
@@ -52,7 +143,7 @@ var recommendedRPCProviders = map[string]string{
 }
 
 func (c *Config) Validate() error {
-	if c.NetworkURL == "" {
+	if c.NetworkURL == "" && len(c.RPCEndpoints) == 0 {
 		return fmt.Errorf("network URL is required\n\n" +
 			"💡 Recommendation: Get a dedicated RPC endpoint for better performance:\n" +
 			"   • Helius: 100k requests/day free - https://helius.dev\n" +
@@ -60,9 +151,19 @@ func (c *Config) Validate() error {
 			"   • Triton: 10M requests/month free - https://triton.one")
 	}
 
-	if len(c.Wallets) == 0 {
+	for i, endpoint := range c.RPCEndpoints {
+		if endpoint.URL == "" {
+			return fmt.Errorf("RPC endpoint at index %d has no url", i)
+		}
+		if endpoint.Weight < 0 || endpoint.RequestsPerSecond < 0 {
+			return fmt.Errorf("RPC endpoint at index %d has a negative weight or rate limit", i)
+		}
+	}
+
+	if len(c.Wallets) == 0 && len(c.Mints) == 0 {
 		return fmt.Errorf("at least one wallet address is required\n\n" +
-			"💡 Add wallet addresses to monitor in the 'wallets' array.\n" +
+			"💡 Add wallet addresses to monitor in the 'wallets' array,\n" +
+			"   or token mints in the 'mints' array to monitor their top holders.\n" +
 			"   Example: \"CvQk2xkXtiMj2JqqVx1YZkeSqQ7jyQkNqqjeNE1jPTfc\"")
 	}
 
@@ -75,32 +176,163 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	// 校验铸币地址格式
+	for i, mint := range c.Mints {
+		if len(mint) < 32 || len(mint) > 44 {
+			return fmt.Errorf("invalid mint address format at index %d: %s", i, mint)
+		}
+	}
+
+	if c.Alerts.MinInsiderScore < 0 || c.Alerts.MinInsiderScore > 100 {
+		return fmt.Errorf("alerts.min_insider_score must be between 0 and 100")
+	}
+
+	if err := c.Alerts.validateRules(); err != nil {
+		return err
+	}
+
+	switch c.Scan.Commitment {
+	case "", "processed", "confirmed", "finalized":
+	default:
+		return fmt.Errorf("invalid scan.commitment '%s': must be processed, confirmed or finalized", c.Scan.Commitment)
+	}
+	switch c.Alerts.Finality {
+	case "", "wait", "retract":
+	default:
+		return fmt.Errorf("invalid alerts.finality '%s': must be wait or retract", c.Alerts.Finality)
+	}
+	if c.Alerts.Cooldown != "" {
+		cooldown, err := time.ParseDuration(c.Alerts.Cooldown)
+		if err != nil {
+			return fmt.Errorf("invalid alerts.cooldown '%s': %w", c.Alerts.Cooldown, err)
+		}
+		if cooldown < 0 {
+			return fmt.Errorf("alerts.cooldown must not be negative")
+		}
+	}
+
+	if c.Backfill.Horizon != "" {
+		if _, err := time.ParseDuration(c.Backfill.Horizon); err != nil {
+			return fmt.Errorf("invalid backfill.horizon '%s': %w", c.Backfill.Horizon, err)
+		}
+	}
+
+	for i, w := range c.Windows {
+		duration, err := time.ParseDuration(w.Duration)
+		if err != nil {
+			return fmt.Errorf("invalid duration '%s' in window %d: %w", w.Duration, i, err)
+		}
+		if duration <= 0 || w.Threshold <= 0 || w.MinUSD < 0 {
+			return fmt.Errorf("window %d must have a positive duration and threshold", i)
+		}
+	}
+
+	if c.Correlation.MinWallets < 0 || c.Correlation.MinWallets == 1 {
+		return fmt.Errorf("correlation.min_wallets must be 0 (disabled) or at least 2")
+	}
+	if c.Correlation.Window != "" {
+		duration, err := time.ParseDuration(c.Correlation.Window)
+		if err != nil {
+			return fmt.Errorf("invalid correlation.window '%s': %w", c.Correlation.Window, err)
+		}
+		if duration <= 0 {
+			return fmt.Errorf("correlation.window must be positive")
+		}
+	}
+
+	if c.Holders.TopN < 0 || c.Holders.SellThreshold < 0 || c.Holders.ConcentrationThreshold < 0 {
+		return fmt.Errorf("holders settings must not be negative")
+	}
+
 	// 检查是否使用公共 RPC 端点
 	c.validateRPCEndpoint()
 
 	return nil
 }
 
+// validateRules 校验告警阈值、覆盖设置与规则
+func (a *AlertConfig) validateRules() error {
+	if a.SignificantChange < 0 || a.MinUSDChange < 0 {
+		return fmt.Errorf("alerts thresholds must not be negative")
+	}
+	for _, overrides := range []map[string]AlertOverride{a.WalletOverrides, a.MintOverrides} {
+		for address, override := range overrides {
+			if (override.SignificantChange != nil && *override.SignificantChange < 0) ||
+				(override.MinUSDChange != nil && *override.MinUSDChange < 0) {
+				return fmt.Errorf("alert override for %s has a negative threshold", address)
+			}
+		}
+	}
+	for i, rule := range a.Rules {
+		switch rule.Level {
+		case "info", "warning", "critical", "ignore":
+		default:
+			return fmt.Errorf("invalid level '%s' in alert rule %d (%s): must be info, warning, critical or ignore", rule.Level, i, rule.Name)
+		}
+		if rule.MinTokenDelta < 0 || rule.MinUSDDelta < 0 || rule.MinChangePercent < 0 || rule.MinPositionValue < 0 {
+			return fmt.Errorf("alert rule %d (%s) has a negative threshold", i, rule.Name)
+		}
+	}
+	return nil
+}
+
 // validateRPCEndpoint 检查用户是否使用公共 RPC 并给出警告
 func (c *Config) validateRPCEndpoint() {
+	if len(c.RPCEndpoints) > 0 {
+		log.Printf("✅ Using %d RPC endpoints", len(c.RPCEndpoints))
+	}
+
+	for _, endpoint := range c.Endpoints() {
+		c.checkPublicRPC(endpoint.URL)
+	}
+}
+
+// checkPublicRPC 对单个端点进行公共 RPC 检查
+func (c *Config) checkPublicRPC(url string) {
 	isPublicRPC := false
 	for _, publicURL := range publicRPCEndpoints {
-		if strings.EqualFold(c.NetworkURL, publicURL) {
+		if strings.EqualFold(url, publicURL) {
 			isPublicRPC = true
 			break
 		}
 	}
 
 	if isPublicRPC {
-		log.Printf("\n⚠️  WARNING: You're using a public RPC endpoint (%s)", c.NetworkURL)
+		log.Printf("\n⚠️  WARNING: You're using a public RPC endpoint (%s)", url)
 		log.Printf("   Public endpoints have strict rate limits and may cause scanning issues.\n")
 		log.Printf("🚀 RECOMMENDATION: Get a dedicated RPC endpoint for better performance:")
 		for provider, details := range recommendedRPCProviders {
 			log.Printf("   • %s: %s", provider, details)
 		}
 		log.Printf("\n💡 After getting your RPC endpoint, update 'network_url' in your config.json\n")
-	} else {
-		log.Printf("✅ Using custom RPC endpoint: %s", c.NetworkURL)
+	} else if len(c.RPCEndpoints) == 0 {
+		log.Printf("✅ Using custom RPC endpoint: %s", url)
+	}
+}
+
+// Endpoints 返回实际使用的 RPC 端点，未配置 rpc_endpoints 时使用 network_url
+func (c *Config) Endpoints() []RPCEndpointConfig {
+	if len(c.RPCEndpoints) > 0 {
+		return c.RPCEndpoints
+	}
+	return []RPCEndpointConfig{{URL: c.NetworkURL, Weight: 1}}
+}
+
+// WebSocketURL 返回流式订阅使用的 WebSocket 地址
+func (c *Config) WebSocketURL() string {
+	if c.Stream.WebSocketURL != "" {
+		return c.Stream.WebSocketURL
+	}
+
+	// 使用多个端点时以第一个端点推导
+	httpURL := c.Endpoints()[0].URL
+	switch {
+	case strings.HasPrefix(httpURL, "https://"):
+		return "wss://" + strings.TrimPrefix(httpURL, "https://")
+	case strings.HasPrefix(httpURL, "http://"):
+		return "ws://" + strings.TrimPrefix(httpURL, "http://")
+	default:
+		return httpURL
 	}
 }
 
@@ -0,0 +1,188 @@
+package correlate
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// 同一方向的持仓变化
+const (
+	SideBuy  = "buy"  // 买入新代币或加仓
+	SideSell = "sell" // 减仓或清仓
+)
+
+// 钱包在窗口内的合计动作
+const (
+	ActionEntered = "entered"
+	ActionAdded   = "added"
+	ActionReduced = "reduced"
+	ActionExited  = "exited"
+)
+
+// Move 为单个钱包对某铸币的一次持仓变化
+type Move struct {
+	Wallet     string
+	Mint       string
+	OldBalance uint64
+	NewBalance uint64
+	Decimals   uint8
+	USDPrice   float64
+	Time       time.Time
+}
+
+// Side 返回变化的方向
+func (m Move) Side() string {
+	if m.NewBalance > m.OldBalance {
+		return SideBuy
+	}
+	return SideSell
+}
+
+// Participant 为参与集群的钱包在窗口内的合计变化
+type Participant struct {
+	Wallet     string
+	Action     string
+	OldBalance uint64 // 窗口内第一次变化前的余额
+	NewBalance uint64 // 窗口内最后一次变化后的余额
+	Decimals   uint8
+	USDPrice   float64
+	Time       time.Time // 第一次变化的时间
+}
+
+// USDValue 返回合计变化量的美元价值，价格未知时为 0
+func (p Participant) USDValue() float64 {
+	delta := float64(p.NewBalance) - float64(p.OldBalance)
+	if delta < 0 {
+		delta = -delta
+	}
+	for i := uint8(0); i < p.Decimals; i++ {
+		delta /= 10
+	}
+	return delta * p.USDPrice
+}
+
+// Cluster 为窗口内同向变动同一铸币的一组钱包，按开始变动的时间排序
+type Cluster struct {
+	Side         string
+	Mint         string
+	Window       time.Duration
+	Participants []Participant
+}
+
+// Correlator 汇总所有监控钱包的持仓变化，在窗口内有至少 minWallets 个钱包同向变动同一铸币时
+// 给出集群。每个集群只告警一次，参与钱包数回落到 minWallets 以下后才会再次告警。可在多个 goroutine 中使用
+type Correlator struct {
+	minWallets int
+	window     time.Duration
+	moves      map[string][]Move // 铸币/方向 -> 窗口内的变化
+	fired      map[string]bool
+	mutex      sync.Mutex
+}
+
+// New 创建关联器，window 为同一集群内变化的最大时间跨度
+func New(minWallets int, window time.Duration) *Correlator {
+	return &Correlator{
+		minWallets: minWallets,
+		window:     window,
+		moves:      make(map[string][]Move),
+		fired:      make(map[string]bool),
+	}
+}
+
+// Observe 记录新的持仓变化，返回本次新形成的集群
+func (c *Correlator) Observe(moves []Move, now time.Time) []Cluster {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	touched := make(map[string]bool)
+	for _, move := range moves {
+		if move.NewBalance == move.OldBalance {
+			continue
+		}
+		key := move.Mint + "/" + move.Side()
+		c.moves[key] = append(c.moves[key], move)
+		touched[key] = true
+	}
+
+	var clusters []Cluster
+	for key, keyMoves := range c.moves {
+		// 变化时间可能早于观察时间（取自交易的区块时间），按变化时间判断是否仍在窗口内
+		start := now.Add(-c.window)
+		recent := keyMoves[:0]
+		for _, move := range keyMoves {
+			if move.Time.After(start) {
+				recent = append(recent, move)
+			}
+		}
+		if len(recent) == 0 {
+			delete(c.moves, key)
+			delete(c.fired, key)
+			continue
+		}
+		c.moves[key] = recent
+
+		participants := summarize(recent)
+		if len(participants) < c.minWallets {
+			delete(c.fired, key)
+			continue
+		}
+		if c.fired[key] || !touched[key] {
+			continue
+		}
+		c.fired[key] = true
+		clusters = append(clusters, Cluster{
+			Side:         recent[0].Side(),
+			Mint:         recent[0].Mint,
+			Window:       c.window,
+			Participants: participants,
+		})
+	}
+
+	sort.Slice(clusters, func(i, j int) bool {
+		if clusters[i].Mint != clusters[j].Mint {
+			return clusters[i].Mint < clusters[j].Mint
+		}
+		return clusters[i].Side < clusters[j].Side
+	})
+	return clusters
+}
+
+// summarize 按钱包合计变化，并按开始变动的时间排序
+func summarize(moves []Move) []Participant {
+	sorted := append([]Move(nil), moves...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Time.Before(sorted[j].Time)
+	})
+
+	byWallet := make(map[string]*Participant)
+	var order []string
+	for _, move := range sorted {
+		participant, exists := byWallet[move.Wallet]
+		if !exists {
+			participant = &Participant{Wallet: move.Wallet, OldBalance: move.OldBalance, Time: move.Time}
+			byWallet[move.Wallet] = participant
+			order = append(order, move.Wallet)
+		}
+		participant.NewBalance = move.NewBalance
+		participant.Decimals = move.Decimals
+		participant.USDPrice = move.USDPrice
+	}
+
+	participants := make([]Participant, 0, len(order))
+	for _, wallet := range order {
+		participant := *byWallet[wallet]
+		switch {
+		case participant.OldBalance == 0:
+			participant.Action = ActionEntered
+		case participant.NewBalance == 0:
+			participant.Action = ActionExited
+		case participant.NewBalance > participant.OldBalance:
+			participant.Action = ActionAdded
+		default:
+			participant.Action = ActionReduced
+		}
+		participants = append(participants, participant)
+	}
+	return participants
+}
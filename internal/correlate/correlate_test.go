@@ -0,0 +1,80 @@
+package correlate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObserveCoordinatedBuy(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	correlator := New(3, 10*time.Minute)
+
+	move := func(wallet string, old, new uint64, minute int) Move {
+		return Move{Wallet: wallet, Mint: "x", OldBalance: old, NewBalance: new, Decimals: 6, USDPrice: 2, Time: start.Add(time.Duration(minute) * time.Minute)}
+	}
+
+	assert.Empty(t, correlator.Observe([]Move{move("a", 0, 1_000_000, 0)}, start))
+	// 同一钱包多次加仓只计一次
+	assert.Empty(t, correlator.Observe([]Move{move("a", 1_000_000, 2_000_000, 2)}, start.Add(2*time.Minute)))
+	// 反方向的变化不计入买入集群
+	assert.Empty(t, correlator.Observe([]Move{move("c", 500, 0, 3)}, start.Add(3*time.Minute)))
+
+	// 变化按区块时间排序，晚观察到的钱包可能先动
+	clusters := correlator.Observe([]Move{move("c", 0, 300_000, 5), move("b", 100_000, 400_000, 1)}, start.Add(5*time.Minute))
+	require.Len(t, clusters, 1)
+	cluster := clusters[0]
+	assert.Equal(t, SideBuy, cluster.Side)
+	assert.Equal(t, "x", cluster.Mint)
+	assert.Equal(t, 10*time.Minute, cluster.Window)
+
+	require.Len(t, cluster.Participants, 3)
+	assert.Equal(t, []string{"a", "b", "c"}, []string{cluster.Participants[0].Wallet, cluster.Participants[1].Wallet, cluster.Participants[2].Wallet})
+	first := cluster.Participants[0]
+	assert.Equal(t, ActionEntered, first.Action)
+	assert.Equal(t, uint64(0), first.OldBalance)
+	assert.Equal(t, uint64(2_000_000), first.NewBalance)
+	assert.Equal(t, start, first.Time)
+	assert.InDelta(t, 4.0, first.USDValue(), 1e-9)
+	assert.Equal(t, ActionAdded, cluster.Participants[1].Action)
+	assert.Equal(t, ActionEntered, cluster.Participants[2].Action)
+
+	// 集群持续期间新的参与者不会重复告警
+	assert.Empty(t, correlator.Observe([]Move{move("d", 0, 1, 6)}, start.Add(6*time.Minute)))
+
+	// 变化移出窗口后集群解散，再次形成时重新告警
+	assert.Empty(t, correlator.Observe(nil, start.Add(20*time.Minute)))
+	clusters = correlator.Observe([]Move{move("a", 2_000_000, 3_000_000, 21), move("b", 400_000, 500_000, 21), move("e", 0, 1, 21)}, start.Add(21*time.Minute))
+	require.Len(t, clusters, 1)
+	assert.Len(t, clusters[0].Participants, 3)
+}
+
+func TestObserveCoordinatedSell(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	correlator := New(2, 10*time.Minute)
+
+	clusters := correlator.Observe([]Move{
+		{Wallet: "a", Mint: "x", OldBalance: 1000, NewBalance: 0, Time: start},
+		{Wallet: "b", Mint: "x", OldBalance: 1000, NewBalance: 400, Time: start.Add(time.Minute)},
+		{Wallet: "a", Mint: "y", OldBalance: 1000, NewBalance: 0, Time: start},
+		{Wallet: "c", Mint: "z", OldBalance: 1000, NewBalance: 1000, Time: start},
+	}, start.Add(time.Minute))
+
+	require.Len(t, clusters, 1)
+	assert.Equal(t, SideSell, clusters[0].Side)
+	assert.Equal(t, "x", clusters[0].Mint)
+	require.Len(t, clusters[0].Participants, 2)
+	assert.Equal(t, ActionExited, clusters[0].Participants[0].Action)
+	assert.Equal(t, ActionReduced, clusters[0].Participants[1].Action)
+	// 价格未知时美元价值为 0
+	assert.Zero(t, clusters[0].Participants[1].USDValue())
+
+	// 早于窗口的变化不计入
+	correlator = New(2, 10*time.Minute)
+	assert.Empty(t, correlator.Observe([]Move{
+		{Wallet: "a", Mint: "x", OldBalance: 1000, NewBalance: 0, Time: start},
+		{Wallet: "b", Mint: "x", OldBalance: 1000, NewBalance: 0, Time: start.Add(15 * time.Minute)},
+	}, start.Add(15*time.Minute)))
+}
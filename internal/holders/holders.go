@@ -0,0 +1,357 @@
+package holders
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/accursedgalaxy/insider-monitor/internal/mint"
+	"github.com/accursedgalaxy/insider-monitor/internal/token2022"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+const (
+	DefaultTopN          = 20  // getTokenLargestAccounts 最多返回 20 个账户
+	ConcentrationHolders = 10  // 集中度按前 10 名持有者计算
+	maxHistory           = 500 // 每个铸币保留的集中度历史点数
+	maxAccountsPerBatch  = 100 // getMultipleAccounts 单次请求上限
+)
+
+// 持有者事件类型
+const (
+	EventHolderSell    = "holder_sell"
+	EventNewTopHolder  = "new_top_holder"
+	EventConcentration = "holder_concentration"
+)
+
+// Holder 为按所有者汇总后的主要持有者
+type Holder struct {
+	Owner        string   `json:"owner"`
+	Accounts     []string `json:"accounts"` // 进入最大账户列表的代币账户
+	Balance      uint64   `json:"balance"`
+	Share        float64  `json:"share"` // 占总供应量的百分比
+	Rank         int      `json:"rank"`
+	PreviousRank int      `json:"previous_rank,omitempty"` // 上一次快照中的排名，0 表示新进入
+}
+
+// Point 为某一时刻的集中度记录
+type Point struct {
+	Time          time.Time `json:"time"`
+	Concentration float64   `json:"concentration"`
+}
+
+// Snapshot 为某个铸币在某一时刻的主要持有者快照
+type Snapshot struct {
+	Mint          string    `json:"mint"`
+	Decimals      uint8     `json:"decimals"`
+	Supply        uint64    `json:"supply"`
+	Holders       []Holder  `json:"holders"`
+	Concentration float64   `json:"concentration"` // 前 10 名持有者占总供应量的百分比
+	TakenAt       time.Time `json:"taken_at"`
+	History       []Point   `json:"history,omitempty"`
+
+	// 上一次快照中的主要持有者当前的余额（包括已跌出前 N 名的），仅用于比较
+	current map[string]uint64
+}
+
+// Holder 返回指定所有者在快照中的记录
+func (s *Snapshot) Holder(owner string) (Holder, bool) {
+	for _, holder := range s.Holders {
+		if holder.Owner == owner {
+			return holder, true
+		}
+	}
+	return Holder{}, false
+}
+
+// Event 描述两次快照之间值得告警的变化
+type Event struct {
+	Type             string
+	Mint             string
+	Owner            string
+	OldBalance       uint64
+	NewBalance       uint64
+	Rank             int
+	PreviousRank     int
+	OldConcentration float64
+	NewConcentration float64
+}
+
+// Tracker 获取铸币的主要持有者并解析其所有者
+type Tracker struct {
+	client *rpc.Client
+	mints  *mint.Resolver
+}
+
+func NewTracker(client *rpc.Client, mints *mint.Resolver) *Tracker {
+	return &Tracker{client: client, mints: mints}
+}
+
+// Snapshot 获取铸币当前的前 topN 名持有者（最多 20 名）。previous 为上一次快照，
+// 其中持有者的代币账户会一并查询，以便得知跌出前 N 名的持有者当前余额
+func (t *Tracker) Snapshot(ctx context.Context, mintAddr string, previous *Snapshot, topN int) (*Snapshot, error) {
+	if topN <= 0 || topN > DefaultTopN {
+		topN = DefaultTopN
+	}
+
+	mintKey, err := solana.PublicKeyFromBase58(mintAddr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mint address %s: %w", mintAddr, err)
+	}
+
+	infos, err := t.mints.Refresh(ctx, []string{mintAddr})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch mint %s: %w", mintAddr, err)
+	}
+	info, exists := infos[mintAddr]
+	if !exists {
+		return nil, fmt.Errorf("mint account %s not found", mintAddr)
+	}
+
+	largest, err := t.client.GetTokenLargestAccounts(ctx, mintKey, rpc.CommitmentConfirmed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get largest accounts for %s: %w", mintAddr, err)
+	}
+
+	// 最大账户列表与上一次持有者的账户一起查询
+	inLargest := make(map[solana.PublicKey]bool, len(largest.Value))
+	accounts := make([]solana.PublicKey, 0, len(largest.Value))
+	for _, account := range largest.Value {
+		if !inLargest[account.Address] {
+			inLargest[account.Address] = true
+			accounts = append(accounts, account.Address)
+		}
+	}
+	tracked := make(map[solana.PublicKey]bool)
+	if previous != nil {
+		for _, holder := range previous.Holders {
+			for _, address := range holder.Accounts {
+				key, err := solana.PublicKeyFromBase58(address)
+				if err != nil || inLargest[key] || tracked[key] {
+					continue
+				}
+				tracked[key] = true
+				accounts = append(accounts, key)
+			}
+		}
+	}
+
+	decoded, err := t.fetchAccounts(ctx, accounts)
+	if err != nil {
+		return nil, err
+	}
+
+	// 按所有者汇总最大账户列表中的余额
+	byOwner := make(map[string]*Holder)
+	current := make(map[string]uint64)
+	for _, address := range accounts {
+		account, exists := decoded[address]
+		if !exists || account.Mint != mintKey {
+			continue // 账户已关闭
+		}
+		owner := account.Owner.String()
+		current[owner] += account.Amount
+
+		if !inLargest[address] || account.Amount == 0 {
+			continue
+		}
+		holder, exists := byOwner[owner]
+		if !exists {
+			holder = &Holder{Owner: owner}
+			byOwner[owner] = holder
+		}
+		holder.Balance += account.Amount
+		holder.Accounts = append(holder.Accounts, address.String())
+	}
+
+	snapshot := &Snapshot{
+		Mint:     mintAddr,
+		Decimals: info.Decimals,
+		Supply:   info.Supply,
+		TakenAt:  time.Now(),
+		current:  current,
+	}
+	for _, holder := range byOwner {
+		holder.Share = info.SupplyShare(holder.Balance)
+		snapshot.Holders = append(snapshot.Holders, *holder)
+	}
+	rank(snapshot, previous, topN)
+
+	if previous != nil {
+		snapshot.History = append(snapshot.History, previous.History...)
+	}
+	snapshot.History = append(snapshot.History, Point{Time: snapshot.TakenAt, Concentration: snapshot.Concentration})
+	if len(snapshot.History) > maxHistory {
+		snapshot.History = snapshot.History[len(snapshot.History)-maxHistory:]
+	}
+	return snapshot, nil
+}
+
+// fetchAccounts 批量读取并解码代币账户，不存在的账户不出现在结果中
+func (t *Tracker) fetchAccounts(ctx context.Context, keys []solana.PublicKey) (map[solana.PublicKey]*tokenAccount, error) {
+	results := make(map[solana.PublicKey]*tokenAccount, len(keys))
+
+	for i := 0; i < len(keys); i += maxAccountsPerBatch {
+		end := i + maxAccountsPerBatch
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		resp, err := t.client.GetMultipleAccountsWithOpts(ctx, keys[i:end], &rpc.GetMultipleAccountsOpts{
+			Encoding:   solana.EncodingBase64,
+			Commitment: rpc.CommitmentConfirmed,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch token accounts %d-%d: %w", i, end, err)
+		}
+
+		for j, account := range resp.Value {
+			if account == nil || i+j >= end {
+				continue
+			}
+			decoded, _, err := token2022.DecodeAccount(account.Data.GetBinary())
+			if err != nil {
+				continue
+			}
+			results[keys[i+j]] = &tokenAccount{Mint: decoded.Mint, Owner: decoded.Owner, Amount: decoded.Amount}
+		}
+	}
+	return results, nil
+}
+
+type tokenAccount struct {
+	Mint   solana.PublicKey
+	Owner  solana.PublicKey
+	Amount uint64
+}
+
+// rank 按余额排序并截取前 topN 名，记录上一次的排名并计算集中度
+func rank(snapshot *Snapshot, previous *Snapshot, topN int) {
+	sort.Slice(snapshot.Holders, func(i, j int) bool {
+		if snapshot.Holders[i].Balance != snapshot.Holders[j].Balance {
+			return snapshot.Holders[i].Balance > snapshot.Holders[j].Balance
+		}
+		return snapshot.Holders[i].Owner < snapshot.Holders[j].Owner
+	})
+	if len(snapshot.Holders) > topN {
+		snapshot.Holders = snapshot.Holders[:topN]
+	}
+
+	var concentrated uint64
+	for i := range snapshot.Holders {
+		holder := &snapshot.Holders[i]
+		holder.Rank = i + 1
+		if previous != nil {
+			if old, exists := previous.Holder(holder.Owner); exists {
+				holder.PreviousRank = old.Rank
+			}
+		}
+		sort.Strings(holder.Accounts)
+		if i < ConcentrationHolders {
+			concentrated += holder.Balance
+		}
+	}
+	if snapshot.Supply > 0 {
+		snapshot.Concentration = float64(concentrated) / float64(snapshot.Supply) * 100.0
+	}
+}
+
+// Compare 比较两次快照，返回主要持有者卖出、新持有者进入前 N 名以及集中度越过阈值的事件。
+// sellThreshold 为余额下降比例（例如 0.2 表示 20%），concentrationThreshold 为百分比，0 表示不检查。
+//
+// 余额下降只有在减少的代币体现为其他已有主要持有者（通常为流动性池）的余额增加或供应量减少时才算卖出；
+// 转入新进入前 N 名的钱包视为转账，转入前 N 名之外的钱包无法观察到，也不算卖出。
+// 多个持有者同时下降时，按排名依次分摊这些变化
+func Compare(old, new *Snapshot, sellThreshold, concentrationThreshold float64) []Event {
+	if old == nil || new == nil {
+		return nil
+	}
+
+	entered, absorbed := counterparts(old, new)
+
+	var events []Event
+	for _, holder := range old.Holders {
+		balance := balanceIn(new, holder.Owner)
+		if balance >= holder.Balance {
+			continue
+		}
+		dropped := holder.Balance - balance
+		transferred := min(dropped, entered)
+		entered -= transferred
+		sold := min(dropped-transferred, absorbed)
+		absorbed -= sold
+		if float64(sold)/float64(holder.Balance) < sellThreshold {
+			continue
+		}
+
+		event := Event{
+			Type:         EventHolderSell,
+			Mint:         new.Mint,
+			Owner:        holder.Owner,
+			OldBalance:   holder.Balance,
+			NewBalance:   balance,
+			PreviousRank: holder.Rank,
+		}
+		if current, exists := new.Holder(holder.Owner); exists {
+			event.Rank = current.Rank
+		}
+		events = append(events, event)
+	}
+
+	for _, holder := range new.Holders {
+		if holder.PreviousRank != 0 {
+			continue
+		}
+		events = append(events, Event{
+			Type:       EventNewTopHolder,
+			Mint:       new.Mint,
+			Owner:      holder.Owner,
+			NewBalance: holder.Balance,
+			Rank:       holder.Rank,
+		})
+	}
+
+	// 集中度向上或向下越过阈值时各告警一次
+	if concentrationThreshold > 0 &&
+		(old.Concentration < concentrationThreshold) != (new.Concentration < concentrationThreshold) {
+		events = append(events, Event{
+			Type:             EventConcentration,
+			Mint:             new.Mint,
+			OldConcentration: old.Concentration,
+			NewConcentration: new.Concentration,
+		})
+	}
+	return events
+}
+
+// balanceIn 返回所有者在新快照中的余额。未查询到账户时以快照中的记录为准，账户关闭视为清仓
+func balanceIn(snapshot *Snapshot, owner string) uint64 {
+	if balance, known := snapshot.current[owner]; known {
+		return balance
+	}
+	if current, exists := snapshot.Holder(owner); exists {
+		return current.Balance
+	}
+	return 0
+}
+
+// counterparts 返回两次快照之间新进入前 N 名的持有者的余额合计，
+// 以及已有主要持有者的余额增加与供应量减少的合计
+func counterparts(old, new *Snapshot) (entered, absorbed uint64) {
+	for _, holder := range new.Holders {
+		if holder.PreviousRank == 0 {
+			entered += holder.Balance
+		}
+	}
+	for _, holder := range old.Holders {
+		if balance := balanceIn(new, holder.Owner); balance > holder.Balance {
+			absorbed += balance - holder.Balance
+		}
+	}
+	if new.Supply > 0 && new.Supply < old.Supply {
+		absorbed += old.Supply - new.Supply
+	}
+	return entered, absorbed
+}
@@ -0,0 +1,188 @@
+package holders
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRank(t *testing.T) {
+	previous := &Snapshot{Holders: []Holder{{Owner: "b", Rank: 1}, {Owner: "c", Rank: 2}}}
+	snapshot := &Snapshot{
+		Supply: 1000,
+		Holders: []Holder{
+			{Owner: "c", Balance: 100},
+			{Owner: "a", Balance: 300, Accounts: []string{"y", "x"}},
+			{Owner: "b", Balance: 300},
+		},
+	}
+
+	rank(snapshot, previous, 2)
+
+	assert.Len(t, snapshot.Holders, 2)
+	assert.Equal(t, "a", snapshot.Holders[0].Owner, "equal balances are ordered by owner")
+	assert.Equal(t, 1, snapshot.Holders[0].Rank)
+	assert.Equal(t, 0, snapshot.Holders[0].PreviousRank)
+	assert.Equal(t, []string{"x", "y"}, snapshot.Holders[0].Accounts)
+	assert.Equal(t, "b", snapshot.Holders[1].Owner)
+	assert.Equal(t, 2, snapshot.Holders[1].Rank)
+	assert.Equal(t, 1, snapshot.Holders[1].PreviousRank)
+	assert.InDelta(t, 60.0, snapshot.Concentration, 0.0001)
+}
+
+func TestCompare(t *testing.T) {
+	old := &Snapshot{
+		Mint:          "mint",
+		Supply:        10_000,
+		Concentration: 40,
+		Holders: []Holder{
+			{Owner: "whale", Balance: 1000, Rank: 1},
+			{Owner: "trimmer", Balance: 500, Rank: 2},
+			{Owner: "exited", Balance: 400, Rank: 3},
+		},
+	}
+
+	tests := []struct {
+		name                   string
+		new                    *Snapshot
+		concentrationThreshold float64
+		want                   []Event
+	}{
+		{
+			name: "no changes",
+			new: &Snapshot{
+				Mint:          "mint",
+				Concentration: 40,
+				Holders: []Holder{
+					{Owner: "whale", Balance: 1000, Rank: 1, PreviousRank: 1},
+					{Owner: "trimmer", Balance: 500, Rank: 2, PreviousRank: 2},
+					{Owner: "exited", Balance: 400, Rank: 3, PreviousRank: 3},
+				},
+			},
+			concentrationThreshold: 50,
+		},
+		{
+			name: "sells below threshold are ignored",
+			new: &Snapshot{
+				Mint:          "mint",
+				Concentration: 39,
+				Holders: []Holder{
+					{Owner: "whale", Balance: 900, Rank: 1, PreviousRank: 1},
+					{Owner: "trimmer", Balance: 500, Rank: 2, PreviousRank: 2},
+					{Owner: "exited", Balance: 400, Rank: 3, PreviousRank: 3},
+				},
+			},
+		},
+		{
+			name: "sell and exit into a tracked pool",
+			new: &Snapshot{
+				Mint:          "mint",
+				Concentration: 35,
+				Holders: []Holder{
+					{Owner: "whale", Balance: 1600, Rank: 1, PreviousRank: 1},
+					{Owner: "trimmer", Balance: 300, Rank: 2, PreviousRank: 2},
+				},
+				// exited 的账户已关闭，不在 current 中
+				current: map[string]uint64{"whale": 1600, "trimmer": 300},
+			},
+			want: []Event{
+				{Type: EventHolderSell, Mint: "mint", Owner: "trimmer", OldBalance: 500, NewBalance: 300, Rank: 2, PreviousRank: 2},
+				{Type: EventHolderSell, Mint: "mint", Owner: "exited", OldBalance: 400, NewBalance: 0, PreviousRank: 3},
+			},
+		},
+		{
+			name: "transfers to a new top holder are not sells",
+			new: &Snapshot{
+				Mint:          "mint",
+				Concentration: 35,
+				Holders: []Holder{
+					{Owner: "whale", Balance: 1000, Rank: 1, PreviousRank: 1},
+					{Owner: "newcomer", Balance: 600, Rank: 2},
+					{Owner: "trimmer", Balance: 300, Rank: 3, PreviousRank: 2},
+				},
+				current: map[string]uint64{"whale": 1000, "trimmer": 300, "newcomer": 600},
+			},
+			want: []Event{
+				{Type: EventNewTopHolder, Mint: "mint", Owner: "newcomer", NewBalance: 600, Rank: 2},
+			},
+		},
+		{
+			name: "tokens moved outside the top list are not sells",
+			new: &Snapshot{
+				Mint:          "mint",
+				Concentration: 35,
+				Holders: []Holder{
+					{Owner: "whale", Balance: 1000, Rank: 1, PreviousRank: 1},
+					{Owner: "exited", Balance: 400, Rank: 2, PreviousRank: 3},
+				},
+				current: map[string]uint64{"whale": 1000, "trimmer": 0, "exited": 400},
+			},
+		},
+		{
+			name: "burned tokens count as sold",
+			new: &Snapshot{
+				Mint:          "mint",
+				Supply:        9_500,
+				Concentration: 35,
+				Holders: []Holder{
+					{Owner: "whale", Balance: 1000, Rank: 1, PreviousRank: 1},
+					{Owner: "exited", Balance: 400, Rank: 2, PreviousRank: 3},
+				},
+				current: map[string]uint64{"whale": 1000, "trimmer": 0, "exited": 400},
+			},
+			want: []Event{
+				{Type: EventHolderSell, Mint: "mint", Owner: "trimmer", OldBalance: 500, NewBalance: 0, PreviousRank: 2},
+			},
+		},
+		{
+			name: "holder dropping out of the top list with a known balance",
+			new: &Snapshot{
+				Mint:          "mint",
+				Concentration: 40,
+				Holders: []Holder{
+					{Owner: "whale", Balance: 1000, Rank: 1, PreviousRank: 1},
+					{Owner: "trimmer", Balance: 500, Rank: 2, PreviousRank: 2},
+				},
+				current: map[string]uint64{"whale": 1000, "trimmer": 500, "exited": 390},
+			},
+		},
+		{
+			name: "concentration crosses threshold upwards",
+			new: &Snapshot{
+				Mint:          "mint",
+				Concentration: 55,
+				Holders: []Holder{
+					{Owner: "whale", Balance: 1000, Rank: 1, PreviousRank: 1},
+					{Owner: "trimmer", Balance: 500, Rank: 2, PreviousRank: 2},
+					{Owner: "exited", Balance: 400, Rank: 3, PreviousRank: 3},
+				},
+			},
+			concentrationThreshold: 50,
+			want: []Event{
+				{Type: EventConcentration, Mint: "mint", OldConcentration: 40, NewConcentration: 55},
+			},
+		},
+		{
+			name: "concentration check disabled",
+			new: &Snapshot{
+				Mint:          "mint",
+				Concentration: 55,
+				Holders: []Holder{
+					{Owner: "whale", Balance: 1000, Rank: 1, PreviousRank: 1},
+					{Owner: "trimmer", Balance: 500, Rank: 2, PreviousRank: 2},
+					{Owner: "exited", Balance: 400, Rank: 3, PreviousRank: 3},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Compare(old, tt.new, 0.2, tt.concentrationThreshold))
+		})
+	}
+}
+
+func TestCompareWithoutPrevious(t *testing.T) {
+	assert.Nil(t, Compare(nil, &Snapshot{Holders: []Holder{{Owner: "a", Rank: 1}}}, 0.2, 50))
+}
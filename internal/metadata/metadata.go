@@ -0,0 +1,346 @@
+package metadata
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/accursedgalaxy/insider-monitor/internal/token2022"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+const (
+	maxAccountsPerBatch = 100                // getMultipleAccounts 单次请求上限
+	missingRetryAfter   = 24 * time.Hour     // 无元数据的铸币隔一段时间再重试
+	foundRefreshAfter   = 7 * 24 * time.Hour // 可变的元数据（名称、图片）定期刷新
+	offChainTimeout     = 5 * time.Second    // 链下 JSON 请求超时
+	maxOffChainSize     = 1 << 20            // 链下 JSON 最大读取 1MB
+	cacheFileName       = "token_metadata.json"
+	ipfsGateway         = "https://ipfs.io/ipfs/"
+)
+
+// 元数据来源
+const (
+	SourceMetaplex  = "metaplex"
+	SourceToken2022 = "token-2022"
+)
+
+// TokenMetadata 为解析后的代币元数据
+type TokenMetadata struct {
+	Mint      string    `json:"mint"`
+	Name      string    `json:"name,omitempty"`
+	Symbol    string    `json:"symbol,omitempty"`
+	URI       string    `json:"uri,omitempty"`
+	Image     string    `json:"image,omitempty"`
+	Source    string    `json:"source,omitempty"` // 为空表示链上未找到元数据
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// Found 判断是否解析到了元数据
+func (m TokenMetadata) Found() bool {
+	return m.Source != ""
+}
+
+// Resolver 解析代币名称、符号与图片，并缓存到磁盘
+type Resolver struct {
+	client        *rpc.Client
+	httpClient    *http.Client
+	fetchOffChain bool
+	cachePath     string
+	cache         map[string]TokenMetadata
+	refreshAfter  time.Duration
+	mutex         sync.RWMutex
+	saveMutex     sync.Mutex // 并发扫描时串行化缓存文件写入
+}
+
+func NewResolver(client *rpc.Client, dataDir string, fetchOffChain bool) *Resolver {
+	r := &Resolver{
+		client:        client,
+		httpClient:    &http.Client{Timeout: offChainTimeout},
+		fetchOffChain: fetchOffChain,
+		cachePath:     filepath.Join(dataDir, cacheFileName),
+		cache:         make(map[string]TokenMetadata),
+		refreshAfter:  foundRefreshAfter,
+	}
+
+	if err := r.load(); err != nil {
+		log.Printf("⚠️  Warning: failed to load token metadata cache: %v", err)
+	}
+	return r
+}
+
+// Get 从缓存中读取元数据
+func (r *Resolver) Get(mint string) (TokenMetadata, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	metadata, exists := r.cache[mint]
+	return metadata, exists && metadata.Found()
+}
+
+// Resolve 返回给定铸币的元数据。embedded 为 Token-2022 铸币的扩展数据，
+// 若其中包含内嵌元数据则优先使用，否则查询 Metaplex 元数据 PDA。
+// 已解析的元数据超过 refreshAfter 后重新查询，查询失败或未找到时沿用旧数据；
+// Metaplex 查询失败时仍返回其余铸币的元数据，并返回错误
+func (r *Resolver) Resolve(ctx context.Context, mints []string, embedded map[string][]token2022.Extension) (map[string]TokenMetadata, error) {
+	results := make(map[string]TokenMetadata, len(mints))
+	previous := make(map[string]TokenMetadata)
+	var pending []string
+
+	r.mutex.RLock()
+	now := time.Now()
+	for _, mint := range mints {
+		metadata, exists := r.cache[mint]
+		if exists {
+			retryAfter := missingRetryAfter
+			if metadata.Found() {
+				retryAfter = r.refreshAfter
+			}
+			if now.Sub(metadata.FetchedAt) < retryAfter {
+				if metadata.Found() {
+					results[mint] = metadata
+				}
+				continue
+			}
+			if metadata.Found() {
+				previous[mint] = metadata
+			}
+		}
+		pending = append(pending, mint)
+	}
+	r.mutex.RUnlock()
+
+	if len(pending) == 0 {
+		return results, nil
+	}
+
+	resolved := make(map[string]TokenMetadata, len(pending))
+	var metaplexMints []string
+	for _, mint := range pending {
+		tokenMetadata, err := token2022.FindTokenMetadata(embedded[mint])
+		if err != nil {
+			log.Printf("⚠️  Warning: failed to parse Token-2022 metadata for %s: %v", mint, err)
+		}
+		if tokenMetadata != nil {
+			resolved[mint] = TokenMetadata{
+				Mint:      mint,
+				Name:      tokenMetadata.Name,
+				Symbol:    tokenMetadata.Symbol,
+				URI:       tokenMetadata.URI,
+				Source:    SourceToken2022,
+				FetchedAt: now,
+			}
+			continue
+		}
+		metaplexMints = append(metaplexMints, mint)
+	}
+
+	fetched, fetchErr := r.fetchMetaplex(ctx, metaplexMints)
+	for mint, metadata := range fetched {
+		resolved[mint] = metadata
+	}
+
+	for _, mint := range pending {
+		metadata, exists := resolved[mint]
+		if !exists {
+			if old, found := previous[mint]; found {
+				// 刷新未找到元数据时沿用旧数据，查询成功时推迟下次刷新
+				results[mint] = old
+				if fetchErr == nil {
+					old.FetchedAt = now
+					resolved[mint] = old
+				}
+				continue
+			}
+			// 记录缺失，避免每次扫描重复查询；查询失败时不记录，下次扫描重试
+			if fetchErr == nil {
+				resolved[mint] = TokenMetadata{Mint: mint, FetchedAt: now}
+			}
+			continue
+		}
+		if r.fetchOffChain && metadata.URI != "" {
+			metadata.Image = r.fetchImage(ctx, metadata.URI)
+			resolved[mint] = metadata
+		}
+		results[mint] = metadata
+	}
+
+	r.mutex.Lock()
+	for mint, metadata := range resolved {
+		r.cache[mint] = metadata
+	}
+	r.mutex.Unlock()
+
+	if err := r.save(); err != nil {
+		log.Printf("⚠️  Warning: failed to save token metadata cache: %v", err)
+	}
+
+	return results, fetchErr
+}
+
+// fetchMetaplex 派生元数据 PDA 并批量读取 Metaplex 元数据账户，失败的批次被跳过，错误合并后返回
+func (r *Resolver) fetchMetaplex(ctx context.Context, mints []string) (map[string]TokenMetadata, error) {
+	results := make(map[string]TokenMetadata, len(mints))
+	var errs []error
+
+	for i := 0; i < len(mints); i += maxAccountsPerBatch {
+		end := i + maxAccountsPerBatch
+		if end > len(mints) {
+			end = len(mints)
+		}
+
+		batch := make([]string, 0, end-i)
+		addresses := make([]solana.PublicKey, 0, end-i)
+		for _, mint := range mints[i:end] {
+			mintKey, err := solana.PublicKeyFromBase58(mint)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("invalid mint address %s: %w", mint, err))
+				continue
+			}
+			address, _, err := solana.FindTokenMetadataAddress(mintKey)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to derive metadata address for %s: %w", mint, err))
+				continue
+			}
+			batch = append(batch, mint)
+			addresses = append(addresses, address)
+		}
+		if len(addresses) == 0 {
+			continue
+		}
+
+		resp, err := r.client.GetMultipleAccountsWithOpts(ctx, addresses, &rpc.GetMultipleAccountsOpts{
+			Encoding: solana.EncodingBase64,
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to fetch metadata accounts %d-%d: %w", i, end, err))
+			continue
+		}
+
+		now := time.Now()
+		for j, account := range resp.Value {
+			if account == nil || j >= len(batch) {
+				continue
+			}
+			metadata, err := decodeMetaplex(account.Data.GetBinary())
+			if err != nil {
+				log.Printf("⚠️  Warning: failed to decode metadata for %s: %v", batch[j], err)
+				continue
+			}
+			metadata.Mint = batch[j]
+			metadata.FetchedAt = now
+			results[batch[j]] = *metadata
+		}
+	}
+
+	return results, errors.Join(errs...)
+}
+
+// decodeMetaplex 解码 Metaplex 元数据账户中的名称、符号与 URI
+//
+// 布局：key(1) + update_authority(32) + mint(32) + name + symbol + uri，
+// 字符串为 u32 长度前缀并以 \0 填充
+func decodeMetaplex(data []byte) (*TokenMetadata, error) {
+	offset := 1 + 32 + 32
+	if len(data) < offset {
+		return nil, fmt.Errorf("metadata account too short: %d bytes", len(data))
+	}
+
+	var fields [3]string
+	for i := range fields {
+		if offset+4 > len(data) {
+			return nil, fmt.Errorf("metadata string %d overflows account data", i)
+		}
+		length := int(binary.LittleEndian.Uint32(data[offset:]))
+		offset += 4
+		if offset+length > len(data) {
+			return nil, fmt.Errorf("metadata string %d overflows account data", i)
+		}
+		fields[i] = strings.TrimSpace(strings.TrimRight(string(data[offset:offset+length]), "\x00"))
+		offset += length
+	}
+
+	return &TokenMetadata{
+		Name:   fields[0],
+		Symbol: fields[1],
+		URI:    fields[2],
+		Source: SourceMetaplex,
+	}, nil
+}
+
+// fetchImage 获取链下 JSON 并返回其中的图片地址，失败时返回空字符串
+func (r *Resolver) fetchImage(ctx context.Context, uri string) string {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, gatewayURL(uri), nil)
+	if err != nil {
+		return ""
+	}
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return ""
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return ""
+	}
+
+	var offChain struct {
+		Image string `json:"image"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxOffChainSize)).Decode(&offChain); err != nil {
+		return ""
+	}
+	return gatewayURL(offChain.Image)
+}
+
+// gatewayURL 将 ipfs:// 地址转换为 HTTP 网关地址
+func gatewayURL(uri string) string {
+	if strings.HasPrefix(uri, "ipfs://") {
+		return ipfsGateway + strings.TrimPrefix(uri, "ipfs://")
+	}
+	return uri
+}
+
+// load 从磁盘读取元数据缓存
+func (r *Resolver) load() error {
+	file, err := os.ReadFile(r.cachePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return json.Unmarshal(file, &r.cache)
+}
+
+// save 将元数据缓存写入磁盘
+func (r *Resolver) save() error {
+	r.saveMutex.Lock()
+	defer r.saveMutex.Unlock()
+
+	r.mutex.RLock()
+	file, err := json.MarshalIndent(r.cache, "", "  ")
+	r.mutex.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata cache: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.cachePath), 0755); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+	return os.WriteFile(r.cachePath, file, 0644)
+}
@@ -0,0 +1,210 @@
+package metadata
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Metaplex 元数据账户的固定长度与字符串的填充长度
+const (
+	metadataAccountLen = 679
+	maxNameLen         = 32
+	maxSymbolLen       = 10
+	maxURILen          = 200
+)
+
+// metaplexAccount 按链上 Metadata V1 账户的布局编码：字符串按最大长度以 \0 填充，
+// 其后为版税、创作者、销售与可变标志等字段，账户以 0 填充到固定长度。
+// padded 为 false 时字符串不填充，与部分发行平台写入的账户一致
+func metaplexAccount(mint solana.PublicKey, name, symbol, uri string, padded bool) []byte {
+	data := []byte{4} // Key::MetadataV1
+	data = append(data, solana.NewWallet().PublicKey().Bytes()...)
+	data = append(data, mint.Bytes()...)
+	for _, field := range []struct {
+		value  string
+		maxLen int
+	}{{name, maxNameLen}, {symbol, maxSymbolLen}, {uri, maxURILen}} {
+		value := []byte(field.value)
+		if padded {
+			value = append(value, make([]byte, field.maxLen-len(value))...)
+		}
+		data = binary.LittleEndian.AppendUint32(data, uint32(len(value)))
+		data = append(data, value...)
+	}
+	data = binary.LittleEndian.AppendUint16(data, 0) // seller_fee_basis_points
+	data = append(data,
+		0,      // creators: None
+		0,      // primary_sale_happened
+		1,      // is_mutable
+		1, 254, // edition_nonce: Some(254)
+		1, 2, // token_standard: Some(Fungible)
+		0, // collection: None
+		0, // uses: None
+	)
+	return append(data, make([]byte, metadataAccountLen-len(data))...)
+}
+
+var usdcMint = solana.MustPublicKeyFromBase58("EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v")
+
+func TestDecodeMetaplex(t *testing.T) {
+	usdc := metaplexAccount(usdcMint, "USD Coin", "USDC", "", true)
+	require.Len(t, usdc, metadataAccountLen)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    TokenMetadata
+		message string
+	}{
+		{
+			name: "padded strings",
+			data: usdc,
+			want: TokenMetadata{Name: "USD Coin", Symbol: "USDC", Source: SourceMetaplex},
+		},
+		{
+			name: "unpadded strings",
+			data: metaplexAccount(usdcMint, "Dog Wif Hat ", "WIF", "https://ipfs.io/ipfs/QmW", false),
+			want: TokenMetadata{Name: "Dog Wif Hat", Symbol: "WIF", URI: "https://ipfs.io/ipfs/QmW", Source: SourceMetaplex},
+		},
+		{
+			name:    "too short",
+			data:    usdc[:40],
+			message: "metadata account too short: 40 bytes",
+		},
+		{
+			name:    "truncated name",
+			data:    usdc[:80],
+			message: "metadata string 0 overflows account data",
+		},
+		{
+			name:    "truncated symbol length",
+			data:    usdc[:1+32+32+4+maxNameLen+2],
+			message: "metadata string 1 overflows account data",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metadata, err := decodeMetaplex(tt.data)
+			if tt.message != "" {
+				assert.EqualError(t, err, tt.message)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, *metadata)
+		})
+	}
+}
+
+// fakeRPC 以元数据 PDA 为键返回 Metaplex 元数据账户
+type fakeRPC struct {
+	t        *testing.T
+	mutex    sync.Mutex
+	accounts map[string][]byte
+	calls    atomic.Int32
+	fail     atomic.Bool
+}
+
+func (f *fakeRPC) setMetadata(mint solana.PublicKey, data []byte) {
+	address, _, err := solana.FindTokenMetadataAddress(mint)
+	require.NoError(f.t, err)
+	f.mutex.Lock()
+	f.accounts[address.String()] = data
+	f.mutex.Unlock()
+}
+
+func (f *fakeRPC) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID     json.RawMessage   `json:"id"`
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+	require.NoError(f.t, json.NewDecoder(r.Body).Decode(&req))
+	require.Equal(f.t, "getMultipleAccounts", req.Method)
+	f.calls.Add(1)
+
+	response := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+	if f.fail.Load() {
+		response["error"] = map[string]interface{}{"code": -32000, "message": "node is unhealthy"}
+	} else {
+		var keys []string
+		require.NoError(f.t, json.Unmarshal(req.Params[0], &keys))
+		values := make([]interface{}, len(keys))
+		f.mutex.Lock()
+		for i, key := range keys {
+			if data, exists := f.accounts[key]; exists {
+				values[i] = map[string]interface{}{
+					"data":     []string{base64.StdEncoding.EncodeToString(data), "base64"},
+					"lamports": 5616720,
+					"owner":    solana.TokenMetadataProgramID.String(),
+				}
+			}
+		}
+		f.mutex.Unlock()
+		response["result"] = map[string]interface{}{"context": map[string]interface{}{"slot": 100}, "value": values}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	require.NoError(f.t, json.NewEncoder(w).Encode(response))
+}
+
+func TestResolveCachesMetadata(t *testing.T) {
+	f := &fakeRPC{t: t, accounts: make(map[string][]byte)}
+	f.setMetadata(usdcMint, metaplexAccount(usdcMint, "USD Coin", "USDC", "", true))
+	server := httptest.NewServer(f)
+	defer server.Close()
+
+	dir := t.TempDir()
+	client := rpc.New(server.URL)
+	resolver := NewResolver(client, dir, false)
+	missing := solana.NewWallet().PublicKey()
+	mints := []string{usdcMint.String(), missing.String()}
+	ctx := context.Background()
+
+	results, err := resolver.Resolve(ctx, mints, nil)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "USDC", results[usdcMint.String()].Symbol)
+	assert.Equal(t, int32(1), f.calls.Load())
+
+	// 找到的与缺失的元数据都被缓存，并在重启后从磁盘读取
+	_, err = resolver.Resolve(ctx, mints, nil)
+	require.NoError(t, err)
+	restarted := NewResolver(client, dir, false)
+	results, err = restarted.Resolve(ctx, mints, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "USD Coin", results[usdcMint.String()].Name)
+	assert.Equal(t, int32(1), f.calls.Load())
+
+	// 超过刷新间隔后重新查询找到的元数据，缺失的铸币仍按重试间隔等待
+	restarted.refreshAfter = 0
+	f.setMetadata(usdcMint, metaplexAccount(usdcMint, "USD Coin v2", "USDC", "", true))
+	results, err = restarted.Resolve(ctx, mints, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "USD Coin v2", results[usdcMint.String()].Name)
+	assert.Equal(t, int32(2), f.calls.Load())
+
+	// 刷新失败时沿用旧数据
+	f.fail.Store(true)
+	results, err = restarted.Resolve(ctx, mints, nil)
+	assert.Error(t, err)
+	assert.Equal(t, "USD Coin v2", results[usdcMint.String()].Name)
+
+	tokenMetadata, found := restarted.Get(usdcMint.String())
+	assert.True(t, found)
+	assert.Equal(t, "USD Coin v2", tokenMetadata.Name)
+	_, found = restarted.Get(missing.String())
+	assert.False(t, found)
+}
@@ -0,0 +1,170 @@
+package mint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/accursedgalaxy/insider-monitor/internal/token2022"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+const (
+	maxAccountsPerBatch = 100              // getMultipleAccounts 单次请求上限
+	defaultCacheTTL     = 10 * time.Minute // 供应量与权限为可变字段，定期刷新
+)
+
+// Info 描述一个铸币账户的链上信息
+type Info struct {
+	Mint            string                `json:"mint"`
+	Decimals        uint8                 `json:"decimals"`
+	Supply          uint64                `json:"supply"`
+	MintAuthority   string                `json:"mint_authority,omitempty"`
+	FreezeAuthority string                `json:"freeze_authority,omitempty"`
+	Extensions      []token2022.Extension `json:"-"`
+	FetchedAt       time.Time             `json:"fetched_at"`
+}
+
+// SupplyShare 返回数量占总供应量的百分比
+func (i Info) SupplyShare(amount uint64) float64 {
+	if i.Supply == 0 {
+		return 0
+	}
+	return float64(amount) / float64(i.Supply) * 100.0
+}
+
+// Resolver 批量获取并缓存铸币信息
+type Resolver struct {
+	client *rpc.Client
+	cache  map[string]Info
+	ttl    time.Duration
+	mutex  sync.RWMutex
+}
+
+func NewResolver(client *rpc.Client) *Resolver {
+	return &Resolver{
+		client: client,
+		cache:  make(map[string]Info),
+		ttl:    defaultCacheTTL,
+	}
+}
+
+// Resolve 返回给定铸币的信息，仅对缺失或过期的条目发起 RPC 请求。
+// 部分铸币无法获取时仍返回其余铸币的信息（过期的缓存条目优先于缺失），并返回错误
+func (r *Resolver) Resolve(ctx context.Context, mints []string) (map[string]Info, error) {
+	results := make(map[string]Info, len(mints))
+	var stale []string
+	expired := make(map[string]Info)
+
+	r.mutex.RLock()
+	now := time.Now()
+	for _, mint := range mints {
+		info, exists := r.cache[mint]
+		if exists && now.Sub(info.FetchedAt) < r.ttl {
+			results[mint] = info
+			continue
+		}
+		if exists {
+			expired[mint] = info
+		}
+		stale = append(stale, mint)
+	}
+	r.mutex.RUnlock()
+
+	if len(stale) == 0 {
+		return results, nil
+	}
+
+	fetched, err := r.fetch(ctx, stale)
+	for mint, info := range fetched {
+		results[mint] = info
+	}
+	if err != nil {
+		for mint, info := range expired {
+			if _, exists := results[mint]; !exists {
+				results[mint] = info
+			}
+		}
+	}
+	return results, err
+}
+
+// Refresh 强制重新获取给定铸币的信息，忽略缓存
+func (r *Resolver) Refresh(ctx context.Context, mints []string) (map[string]Info, error) {
+	return r.fetch(ctx, mints)
+}
+
+// fetch 按批次调用 getMultipleAccounts 并更新缓存。
+// 地址无效或所在批次请求失败的铸币被跳过，其余铸币照常返回，错误合并后返回
+func (r *Resolver) fetch(ctx context.Context, mints []string) (map[string]Info, error) {
+	results := make(map[string]Info, len(mints))
+	var errs []error
+
+	for i := 0; i < len(mints); i += maxAccountsPerBatch {
+		end := i + maxAccountsPerBatch
+		if end > len(mints) {
+			end = len(mints)
+		}
+
+		batch := make([]string, 0, end-i)
+		keys := make([]solana.PublicKey, 0, end-i)
+		for _, mint := range mints[i:end] {
+			key, err := solana.PublicKeyFromBase58(mint)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("invalid mint address %s: %w", mint, err))
+				continue
+			}
+			batch = append(batch, mint)
+			keys = append(keys, key)
+		}
+		if len(keys) == 0 {
+			continue
+		}
+
+		resp, err := r.client.GetMultipleAccountsWithOpts(ctx, keys, &rpc.GetMultipleAccountsOpts{
+			Encoding: solana.EncodingBase64,
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to fetch mint accounts %d-%d: %w", i, end, err))
+			continue
+		}
+
+		now := time.Now()
+		for j, account := range resp.Value {
+			if account == nil || j >= len(batch) {
+				continue // 铸币账户不存在
+			}
+
+			decoded, extensions, err := token2022.DecodeMint(account.Data.GetBinary())
+			if err != nil {
+				continue
+			}
+
+			info := Info{
+				Mint:       batch[j],
+				Decimals:   decoded.Decimals,
+				Supply:     decoded.Supply,
+				Extensions: extensions,
+				FetchedAt:  now,
+			}
+			if decoded.MintAuthority != nil {
+				info.MintAuthority = decoded.MintAuthority.String()
+			}
+			if decoded.FreezeAuthority != nil {
+				info.FreezeAuthority = decoded.FreezeAuthority.String()
+			}
+			results[batch[j]] = info
+		}
+	}
+
+	r.mutex.Lock()
+	for mint, info := range results {
+		r.cache[mint] = info
+	}
+	r.mutex.Unlock()
+
+	return results, errors.Join(errs...)
+}
@@ -0,0 +1,163 @@
+package mint
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/accursedgalaxy/insider-monitor/internal/token2022"
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRPC 按地址返回铸币账户数据，未列出的地址返回 null
+type fakeRPC struct {
+	t        *testing.T
+	accounts map[string][]byte
+	calls    atomic.Int32
+	fail     atomic.Bool // 为 true 时 getMultipleAccounts 返回错误
+}
+
+func (f *fakeRPC) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID     json.RawMessage   `json:"id"`
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+	require.NoError(f.t, json.NewDecoder(r.Body).Decode(&req))
+	require.Equal(f.t, "getMultipleAccounts", req.Method)
+	f.calls.Add(1)
+
+	response := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+	if f.fail.Load() {
+		response["error"] = map[string]interface{}{"code": -32000, "message": "node is unhealthy"}
+	} else {
+		var keys []string
+		require.NoError(f.t, json.Unmarshal(req.Params[0], &keys))
+		values := make([]interface{}, len(keys))
+		for i, key := range keys {
+			if data, exists := f.accounts[key]; exists {
+				values[i] = map[string]interface{}{
+					"data":     []string{base64.StdEncoding.EncodeToString(data), "base64"},
+					"lamports": 1461600,
+					"owner":    solana.TokenProgramID.String(),
+				}
+			}
+		}
+		response["result"] = map[string]interface{}{"context": map[string]interface{}{"slot": 100}, "value": values}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	require.NoError(f.t, json.NewEncoder(w).Encode(response))
+}
+
+func encodeMint(t *testing.T, mint token.Mint) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, bin.NewBinEncoder(&buf).Encode(mint))
+	return buf.Bytes()
+}
+
+// encodeToken2022Mint 将铸币填充到代币账户长度，追加账户类型与一个不可转让扩展
+func encodeToken2022Mint(t *testing.T, mint token.Mint) []byte {
+	data := make([]byte, token2022.AccountLen)
+	copy(data, encodeMint(t, mint))
+	data = append(data, byte(token2022.AccountTypeMint))
+	header := make([]byte, 4)
+	binary.LittleEndian.PutUint16(header, uint16(token2022.ExtensionNonTransferable))
+	return append(data, header...)
+}
+
+func newResolver(t *testing.T, accounts map[string][]byte) (*Resolver, *fakeRPC) {
+	f := &fakeRPC{t: t, accounts: accounts}
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	return NewResolver(rpc.New(server.URL)), f
+}
+
+func TestResolveClassicAndToken2022Mints(t *testing.T) {
+	authority := solana.NewWallet().PublicKey()
+	classic := solana.NewWallet().PublicKey().String()
+	token22 := solana.NewWallet().PublicKey().String()
+	missing := solana.NewWallet().PublicKey().String()
+
+	resolver, _ := newResolver(t, map[string][]byte{
+		classic: encodeMint(t, token.Mint{MintAuthority: &authority, Supply: 1_000_000, Decimals: 6, IsInitialized: true}),
+		token22: encodeToken2022Mint(t, token.Mint{FreezeAuthority: &authority, Supply: 500, Decimals: 2, IsInitialized: true}),
+	})
+
+	infos, err := resolver.Resolve(context.Background(), []string{classic, token22, missing})
+	require.NoError(t, err)
+	require.Len(t, infos, 2, "mints without an account are left out")
+
+	assert.Equal(t, uint8(6), infos[classic].Decimals)
+	assert.Equal(t, uint64(1_000_000), infos[classic].Supply)
+	assert.Equal(t, authority.String(), infos[classic].MintAuthority)
+	assert.Empty(t, infos[classic].FreezeAuthority)
+	assert.Empty(t, infos[classic].Extensions)
+	assert.InDelta(t, 25.0, infos[classic].SupplyShare(250_000), 1e-9)
+
+	assert.Equal(t, uint8(2), infos[token22].Decimals)
+	assert.Equal(t, authority.String(), infos[token22].FreezeAuthority)
+	require.Len(t, infos[token22].Extensions, 1)
+	assert.Equal(t, token2022.ExtensionNonTransferable, infos[token22].Extensions[0].Type)
+}
+
+func TestResolveCaches(t *testing.T) {
+	address := solana.NewWallet().PublicKey().String()
+	resolver, f := newResolver(t, map[string][]byte{
+		address: encodeMint(t, token.Mint{Supply: 100, Decimals: 9, IsInitialized: true}),
+	})
+	ctx := context.Background()
+
+	_, err := resolver.Resolve(ctx, []string{address})
+	require.NoError(t, err)
+	infos, err := resolver.Resolve(ctx, []string{address})
+	require.NoError(t, err)
+	assert.Equal(t, uint8(9), infos[address].Decimals)
+	assert.Equal(t, int32(1), f.calls.Load(), "cached mints are not fetched again")
+
+	// Refresh 忽略缓存
+	_, err = resolver.Refresh(ctx, []string{address})
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), f.calls.Load())
+
+	// 过期的条目重新获取
+	resolver.ttl = time.Nanosecond
+	_, err = resolver.Resolve(ctx, []string{address})
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), f.calls.Load())
+}
+
+func TestResolveDegradesPerMint(t *testing.T) {
+	address := solana.NewWallet().PublicKey().String()
+	resolver, f := newResolver(t, map[string][]byte{
+		address: encodeMint(t, token.Mint{Supply: 100, Decimals: 9, IsInitialized: true}),
+	})
+	ctx := context.Background()
+
+	// 无效的地址不影响其他铸币
+	infos, err := resolver.Resolve(ctx, []string{"not-a-mint", address})
+	assert.ErrorContains(t, err, "invalid mint address not-a-mint")
+	require.Contains(t, infos, address)
+	assert.Equal(t, uint8(9), infos[address].Decimals)
+
+	// 请求失败时沿用过期的缓存条目，没有缓存的铸币缺失
+	other := solana.NewWallet().PublicKey().String()
+	resolver.ttl = time.Nanosecond
+	f.fail.Store(true)
+	infos, err = resolver.Resolve(ctx, []string{address, other})
+	assert.Error(t, err)
+	assert.Equal(t, uint8(9), infos[address].Decimals)
+	assert.NotContains(t, infos, other)
+}
@@ -0,0 +1,155 @@
+package monitor
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/accursedgalaxy/insider-monitor/internal/utils"
+)
+
+// 代币账户状态事件，通常是协同抛售或钱包被盗的前兆
+const (
+	EventDelegateApproved       = "delegate_approved"
+	EventDelegateRevoked        = "delegate_revoked"
+	EventDelegatedAmountChanged = "delegated_amount_changed"
+	EventFrozen                 = "frozen"
+	EventThawed                 = "thawed"
+	EventCloseAuthorityChanged  = "close_authority_changed"
+)
+
+// AccountStateChange 描述单个代币账户在两次快照之间的状态变化
+type AccountStateChange struct {
+	Address string
+	Events  []string
+	Old     TokenAccountDetail
+	New     TokenAccountDetail
+}
+
+// HasEvent 判断是否包含指定事件
+func (a *AccountStateChange) HasEvent(event string) bool {
+	for _, e := range a.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Benign 判断变化是否仅为撤销委托或解冻等风险降低的操作
+func (a *AccountStateChange) Benign() bool {
+	for _, event := range a.Events {
+		if event != EventDelegateRevoked && event != EventThawed {
+			return false
+		}
+	}
+	return true
+}
+
+// Describe 返回各事件的可读描述，数量按 decimals 换算
+func (a *AccountStateChange) Describe(decimals uint8) []string {
+	var lines []string
+	for _, event := range a.Events {
+		switch event {
+		case EventDelegateApproved:
+			lines = append(lines, fmt.Sprintf("Delegate approved: %s may transfer %s",
+				a.New.Delegate, utils.FormatTokenAmount(a.New.DelegatedAmount, decimals)))
+		case EventDelegateRevoked:
+			lines = append(lines, fmt.Sprintf("Delegate revoked: %s", a.Old.Delegate))
+		case EventDelegatedAmountChanged:
+			lines = append(lines, fmt.Sprintf("Delegated amount changed: %s → %s",
+				utils.FormatTokenAmount(a.Old.DelegatedAmount, decimals),
+				utils.FormatTokenAmount(a.New.DelegatedAmount, decimals)))
+		case EventFrozen:
+			lines = append(lines, "Account frozen")
+		case EventThawed:
+			lines = append(lines, "Account thawed")
+		case EventCloseAuthorityChanged:
+			lines = append(lines, fmt.Sprintf("Close authority changed: %s → %s",
+				orNone(a.Old.CloseAuthority), orNone(a.New.CloseAuthority)))
+		}
+	}
+	return lines
+}
+
+// accountStateEvents 比较同一代币账户的新旧状态
+func accountStateEvents(old, new TokenAccountDetail) []string {
+	var events []string
+
+	switch {
+	case new.Delegate != "" && new.Delegate != old.Delegate:
+		events = append(events, EventDelegateApproved)
+	case new.Delegate == "" && old.Delegate != "":
+		events = append(events, EventDelegateRevoked)
+	case new.Delegate != "" && new.DelegatedAmount != old.DelegatedAmount:
+		// 同一委托的额度变化：重新授权或委托方已转走部分代币
+		events = append(events, EventDelegatedAmountChanged)
+	}
+
+	wasFrozen := old.State == AccountStateFrozen
+	isFrozen := new.State == AccountStateFrozen
+	if isFrozen && !wasFrozen {
+		events = append(events, EventFrozen)
+	} else if wasFrozen && !isFrozen {
+		events = append(events, EventThawed)
+	}
+
+	if new.CloseAuthority != old.CloseAuthority {
+		events = append(events, EventCloseAuthorityChanged)
+	}
+	return events
+}
+
+// accountStateChanges 为持仓下状态发生变化的代币账户生成 account_state 变化。
+// 新出现的账户与空状态比较；旧快照没有账户明细（升级前的数据）时跳过，避免误报
+func accountStateChanges(walletAddr, mint string, oldInfo, newInfo TokenAccountInfo) []Change {
+	if len(oldInfo.Accounts) == 0 {
+		return nil
+	}
+
+	previous := make(map[string]TokenAccountDetail, len(oldInfo.Accounts))
+	for _, account := range oldInfo.Accounts {
+		previous[account.Address] = account
+	}
+
+	var changes []Change
+	for _, account := range newInfo.Accounts {
+		old := previous[account.Address]
+		events := accountStateEvents(old, account)
+		if len(events) == 0 {
+			continue
+		}
+		changes = append(changes, Change{
+			WalletAddress: walletAddr,
+			TokenMint:     mint,
+			TokenSymbol:   newInfo.Symbol,
+			TokenName:     newInfo.Name,
+			TokenImage:    newInfo.ImageURI,
+			TokenDecimals: newInfo.Decimals,
+			TokenProgram:  newInfo.Program,
+			TokenFlags:    newInfo.Flags(),
+			USDPrice:      newInfo.USDPrice,
+			SupplyShare:   newInfo.SupplyShare,
+			ChangeType:    "account_state",
+			OldBalance:    old.Balance,
+			NewBalance:    account.Balance,
+			AccountState: &AccountStateChange{
+				Address: account.Address,
+				Events:  events,
+				Old:     old,
+				New:     account,
+			},
+		})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].AccountState.Address < changes[j].AccountState.Address
+	})
+	return changes
+}
+
+func orNone(address string) string {
+	if address == "" {
+		return "none"
+	}
+	return address
+}
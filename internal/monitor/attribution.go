@@ -0,0 +1,157 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/accursedgalaxy/insider-monitor/internal/attribution"
+	"github.com/accursedgalaxy/insider-monitor/internal/utils"
+	"github.com/gagliardetto/solana-go"
+)
+
+// AttributeChanges 为检测到的变化查找导致变化的交易。
+// oldData 为上一次的钱包数据，其扫描时间作为回溯起点；查找失败只记录警告，不影响告警
+func (w *WalletMonitor) AttributeChanges(ctx context.Context, changes []Change, oldData map[string]*WalletData) {
+	byWallet := make(map[string][]int)
+	for i, change := range changes {
+		// 委托、冻结与铸币权限变化不改变余额，无法按余额变化归因
+		if change.TokenMint == "" || change.AccountState != nil || change.ChangeType == "authority_change" {
+			continue
+		}
+		byWallet[change.WalletAddress] = append(byWallet[change.WalletAddress], i)
+	}
+
+	for walletAddr, indexes := range byWallet {
+		wallet, err := solana.PublicKeyFromBase58(walletAddr)
+		if err != nil {
+			continue
+		}
+
+		// 转入代币的交易未必引用钱包地址，因此同时查询对应的代币账户
+		addresses := []solana.PublicKey{wallet}
+		seen := map[solana.PublicKey]bool{wallet: true}
+		add := func(address solana.PublicKey) {
+			if !seen[address] {
+				seen[address] = true
+				addresses = append(addresses, address)
+			}
+		}
+		for _, i := range indexes {
+			if address, ok := tokenAccountAddress(wallet, changes[i]); ok {
+				add(address)
+			}
+			// 非关联账户（辅助账户）的变动同样需要查询
+			for _, moved := range changes[i].AccountChanges {
+				if address, err := solana.PublicKeyFromBase58(moved.Address); err == nil {
+					add(address)
+				}
+			}
+		}
+
+		var since time.Time
+		if previous, exists := oldData[walletAddr]; exists && previous != nil {
+			since = previous.LastScanned
+		}
+
+		transactions, err := w.attributor.Recent(ctx, addresses, since)
+		if err != nil {
+			log.Printf("⚠️  Warning: failed to attribute changes for wallet %s: %v", walletAddr, err)
+			continue
+		}
+
+		for _, i := range indexes {
+			changes[i].Transactions = attribution.Attribute(transactions, walletAddr, changes[i].TokenMint)
+			changes[i].classify()
+		}
+	}
+}
+
+// tokenAccountAddress 返回变化所涉及代币的关联代币账户地址，原生 SOL 无需额外查询
+func tokenAccountAddress(wallet solana.PublicKey, change Change) (solana.PublicKey, bool) {
+	program := solana.TokenProgramID
+	switch change.TokenProgram {
+	case ProgramNative:
+		return solana.PublicKey{}, false
+	case ProgramToken2022:
+		program = solana.Token2022ProgramID
+	}
+
+	mint, err := solana.PublicKeyFromBase58(change.TokenMint)
+	if err != nil {
+		return solana.PublicKey{}, false
+	}
+	address, err := attribution.TokenAccountAddress(wallet, mint, program)
+	if err != nil {
+		return solana.PublicKey{}, false
+	}
+	return address, true
+}
+
+// classify 以余额变化最大的归因交易作为变化类型，并汇总同类交易的报价金额
+func (c *Change) classify() {
+	var primary *attribution.Attribution
+	for i := range c.Transactions {
+		tx := &c.Transactions[i]
+		if tx.Kind == "" {
+			continue
+		}
+		if primary == nil || absInt(tx.Delta) > absInt(primary.Delta) {
+			primary = tx
+		}
+	}
+	if primary == nil {
+		return
+	}
+
+	c.Kind = primary.Kind
+	c.Venue = primary.Venue
+	c.QuoteMint = primary.QuoteMint
+	c.QuoteDecimals = primary.QuoteDecimals
+	c.QuoteAmount = 0
+	for _, tx := range c.Transactions {
+		if tx.Kind == primary.Kind && tx.QuoteMint == primary.QuoteMint {
+			c.QuoteAmount += tx.QuoteAmount
+		}
+	}
+}
+
+// Activity 返回变化类型的简短描述，例如 "buy on Jupiter for 1.50 SOL"
+func (c Change) Activity() string {
+	if c.Kind == "" {
+		return ""
+	}
+
+	activity := strings.ReplaceAll(c.Kind, "_", " ")
+	if c.Venue != "" {
+		activity += " on " + c.Venue
+	}
+	if c.QuoteMint != "" && c.QuoteAmount > 0 {
+		preposition := "for"
+		if c.Kind == attribution.KindSell {
+			preposition = "receiving"
+		}
+		activity += fmt.Sprintf(" %s %s %s", preposition, utils.FormatTokenAmount(c.QuoteAmount, c.QuoteDecimals), c.QuoteSymbol())
+	}
+	return activity
+}
+
+// QuoteSymbol 返回报价资产的符号，未知代币使用缩写地址
+func (c Change) QuoteSymbol() string {
+	if symbol, found := getKnownTokenName(c.QuoteMint); found {
+		return symbol
+	}
+	if len(c.QuoteMint) > 8 {
+		return c.QuoteMint[:8] + "..."
+	}
+	return c.QuoteMint
+}
+
+func absInt(x int64) int64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,35 @@
+package monitor
+
+import "github.com/accursedgalaxy/insider-monitor/internal/authority"
+
+// authorityChange 比较持仓铸币的铸币与冻结权限，发生变化时返回 authority_change 变化。
+// 任一快照缺少权限信息（升级前的数据或原生 SOL）时不比较
+func authorityChange(walletAddr, mint string, oldInfo, newInfo TokenAccountInfo) (Change, bool) {
+	if oldInfo.Authorities == nil || newInfo.Authorities == nil {
+		return Change{}, false
+	}
+
+	lines := authority.Diff(*oldInfo.Authorities, *newInfo.Authorities)
+	if len(lines) == 0 {
+		return Change{}, false
+	}
+
+	return Change{
+		WalletAddress:       walletAddr,
+		TokenMint:           mint,
+		TokenSymbol:         newInfo.Symbol,
+		TokenName:           newInfo.Name,
+		TokenImage:          newInfo.ImageURI,
+		TokenDecimals:       newInfo.Decimals,
+		TokenProgram:        newInfo.Program,
+		TokenFlags:          newInfo.Flags(),
+		USDPrice:            newInfo.USDPrice,
+		SupplyShare:         newInfo.SupplyShare,
+		ChangeType:          "authority_change",
+		OldBalance:          oldInfo.Balance,
+		NewBalance:          newInfo.Balance,
+		Authorities:         newInfo.Authorities,
+		AuthorityChanges:    lines,
+		PreviousAuthorities: oldInfo.Authorities,
+	}, true
+}
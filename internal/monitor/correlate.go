@@ -0,0 +1,87 @@
+package monitor
+
+import (
+	"time"
+
+	"github.com/accursedgalaxy/insider-monitor/internal/correlate"
+)
+
+// 跨钱包关联的变化类型
+const (
+	ChangeCoordinatedBuy  = "coordinated_buy"
+	ChangeCoordinatedSell = "coordinated_sell"
+)
+
+// CorrelateChanges 将新代币、余额变化与清仓交给关联器，返回本次新形成的 coordinated_buy 与
+// coordinated_sell 变化。变化时间取自归因交易的区块时间，未归因时为 now。
+// 原生 SOL 的变化多为手续费，不参与关联
+func CorrelateChanges(correlator *correlate.Correlator, changes []Change, now time.Time) []Change {
+	var moves []correlate.Move
+	latest := make(map[string]Change) // 铸币 -> 最近一次变化，用于补全代币信息
+	for _, change := range changes {
+		switch change.ChangeType {
+		case "new_token", "balance_change", "token_exit":
+		default:
+			continue
+		}
+		if change.TokenProgram == ProgramNative {
+			continue
+		}
+		moves = append(moves, correlate.Move{
+			Wallet:     change.WalletAddress,
+			Mint:       change.TokenMint,
+			OldBalance: change.OldBalance,
+			NewBalance: change.NewBalance,
+			Decimals:   change.TokenDecimals,
+			USDPrice:   change.USDPrice,
+			Time:       changeTime(change, now),
+		})
+		latest[change.TokenMint] = change
+	}
+	if len(moves) == 0 {
+		return nil
+	}
+
+	var clusters []Change
+	for _, cluster := range correlator.Observe(moves, now) {
+		info := latest[cluster.Mint]
+		changeType := ChangeCoordinatedBuy
+		if cluster.Side == correlate.SideSell {
+			changeType = ChangeCoordinatedSell
+		}
+
+		clusterChange := Change{
+			TokenMint:     cluster.Mint,
+			TokenSymbol:   info.TokenSymbol,
+			TokenName:     info.TokenName,
+			TokenImage:    info.TokenImage,
+			TokenDecimals: info.TokenDecimals,
+			TokenProgram:  info.TokenProgram,
+			TokenFlags:    info.TokenFlags,
+			USDPrice:      info.USDPrice,
+			ChangeType:    changeType,
+			Window:        cluster.Window,
+			WindowStart:   cluster.Participants[0].Time,
+			Participants:  cluster.Participants,
+			Commitment:    info.Commitment,
+		}
+		// 新旧余额为参与钱包的合计，使规则中的数量与美元条件作用于整个集群
+		for _, participant := range cluster.Participants {
+			clusterChange.OldBalance += participant.OldBalance
+			clusterChange.NewBalance += participant.NewBalance
+		}
+		clusterChange.ChangePercent = calculatePercentageChange(clusterChange.OldBalance, clusterChange.NewBalance)
+		// 取本次变化中最新的 slot，任一变化未最终确认时集群同样注明
+		for _, change := range changes {
+			if change.TokenMint != cluster.Mint {
+				continue
+			}
+			if change.Slot > clusterChange.Slot {
+				clusterChange.Slot = change.Slot
+			}
+			clusterChange.Unfinalized = clusterChange.Unfinalized || change.Unfinalized
+		}
+		clusters = append(clusters, clusterChange)
+	}
+	return clusters
+}
@@ -0,0 +1,386 @@
+package monitor
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/accursedgalaxy/insider-monitor/internal/authority"
+	"github.com/accursedgalaxy/insider-monitor/internal/token2022"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// 变化的最终确认模式，对应 alerts.finality
+const (
+	FinalityWait    = "wait"    // 变化达到 finalized 后才告警
+	FinalityRetract = "retract" // 立即告警，未能最终确认时发送撤回告警
+)
+
+// ChangeRetracted 为撤回未能最终确认的变化时生成的变化类型
+const ChangeRetracted = "change_retracted"
+
+const (
+	// 变化所在 slot 已最终确认后，仍无法确认该变化时最多等待的时间
+	finalityTimeout = 2 * time.Minute
+	// 撤回变化后，在该时间内忽略回滚本身造成的反向变化
+	revertWindow = 10 * time.Minute
+	// getSignatureStatuses 单次最多查询的签名数
+	maxSignatureStatuses = 256
+)
+
+// PendingChange 为在未最终确认的数据中检测到、等待最终确认的变化
+type PendingChange struct {
+	Change
+	ObservedAt time.Time
+	id         uint64
+}
+
+type revertedChange struct {
+	change Change
+	at     time.Time
+}
+
+// FinalityTracker 暂存在 confirmed 或 processed 数据中检测到的变化，
+// 在其最终确认或被回滚后给出需要告警的变化。可在多个 goroutine 中使用
+type FinalityTracker struct {
+	mode     string
+	pending  []PendingChange
+	reverted map[string]revertedChange // 钱包/铸币 -> 最近被撤回的变化
+	nextID   uint64
+	mutex    sync.Mutex
+}
+
+// NewFinalityTracker 创建跟踪器，mode 为空时所有变化立即告警
+func NewFinalityTracker(mode string) *FinalityTracker {
+	return &FinalityTracker{mode: mode, reverted: make(map[string]revertedChange)}
+}
+
+// Pending 返回等待最终确认的变化数
+func (t *FinalityTracker) Pending() int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return len(t.pending)
+}
+
+// Hold 返回可以立即告警的变化。wait 模式下未最终确认的变化被暂存；
+// retract 模式下立即返回并标记为未最终确认。已撤回变化的反向变化（即观察到回滚本身）被忽略
+func (t *FinalityTracker) Hold(changes []Change, now time.Time) []Change {
+	if t.mode == "" {
+		return changes
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for key, reverted := range t.reverted {
+		if now.Sub(reverted.at) > revertWindow {
+			delete(t.reverted, key)
+		}
+	}
+
+	var ready []Change
+	for _, change := range changes {
+		key := change.WalletAddress + "/" + change.TokenMint
+		if reverted, exists := t.reverted[key]; exists && reverses(change, reverted.change) {
+			delete(t.reverted, key)
+			log.Printf("↩️  Ignoring %s of %s in wallet %s: it undoes a change that never finalized",
+				change.ChangeType, change.TokenMint, change.WalletAddress)
+			continue
+		}
+
+		if !needsFinality(change) {
+			ready = append(ready, change)
+			continue
+		}
+		if t.mode == FinalityRetract {
+			change.Unfinalized = true
+			ready = append(ready, change)
+		}
+		t.nextID++
+		t.pending = append(t.pending, PendingChange{Change: change, ObservedAt: now, id: t.nextID})
+	}
+	return ready
+}
+
+// needsFinality 判断变化是否来自未最终确认的数据且需要确认。
+// 新钱包基线、扫描失败与主要持有者变化不涉及单个钱包的链上状态回滚
+func needsFinality(change Change) bool {
+	if change.Commitment == "" || change.Commitment == string(rpc.CommitmentFinalized) {
+		return false
+	}
+	switch change.ChangeType {
+	case "new_token", "balance_change", "token_exit", "account_state", "authority_change":
+		return true
+	}
+	return false
+}
+
+// reverses 判断 change 是否恰好撤销了 original
+func reverses(change, original Change) bool {
+	switch {
+	case original.ChangeType == "new_token":
+		return change.ChangeType == "token_exit"
+	case original.ChangeType == "token_exit":
+		return change.ChangeType == "new_token"
+	case original.ChangeType == "balance_change" && change.ChangeType == "balance_change":
+		return change.NewBalance == original.OldBalance
+	case original.ChangeType == "authority_change" && change.ChangeType == "authority_change":
+		return change.Authorities != nil && original.PreviousAuthorities != nil &&
+			sameAuthorities(*change.Authorities, *original.PreviousAuthorities)
+	case original.ChangeType == "account_state" && change.ChangeType == "account_state":
+		return change.AccountState != nil && original.AccountState != nil &&
+			change.AccountState.Address == original.AccountState.Address &&
+			sameAccountState(change.AccountState.New, original.AccountState.Old)
+	}
+	return false
+}
+
+// VerifyFinality 检查暂存的变化是否已最终确认，返回需要告警的变化：
+// wait 模式下为已最终确认的变化，retract 模式下为撤回告警。
+// 有归因交易的变化按交易的确认状态判断，其余变化与以 finalized 重新扫描的钱包数据比较
+func (w *WalletMonitor) VerifyFinality(ctx context.Context, tracker *FinalityTracker, now time.Time) []Change {
+	tracker.mutex.Lock()
+	pending := append([]PendingChange(nil), tracker.pending...)
+	tracker.mutex.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	finalizedSlot, err := w.client.GetSlot(ctx, rpc.CommitmentFinalized)
+	if err != nil {
+		log.Printf("⚠️  Warning: failed to get finalized slot: %v", err)
+		return nil
+	}
+
+	// 变化所在 slot 尚未最终确认的无需检查
+	var ready []PendingChange
+	for _, change := range pending {
+		if change.Slot <= finalizedSlot {
+			ready = append(ready, change)
+		}
+	}
+	if len(ready) == 0 {
+		return nil
+	}
+
+	statuses, err := w.signatureStatuses(ctx, ready)
+	if err != nil {
+		log.Printf("⚠️  Warning: failed to get signature statuses: %v", err)
+		return nil
+	}
+
+	finalizedData := make(map[string]*WalletData)
+	decided := make(map[uint64]bool)
+	var finalized, rolledBack []PendingChange
+	for _, change := range ready {
+		confirmed, known := finalizedBySignatures(change, statuses)
+		if len(change.Transactions) == 0 {
+			data, exists := finalizedData[change.WalletAddress]
+			if !exists {
+				data = w.scanFinalized(ctx, change.WalletAddress)
+				finalizedData[change.WalletAddress] = data
+			}
+			if data != nil && w.loadFinalizedAuthorities(ctx, change.Change, data) && data.Slot >= change.Slot {
+				confirmed, known = finalizedIn(change.Change, data), true
+			}
+		}
+
+		switch {
+		case known && confirmed:
+			finalized = append(finalized, change)
+		case known || now.Sub(change.ObservedAt) > finalityTimeout:
+			rolledBack = append(rolledBack, change)
+		default:
+			continue
+		}
+		decided[change.id] = true
+	}
+
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	remaining := tracker.pending[:0]
+	for _, change := range tracker.pending {
+		if !decided[change.id] {
+			remaining = append(remaining, change)
+		}
+	}
+	tracker.pending = remaining
+
+	var alerts []Change
+	for _, change := range finalized {
+		if tracker.mode == FinalityWait {
+			alerts = append(alerts, change.Change)
+		}
+	}
+	for _, change := range rolledBack {
+		tracker.reverted[change.WalletAddress+"/"+change.TokenMint] = revertedChange{change: change.Change, at: now}
+		if tracker.mode == FinalityRetract {
+			retraction := change.Change
+			retraction.RetractedType = change.ChangeType
+			retraction.ChangeType = ChangeRetracted
+			retraction.Unfinalized = false
+			alerts = append(alerts, retraction)
+		} else {
+			log.Printf("↩️  Dropping %s of %s in wallet %s: not finalized (seen at %s slot %d)",
+				change.ChangeType, change.TokenMint, change.WalletAddress, change.Commitment, change.Slot)
+		}
+	}
+	return alerts
+}
+
+// signatureStatuses 查询暂存变化中归因交易的确认状态
+func (w *WalletMonitor) signatureStatuses(ctx context.Context, changes []PendingChange) (map[string]*rpc.SignatureStatusesResult, error) {
+	seen := make(map[string]bool)
+	var signatures []solana.Signature
+	for _, change := range changes {
+		for _, tx := range change.Transactions {
+			if seen[tx.Signature] {
+				continue
+			}
+			seen[tx.Signature] = true
+			if signature, err := solana.SignatureFromBase58(tx.Signature); err == nil {
+				signatures = append(signatures, signature)
+			}
+		}
+	}
+
+	statuses := make(map[string]*rpc.SignatureStatusesResult)
+	for start := 0; start < len(signatures); start += maxSignatureStatuses {
+		end := start + maxSignatureStatuses
+		if end > len(signatures) {
+			end = len(signatures)
+		}
+		result, err := w.client.GetSignatureStatuses(ctx, false, signatures[start:end]...)
+		if err != nil {
+			return nil, err
+		}
+		for i, status := range result.Value {
+			if i < end-start {
+				statuses[signatures[start+i].String()] = status
+			}
+		}
+	}
+	return statuses, nil
+}
+
+// finalizedBySignatures 按归因交易判断变化是否已最终确认。
+// 没有归因交易或部分交易尚未最终确认时返回 known=false
+func finalizedBySignatures(change PendingChange, statuses map[string]*rpc.SignatureStatusesResult) (confirmed, known bool) {
+	if len(change.Transactions) == 0 {
+		return false, false
+	}
+	for _, tx := range change.Transactions {
+		status := statuses[tx.Signature]
+		if status == nil {
+			return false, false
+		}
+		if status.Err != nil {
+			return false, true
+		}
+		if status.ConfirmationStatus != rpc.ConfirmationStatusFinalized {
+			return false, false
+		}
+	}
+	return true, true
+}
+
+// scanFinalized 以 finalized 承诺级别重新查询钱包余额，失败时返回 nil。
+// 仅用于确认变化，不解析铸币信息与元数据，也不记录铸币权限
+func (w *WalletMonitor) scanFinalized(ctx context.Context, walletAddr string) *WalletData {
+	wallet, err := solana.PublicKeyFromBase58(walletAddr)
+	if err != nil {
+		return nil
+	}
+	data, _, lamports, err := w.fetchBalances(ctx, wallet, rpc.CommitmentFinalized)
+	if err != nil {
+		log.Printf("⚠️  Warning: failed to scan wallet %s at finalized commitment: %v", walletAddr, err)
+		return nil
+	}
+	w.applyNativeBalance(data, lamports)
+	return data
+}
+
+// loadFinalizedAuthorities 为权限变化读取铸币在 finalized 承诺级别下的权限并填入钱包数据，
+// 不经过权限跟踪器。其他类型的变化无需读取；读取失败时返回 false，留待下一轮确认
+func (w *WalletMonitor) loadFinalizedAuthorities(ctx context.Context, change Change, data *WalletData) bool {
+	info, held := data.TokenAccounts[change.TokenMint]
+	if change.ChangeType != "authority_change" || !held || info.Authorities != nil {
+		return true
+	}
+	key, err := solana.PublicKeyFromBase58(change.TokenMint)
+	if err != nil {
+		return true
+	}
+
+	resp, err := w.client.GetMultipleAccountsWithOpts(ctx, []solana.PublicKey{key}, &rpc.GetMultipleAccountsOpts{
+		Encoding:   solana.EncodingBase64,
+		Commitment: rpc.CommitmentFinalized,
+	})
+	if err != nil || len(resp.Value) == 0 || resp.Value[0] == nil {
+		log.Printf("⚠️  Warning: failed to read finalized authorities of mint %s: %v", change.TokenMint, err)
+		return false
+	}
+	decoded, _, err := token2022.DecodeMint(resp.Value[0].Data.GetBinary())
+	if err != nil {
+		log.Printf("⚠️  Warning: failed to decode mint %s: %v", change.TokenMint, err)
+		return false
+	}
+
+	authorities := &authority.Authorities{}
+	if decoded.MintAuthority != nil {
+		authorities.MintAuthority = decoded.MintAuthority.String()
+	}
+	if decoded.FreezeAuthority != nil {
+		authorities.FreezeAuthority = decoded.FreezeAuthority.String()
+	}
+	info.Authorities = authorities
+	data.TokenAccounts[change.TokenMint] = info
+	data.observeSlot(resp.Context.Slot)
+	return true
+}
+
+// finalizedIn 判断变化是否体现在最终确认的钱包数据中
+func finalizedIn(change Change, data *WalletData) bool {
+	info, held := data.TokenAccounts[change.TokenMint]
+	switch change.ChangeType {
+	case "new_token":
+		return held
+	case "token_exit":
+		return !held
+	case "balance_change":
+		var balance uint64
+		if held {
+			balance = info.Balance
+		}
+		if change.NewBalance > change.OldBalance {
+			return balance > change.OldBalance
+		}
+		return balance < change.OldBalance
+	case "authority_change":
+		return held && info.Authorities != nil && change.Authorities != nil &&
+			sameAuthorities(*info.Authorities, *change.Authorities)
+	case "account_state":
+		if change.AccountState == nil {
+			return true
+		}
+		for _, account := range info.Accounts {
+			if account.Address == change.AccountState.Address {
+				return sameAccountState(account, change.AccountState.New)
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func sameAuthorities(a, b authority.Authorities) bool {
+	return a.MintAuthority == b.MintAuthority && a.FreezeAuthority == b.FreezeAuthority
+}
+
+func sameAccountState(a, b TokenAccountDetail) bool {
+	return a.Delegate == b.Delegate && a.DelegatedAmount == b.DelegatedAmount &&
+		a.CloseAuthority == b.CloseAuthority && a.State == b.State
+}
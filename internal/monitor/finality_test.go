@@ -0,0 +1,195 @@
+package monitor
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/accursedgalaxy/insider-monitor/internal/attribution"
+	"github.com/accursedgalaxy/insider-monitor/internal/authority"
+	"github.com/accursedgalaxy/insider-monitor/internal/metadata"
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFinalityTrackerHold(t *testing.T) {
+	changes := []Change{
+		{WalletAddress: "w", TokenMint: "a", ChangeType: "balance_change", Commitment: "confirmed", Slot: 10},
+		{WalletAddress: "w", TokenMint: "b", ChangeType: "balance_change", Commitment: "finalized", Slot: 10},
+		{WalletAddress: "w", ChangeType: "new_wallet", Commitment: "confirmed", Slot: 10},
+	}
+
+	tests := []struct {
+		name        string
+		mode        string
+		ready       []string // 立即告警的铸币或变化类型
+		unfinalized bool
+		pending     int
+	}{
+		{name: "disabled", mode: "", ready: []string{"a", "b", "new_wallet"}},
+		{name: "wait", mode: FinalityWait, ready: []string{"b", "new_wallet"}, pending: 1},
+		{name: "retract", mode: FinalityRetract, ready: []string{"a", "b", "new_wallet"}, unfinalized: true, pending: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := NewFinalityTracker(tt.mode)
+			ready := tracker.Hold(changes, time.Now())
+
+			var labels []string
+			for _, change := range ready {
+				label := change.TokenMint
+				if label == "" {
+					label = change.ChangeType
+				}
+				labels = append(labels, label)
+				assert.Equal(t, tt.unfinalized && change.TokenMint == "a", change.Unfinalized)
+			}
+			assert.Equal(t, tt.ready, labels)
+			assert.Equal(t, tt.pending, tracker.Pending())
+		})
+	}
+}
+
+func TestVerifyFinalityBySignatures(t *testing.T) {
+	f := newFakeRPC(t)
+	server := httptest.NewServer(f)
+	defer server.Close()
+	w, _ := newStreamingMonitor(t, f, server.URL)
+
+	finalizedSig := solana.Signature{1}.String()
+	confirmedSig := solana.Signature{2}.String()
+	f.signatureStatuses = map[string]string{finalizedSig: "finalized", confirmedSig: "confirmed"}
+
+	change := func(mint string, slot uint64, signature string) Change {
+		return Change{
+			WalletAddress: f.wallet.String(),
+			TokenMint:     mint,
+			ChangeType:    "balance_change",
+			OldBalance:    100,
+			NewBalance:    200,
+			Slot:          slot,
+			Commitment:    "confirmed",
+			Transactions:  []attribution.Attribution{{Signature: signature}},
+		}
+	}
+
+	now := time.Now()
+	tracker := NewFinalityTracker(FinalityWait)
+	require.Empty(t, tracker.Hold([]Change{
+		change("finalized", 100, finalizedSig),
+		change("confirmed", 100, confirmedSig),
+		change("future", 200, finalizedSig), // 所在 slot 尚未最终确认
+	}, now))
+
+	alerts := w.VerifyFinality(context.Background(), tracker, now)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, "finalized", alerts[0].TokenMint)
+	assert.Equal(t, 2, tracker.Pending())
+
+	// 超时仍未最终确认的变化在 wait 模式下被丢弃
+	alerts = w.VerifyFinality(context.Background(), tracker, now.Add(finalityTimeout+time.Second))
+	assert.Empty(t, alerts)
+	assert.Equal(t, 1, tracker.Pending())
+}
+
+func TestVerifyFinalityByState(t *testing.T) {
+	f := newFakeRPC(t)
+	server := httptest.NewServer(f)
+	defer server.Close()
+	w, _ := newStreamingMonitor(t, f, server.URL)
+
+	newToken := Change{
+		WalletAddress: f.wallet.String(),
+		TokenMint:     f.mint.String(),
+		ChangeType:    "new_token",
+		NewBalance:    1000,
+		Slot:          100,
+		Commitment:    "confirmed",
+	}
+
+	// 最终确认的数据中同样持有该代币
+	tracker := NewFinalityTracker(FinalityRetract)
+	ready := tracker.Hold([]Change{newToken}, time.Now())
+	require.Len(t, ready, 1)
+	assert.True(t, ready[0].Unfinalized)
+	assert.Empty(t, w.VerifyFinality(context.Background(), tracker, time.Now()))
+	assert.Zero(t, tracker.Pending())
+
+	// 买入被回滚：撤回告警，随后观察到的清仓被忽略
+	zero := uint64(0)
+	f.finalizedAmount = &zero
+	tracker.Hold([]Change{newToken}, time.Now())
+	alerts := w.VerifyFinality(context.Background(), tracker, time.Now())
+	require.Len(t, alerts, 1)
+	assert.Equal(t, ChangeRetracted, alerts[0].ChangeType)
+	assert.Equal(t, "new_token", alerts[0].RetractedType)
+	assert.False(t, alerts[0].Unfinalized)
+
+	exit := newToken
+	exit.ChangeType = "token_exit"
+	assert.Empty(t, tracker.Hold([]Change{exit}, time.Now()))
+	assert.Zero(t, tracker.Pending())
+}
+
+func TestScanWalletRecordsSlot(t *testing.T) {
+	f := newFakeRPC(t)
+	server := httptest.NewServer(f)
+	defer server.Close()
+	w, _ := newStreamingMonitor(t, f, server.URL)
+
+	data, _, err := w.scanWallet(context.Background(), f.wallet)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(100), data.Slot)
+	assert.Equal(t, "confirmed", data.Commitment)
+
+	changes := DetectChanges(
+		map[string]*WalletData{f.wallet.String(): {WalletAddress: f.wallet.String(), TokenAccounts: map[string]TokenAccountInfo{}}},
+		map[string]*WalletData{f.wallet.String(): data},
+		0.2,
+	)
+	require.NotEmpty(t, changes)
+	assert.Equal(t, uint64(100), changes[0].Slot)
+	assert.Equal(t, "confirmed", changes[0].Commitment)
+}
+
+func TestVerifyFinalityScansBalancesOnly(t *testing.T) {
+	f := newFakeRPC(t)
+	f.mintAuthority = solana.NewWallet().PublicKey()
+	server := httptest.NewServer(f)
+	defer server.Close()
+	w, _ := newStreamingMonitor(t, f, server.URL)
+	authorityDir, metadataDir := t.TempDir(), t.TempDir()
+	w.authorities = authority.NewTracker(authorityDir)
+	w.metadata = metadata.NewResolver(w.client, metadataDir, false)
+
+	balanceChange := Change{
+		WalletAddress: f.wallet.String(),
+		TokenMint:     f.mint.String(),
+		ChangeType:    "balance_change",
+		OldBalance:    500,
+		NewBalance:    1000,
+		Slot:          100,
+		Commitment:    "confirmed",
+	}
+	authorityChange := balanceChange
+	authorityChange.ChangeType = "authority_change"
+	authorityChange.Authorities = &authority.Authorities{MintAuthority: f.mintAuthority.String()}
+
+	tracker := NewFinalityTracker(FinalityWait)
+	assert.Empty(t, tracker.Hold([]Change{balanceChange, authorityChange}, time.Now()))
+	alerts := w.VerifyFinality(context.Background(), tracker, time.Now())
+	require.Len(t, alerts, 2)
+	assert.Equal(t, "balance_change", alerts[0].ChangeType)
+	assert.Equal(t, "authority_change", alerts[1].ChangeType)
+
+	// 确认过程不记录铸币权限，也不解析元数据
+	for _, dir := range []string{authorityDir, metadataDir} {
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	}
+}
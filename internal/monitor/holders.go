@@ -0,0 +1,81 @@
+package monitor
+
+import (
+	"context"
+	"log"
+
+	"github.com/accursedgalaxy/insider-monitor/internal/config"
+	"github.com/accursedgalaxy/insider-monitor/internal/holders"
+)
+
+// 未配置时主要持有者余额下降达到 20% 视为卖出
+const defaultHolderSellThreshold = 0.2
+
+// ScanMints 获取各铸币当前的主要持有者，并与上一次快照比较生成告警变化。
+// 首次快照仅作为基线不产生变化；获取失败的铸币保留上一次的快照
+func (w *WalletMonitor) ScanMints(ctx context.Context, mints []string, previous map[string]*holders.Snapshot, cfg config.HolderConfig) (map[string]*holders.Snapshot, []Change) {
+	sellThreshold := cfg.SellThreshold
+	if sellThreshold <= 0 {
+		sellThreshold = defaultHolderSellThreshold
+	}
+
+	snapshots := make(map[string]*holders.Snapshot, len(mints))
+	var changes []Change
+	for _, mintAddr := range mints {
+		old := previous[mintAddr]
+		snapshot, err := w.holders.Snapshot(ctx, mintAddr, old, cfg.TopN)
+		if err != nil {
+			log.Printf("❌ Error fetching top holders for mint %s: %v", mintAddr, err)
+			if old != nil {
+				snapshots[mintAddr] = old
+			}
+			continue
+		}
+		snapshots[mintAddr] = snapshot
+		log.Printf("✅ Mint %s: tracking %d top holders, top-%d concentration %.2f%%",
+			mintAddr, len(snapshot.Holders), holders.ConcentrationHolders, snapshot.Concentration)
+
+		events := holders.Compare(old, snapshot, sellThreshold, cfg.ConcentrationThreshold)
+		if len(events) == 0 {
+			continue
+		}
+
+		var symbol, name, image string
+		if tokenMetadata, exists := w.metadata.Get(mintAddr); exists {
+			symbol, name, image = tokenMetadata.Symbol, tokenMetadata.Name, tokenMetadata.Image
+		} else if resolved, err := w.metadata.Resolve(ctx, []string{mintAddr}, nil); err == nil {
+			symbol, name, image = resolved[mintAddr].Symbol, resolved[mintAddr].Name, resolved[mintAddr].Image
+		}
+		for _, event := range events {
+			change := holderChange(event, snapshot)
+			change.TokenSymbol = symbol
+			change.TokenName = name
+			change.TokenImage = image
+			changes = append(changes, change)
+		}
+	}
+	return snapshots, changes
+}
+
+// holderChange 将主要持有者事件转换为告警变化，附带当前的集中度；集中度事件的钱包地址为空
+func holderChange(event holders.Event, snapshot *holders.Snapshot) Change {
+	change := Change{
+		WalletAddress:         event.Owner,
+		TokenMint:             event.Mint,
+		TokenDecimals:         snapshot.Decimals,
+		ChangeType:            event.Type,
+		OldBalance:            event.OldBalance,
+		NewBalance:            event.NewBalance,
+		HolderRank:            event.Rank,
+		PreviousRank:          event.PreviousRank,
+		Concentration:         snapshot.Concentration,
+		PreviousConcentration: event.OldConcentration,
+	}
+	if event.Type != holders.EventConcentration {
+		change.ChangePercent = calculatePercentageChange(event.OldBalance, event.NewBalance)
+		if snapshot.Supply > 0 {
+			change.SupplyShare = float64(event.NewBalance) / float64(snapshot.Supply) * 100.0
+		}
+	}
+	return change
+}
@@ -2,16 +2,29 @@ package monitor
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"math"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/accursedgalaxy/insider-monitor/internal/attribution"
+	"github.com/accursedgalaxy/insider-monitor/internal/authority"
+	"github.com/accursedgalaxy/insider-monitor/internal/backfill"
 	"github.com/accursedgalaxy/insider-monitor/internal/config"
+	"github.com/accursedgalaxy/insider-monitor/internal/correlate"
+	"github.com/accursedgalaxy/insider-monitor/internal/holders"
+	"github.com/accursedgalaxy/insider-monitor/internal/metadata"
+	"github.com/accursedgalaxy/insider-monitor/internal/mint"
 	"github.com/accursedgalaxy/insider-monitor/internal/price"
-	bin "github.com/gagliardetto/binary"
+	"github.com/accursedgalaxy/insider-monitor/internal/rpcpool"
+	"github.com/accursedgalaxy/insider-monitor/internal/score"
+	"github.com/accursedgalaxy/insider-monitor/internal/token2022"
+	"github.com/accursedgalaxy/insider-monitor/internal/utils"
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/programs/token"
 	"github.com/gagliardetto/solana-go/rpc"
@@ -19,19 +32,76 @@ import (
 
 type WalletMonitor struct {
 	client       *rpc.Client
+	pool         *rpcpool.Pool
 	wallets      []solana.PublicKey
 	networkURL   string
 	isConnected  bool
 	scanConfig   *config.ScanConfig
 	priceService *price.JupiterPrice
+	mintResolver *mint.Resolver
+	metadata     *metadata.Resolver
+	attributor   *attribution.Resolver
+	holders      *holders.Tracker
+	authorities  *authority.Tracker
+	scorer       *score.Scorer
+	workers      int
+	commitment   rpc.CommitmentType // 扫描与订阅使用的承诺级别
+
+	decimals      map[string]uint8 // 已解析过的铸币小数位，铸币信息暂时无法解析时沿用
+	decimalsMutex sync.Mutex
 }
 
+// 本地数据目录，用于存放元数据等缓存
+const defaultDataDir = "./data"
+
+// 以下常量为未配置时的并发扫描默认值
+const (
+	defaultWorkers           = 4
+	defaultRequestsPerSecond = 4
+)
+
 func NewWalletMonitor(networkURL string, wallets []string, scanConfig *config.ScanConfig) (*WalletMonitor, error) {
-	client := rpc.NewWithCustomRPCClient(rpc.NewWithLimiter(
-		networkURL,
-		4,
-		1,
-	))
+	return NewWalletMonitorWithEndpoints([]config.RPCEndpointConfig{{URL: networkURL}}, wallets, scanConfig)
+}
+
+// NewWalletMonitorWithEndpoints 创建使用多个 RPC 端点的监控器，请求按权重分发并在端点故障时自动切换
+func NewWalletMonitorWithEndpoints(endpoints []config.RPCEndpointConfig, wallets []string, scanConfig *config.ScanConfig) (*WalletMonitor, error) {
+	workers := defaultWorkers
+	requestsPerSecond := float64(defaultRequestsPerSecond)
+	if scanConfig != nil {
+		if scanConfig.Workers > 0 {
+			workers = scanConfig.Workers
+		}
+		if scanConfig.RequestsPerSecond > 0 {
+			requestsPerSecond = scanConfig.RequestsPerSecond
+		}
+	}
+
+	// 每个端点独立限速，未单独配置时使用扫描的速率上限，突发量与工作协程数一致
+	poolEndpoints := make([]rpcpool.Endpoint, len(endpoints))
+	for i, endpoint := range endpoints {
+		poolEndpoints[i] = rpcpool.Endpoint{
+			URL:               endpoint.URL,
+			Weight:            endpoint.Weight,
+			RequestsPerSecond: endpoint.RequestsPerSecond,
+			Burst:             workers,
+		}
+		if poolEndpoints[i].RequestsPerSecond <= 0 {
+			poolEndpoints[i].RequestsPerSecond = requestsPerSecond
+		}
+	}
+	pool, err := rpcpool.New(poolEndpoints)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create RPC pool: %w", err)
+	}
+	client := rpc.NewWithCustomRPCClient(pool)
+
+	fetchOffChain := scanConfig != nil && scanConfig.FetchOffChainMetadata
+
+	commitment := rpc.CommitmentConfirmed
+	if scanConfig != nil && scanConfig.Commitment != "" {
+		commitment = rpc.CommitmentType(scanConfig.Commitment)
+	}
 
 	// 将钱包地址转换为 PublicKey
 	pubKeys := make([]solana.PublicKey, len(wallets))
@@ -43,31 +113,127 @@ func NewWalletMonitor(networkURL string, wallets []string, scanConfig *config.Sc
 		pubKeys[i] = pubKey
 	}
 
+	mintResolver := mint.NewResolver(client)
 	return &WalletMonitor{
 		client:       client,
+		pool:         pool,
 		wallets:      pubKeys,
-		networkURL:   networkURL,
+		networkURL:   endpoints[0].URL,
 		scanConfig:   scanConfig,
 		priceService: price.NewJupiterPrice(),
+		mintResolver: mintResolver,
+		metadata:     metadata.NewResolver(client, defaultDataDir, fetchOffChain),
+		attributor:   attribution.NewResolver(client),
+		holders:      holders.NewTracker(client, mintResolver),
+		authorities:  authority.NewTracker(defaultDataDir),
+		scorer:       score.NewScorer(score.NewRPCLaunchResolver(client), defaultDataDir),
+		workers:      workers,
+		commitment:   commitment,
 	}, nil
 }
 
+// Backfill 回填全部受监控钱包的历史交易，结果保存在数据目录的 history 子目录中
+func (w *WalletMonitor) Backfill(ctx context.Context, options backfill.Options) ([]backfill.Summary, error) {
+	wallets := make([]string, len(w.wallets))
+	for i, wallet := range w.wallets {
+		wallets[i] = wallet.String()
+	}
+	worker := backfill.NewWorker(backfill.NewRPCSource(w.client), backfill.NewStore(defaultDataDir), options)
+	return worker.Run(ctx, wallets)
+}
+
+// StartHealthChecks 在后台定期探测各 RPC 端点的健康状况，直到 ctx 被取消
+func (w *WalletMonitor) StartHealthChecks(ctx context.Context) {
+	w.pool.Start(ctx)
+}
+
+// EndpointStats 返回各 RPC 端点的运行统计
+func (w *WalletMonitor) EndpointStats() []rpcpool.EndpointStats {
+	return w.pool.Stats()
+}
+
+// 代币程序标识，用于区分持仓所属的代币程序
+const (
+	ProgramSPLToken  = "spl-token"
+	ProgramToken2022 = "token-2022"
+	ProgramNative    = "native" // 原生 SOL（含折叠进来的 wSOL）
+)
+
+// 原生 SOL 持仓以 wSOL 铸币地址为键，便于直接获取价格
+var NativeSOLMint = solana.SolMint.String()
+
+const nativeSOLDecimals = 9
+
+// tokenProgram 描述一个需要扫描的代币程序
+type tokenProgram struct {
+	id   solana.PublicKey
+	name string
+}
+
+// 扫描时依次查询的代币程序
+var tokenPrograms = []tokenProgram{
+	{id: solana.TokenProgramID, name: ProgramSPLToken},
+	{id: solana.Token2022ProgramID, name: ProgramToken2022},
+}
+
 // 简化的 TokenAccountInfo
 type TokenAccountInfo struct {
-	Balance         uint64    `json:"balance"`
-	LastUpdated     time.Time `json:"last_updated"`
-	Symbol          string    `json:"symbol"`
-	Decimals        uint8     `json:"decimals"`
-	USDPrice        float64   `json:"usd_price"`
-	USDValue        float64   `json:"usd_value"`
-	ConfidenceLevel string    `json:"confidence_level"`
+	Balance         uint64                       `json:"balance"`
+	LastUpdated     time.Time                    `json:"last_updated"`
+	Symbol          string                       `json:"symbol"`
+	Decimals        uint8                        `json:"decimals"`
+	USDPrice        float64                      `json:"usd_price"`
+	USDValue        float64                      `json:"usd_value"`
+	ConfidenceLevel string                       `json:"confidence_level"`
+	Program         string                       `json:"program"`
+	Extensions      *token2022.AccountExtensions `json:"extensions,omitempty"`      // 各代币账户扩展的合并结果
+	MintExtensions  *token2022.MintExtensions    `json:"mint_extensions,omitempty"` // 铸币的扩展配置（转账手续费、转账钩子等）
+	Name            string                       `json:"name,omitempty"`
+	ImageURI        string                       `json:"image_uri,omitempty"`
+	Supply          uint64                       `json:"supply,omitempty"`
+	SupplyShare     float64                      `json:"supply_share,omitempty"` // 占总供应量的百分比
+	Accounts        []TokenAccountDetail         `json:"accounts,omitempty"`     // 持有该铸币的各个代币账户，Balance 为其合计
+	Authorities     *authority.Authorities       `json:"authorities,omitempty"`  // 铸币与冻结权限，升级前的数据与原生 SOL 为空
+	Unresolved      bool                         `json:"unresolved,omitempty"`   // 小数位未知，不计算价值，也不比较余额变化
+}
+
+// Flags 返回持仓的扩展标记：先列出代币账户的标记，再列出铸币扩展配置的标记
+func (i TokenAccountInfo) Flags() []string {
+	flags := i.Extensions.Flags()
+	for _, flag := range i.MintExtensions.Flags() {
+		if !slices.Contains(flags, flag) {
+			flags = append(flags, flag)
+		}
+	}
+	return flags
+}
+
+// TokenAccountDetail 描述持有某铸币的单个代币账户
+type TokenAccountDetail struct {
+	Address         string `json:"address"`
+	Balance         uint64 `json:"balance"`
+	Program         string `json:"program"`
+	State           string `json:"state"`
+	Delegate        string `json:"delegate,omitempty"`
+	DelegatedAmount uint64 `json:"delegated_amount,omitempty"`
+	CloseAuthority  string `json:"close_authority,omitempty"`
+}
+
+// UIAmount 返回按小数位换算后的实际持仓数量
+func (t TokenAccountInfo) UIAmount() float64 {
+	return float64(t.Balance) / math.Pow(10, float64(t.Decimals))
 }
 
 // 简化的 WalletData
 type WalletData struct {
-	WalletAddress string                      `json:"wallet_address"`
-	TokenAccounts map[string]TokenAccountInfo `json:"token_accounts"` // mint -> 信息
-	LastScanned   time.Time                   `json:"last_scanned"`
+	WalletAddress     string                      `json:"wallet_address"`
+	TokenAccounts     map[string]TokenAccountInfo `json:"token_accounts"`                // mint -> 信息
+	SOLBalance        uint64                      `json:"sol_balance"`                   // 原生 lamports 余额
+	WrappedSOLBalance uint64                      `json:"wrapped_sol_balance,omitempty"` // wSOL 代币账户余额
+	LastScanned       time.Time                   `json:"last_scanned"`
+	// 扫描时各 RPC 响应中最低的上下文 slot，快照至少反映了该 slot 的状态
+	Slot       uint64 `json:"slot,omitempty"`
+	Commitment string `json:"commitment,omitempty"` // 扫描使用的承诺级别
 }
 
 // 以下常量用于重试配置
@@ -77,27 +243,56 @@ const (
 	maxBackoff     = 30 * time.Second
 )
 
-func (w *WalletMonitor) getTokenAccountsWithRetry(wallet solana.PublicKey) (*rpc.GetTokenAccountsResult, error) {
-	var lastErr error
-	backoff := initialBackoff
-
-	for attempt := 0; attempt < maxRetries; attempt++ {
-		accounts, err := w.client.GetTokenAccountsByOwner(
-			context.Background(),
+func (w *WalletMonitor) getTokenAccountsWithRetry(ctx context.Context, wallet solana.PublicKey, programID solana.PublicKey, commitment rpc.CommitmentType) (*rpc.GetTokenAccountsResult, error) {
+	var accounts *rpc.GetTokenAccountsResult
+	err := w.callWithRetry(ctx, wallet, func() error {
+		var err error
+		accounts, err = w.client.GetTokenAccountsByOwner(
+			ctx,
 			wallet,
 			&rpc.GetTokenAccountsConfig{
-				ProgramId: solana.TokenProgramID.ToPointer(),
+				ProgramId: programID.ToPointer(),
 			},
 			&rpc.GetTokenAccountsOpts{
-				Encoding: solana.EncodingBase64,
+				Encoding:   solana.EncodingBase64,
+				Commitment: commitment,
 			},
 		)
+		return err
+	})
+	return accounts, err
+}
+
+// getBalanceWithRetry 获取钱包的原生 SOL 余额（lamports）及响应的上下文 slot
+func (w *WalletMonitor) getBalanceWithRetry(ctx context.Context, wallet solana.PublicKey, commitment rpc.CommitmentType) (uint64, uint64, error) {
+	var lamports, slot uint64
+	err := w.callWithRetry(ctx, wallet, func() error {
+		result, err := w.client.GetBalance(ctx, wallet, commitment)
+		if err != nil {
+			return err
+		}
+		lamports = result.Value
+		slot = result.Context.Slot
+		return nil
+	})
+	return lamports, slot, err
+}
+
+// callWithRetry 执行 RPC 调用，遇到速率限制时指数回退重试；ctx 取消时立即返回
+func (w *WalletMonitor) callWithRetry(ctx context.Context, wallet solana.PublicKey, call func() error) error {
+	var lastErr error
+	backoff := initialBackoff
 
+	for attempt := 0; attempt < maxRetries; attempt++ {
+		err := call()
 		if err == nil {
-			return accounts, nil
+			return nil
 		}
 
 		lastErr = err
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 		if strings.Contains(err.Error(), "429") || strings.Contains(err.Error(), "Too Many Requests") {
 			log.Printf("⚠️  Rate limited on attempt %d for wallet %s, waiting %v before retry",
 				attempt+1, wallet.String(), backoff)
@@ -111,7 +306,11 @@ func (w *WalletMonitor) getTokenAccountsWithRetry(wallet solana.PublicKey) (*rpc
 				log.Printf("   • Triton: 10M requests/month free - https://triton.one")
 			}
 
-			time.Sleep(backoff)
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
 
 			// 指数回退并设置最大值
 			backoff *= 2
@@ -123,7 +322,7 @@ func (w *WalletMonitor) getTokenAccountsWithRetry(wallet solana.PublicKey) (*rpc
 
 		// 处理其他常见错误并提供提示
 		if strings.Contains(err.Error(), "connection") || strings.Contains(err.Error(), "timeout") {
-			return nil, fmt.Errorf("connection error: %w\n\n"+
+			return fmt.Errorf("connection error: %w\n\n"+
 				"💡 This might be due to:\n"+
 				"   • Network connectivity issues\n"+
 				"   • RPC endpoint is down or overloaded\n"+
@@ -131,7 +330,7 @@ func (w *WalletMonitor) getTokenAccountsWithRetry(wallet solana.PublicKey) (*rpc
 		}
 
 		// 若不是速率限制或连接错误，则立即返回
-		return nil, fmt.Errorf("RPC request failed: %w\n\n"+
+		return fmt.Errorf("RPC request failed: %w\n\n"+
 			"💡 If this error persists, try:\n"+
 			"   • Check your RPC endpoint URL in config.json\n"+
 			"   • Verify your network connection\n"+
@@ -140,7 +339,7 @@ func (w *WalletMonitor) getTokenAccountsWithRetry(wallet solana.PublicKey) (*rpc
 
 	// 提供带有解决方案的增强错误信息
 	if strings.Contains(lastErr.Error(), "429") || strings.Contains(lastErr.Error(), "Too Many Requests") {
-		return nil, fmt.Errorf("❌ Rate limit exceeded after %d retries\n\n"+
+		return fmt.Errorf("❌ Rate limit exceeded after %d retries\n\n"+
 			"🔧 SOLUTION: You're likely using a public RPC endpoint with strict limits.\n"+
 			"   Update your config.json with a dedicated RPC endpoint:\n\n"+
 			"   {\n"+
@@ -154,7 +353,7 @@ func (w *WalletMonitor) getTokenAccountsWithRetry(wallet solana.PublicKey) (*rpc
 			"Original error: %w", maxRetries, lastErr)
 	}
 
-	return nil, fmt.Errorf("failed after %d retries: %w", maxRetries, lastErr)
+	return fmt.Errorf("failed after %d retries: %w", maxRetries, lastErr)
 }
 
 // shouldIncludeToken 根据扫描配置判断是否包含某个代币
@@ -187,57 +386,428 @@ func (w *WalletMonitor) shouldIncludeToken(mint string) bool {
 	}
 }
 
-func (w *WalletMonitor) GetWalletData(wallet solana.PublicKey) (*WalletData, error) {
+// tokenAccountRef 记录一个代币账户的地址及其解码结果
+type tokenAccountRef struct {
+	Address         string
+	Owner           string
+	Mint            string
+	Program         string
+	Amount          uint64
+	State           string
+	Delegate        string
+	DelegatedAmount uint64
+	CloseAuthority  string
+}
+
+// 代币账户状态
+const (
+	AccountStateInitialized = "initialized"
+	AccountStateFrozen      = "frozen"
+)
+
+// newTokenAccountRef 从解码后的代币账户构造引用
+func newTokenAccountRef(address, owner, program string, account *token.Account) tokenAccountRef {
+	ref := tokenAccountRef{
+		Address: address,
+		Owner:   owner,
+		Mint:    account.Mint.String(),
+		Program: program,
+	}
+	ref.update(account)
+	return ref
+}
+
+// update 使用最新的账户数据刷新余额、状态与委托信息
+func (r *tokenAccountRef) update(account *token.Account) {
+	r.Amount = account.Amount
+	r.State = AccountStateInitialized
+	if account.State == token.Frozen {
+		r.State = AccountStateFrozen
+	}
+	r.Delegate = ""
+	r.DelegatedAmount = 0
+	if account.Delegate != nil {
+		r.Delegate = account.Delegate.String()
+		r.DelegatedAmount = account.DelegatedAmount
+	}
+	r.CloseAuthority = ""
+	if account.CloseAuthority != nil {
+		r.CloseAuthority = account.CloseAuthority.String()
+	}
+}
+
+// detail 转换为持仓中保存的账户明细
+func (r tokenAccountRef) detail() TokenAccountDetail {
+	return TokenAccountDetail{
+		Address:         r.Address,
+		Balance:         r.Amount,
+		Program:         r.Program,
+		State:           r.State,
+		Delegate:        r.Delegate,
+		DelegatedAmount: r.DelegatedAmount,
+		CloseAuthority:  r.CloseAuthority,
+	}
+}
+
+func (w *WalletMonitor) GetWalletData(ctx context.Context, wallet solana.PublicKey) (*WalletData, error) {
+	walletData, _, err := w.scanWallet(ctx, wallet)
+	return walletData, err
+}
+
+// scanWallet 以配置的承诺级别获取钱包数据，并返回扫描到的全部代币账户（包括被筛选掉的）
+func (w *WalletMonitor) scanWallet(ctx context.Context, wallet solana.PublicKey) (*WalletData, []tokenAccountRef, error) {
+	return w.scanWalletAt(ctx, wallet, w.commitment)
+}
+
+// scanWalletAt 以指定的承诺级别获取钱包数据
+func (w *WalletMonitor) scanWalletAt(ctx context.Context, wallet solana.PublicKey, commitment rpc.CommitmentType) (*WalletData, []tokenAccountRef, error) {
+	walletData, refs, lamports, err := w.fetchBalances(ctx, wallet, commitment)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 解析真实的小数位与供应量
+	if err := w.applyMintInfo(ctx, walletData); err != nil {
+		return nil, nil, fmt.Errorf("failed to resolve mint info for wallet %s: %w", wallet.String(), err)
+	}
+
+	// 将原生 SOL 与 wSOL 合并为一个持仓
+	w.applyNativeBalance(walletData, lamports)
+
+	log.Printf("✅ Wallet %s: found %d token accounts (after filtering)", wallet.String(), len(walletData.TokenAccounts))
+	return walletData, refs, nil
+}
+
+// fetchBalances 以指定的承诺级别获取钱包的原生余额与代币账户，
+// 仅填充余额、账户与 slot，不解析铸币信息与元数据
+func (w *WalletMonitor) fetchBalances(ctx context.Context, wallet solana.PublicKey, commitment rpc.CommitmentType) (*WalletData, []tokenAccountRef, uint64, error) {
 	walletData := &WalletData{
 		WalletAddress: wallet.String(),
 		TokenAccounts: make(map[string]TokenAccountInfo),
 		LastScanned:   time.Now(),
+		Commitment:    string(commitment),
 	}
+	var refs []tokenAccountRef
 
-	// 使用带重试的版本
-	accounts, err := w.getTokenAccountsWithRetry(wallet)
+	// 获取原生 SOL 余额
+	lamports, slot, err := w.getBalanceWithRetry(ctx, wallet, commitment)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get token accounts for wallet %s: %w", wallet.String(), err)
+		return nil, nil, 0, fmt.Errorf("failed to get SOL balance for wallet %s: %w", wallet.String(), err)
 	}
+	walletData.observeSlot(slot)
 
-	// 处理代币账户
-	for _, acc := range accounts.Value {
-		var tokenAccount token.Account
-		err = bin.NewBinDecoder(acc.Account.Data.GetBinary()).Decode(&tokenAccount)
+	// 分别查询旧版 SPL Token 与 Token-2022 程序下的账户
+	for _, program := range tokenPrograms {
+		// 使用带重试的版本
+		accounts, err := w.getTokenAccountsWithRetry(ctx, wallet, program.id, commitment)
 		if err != nil {
-			log.Printf("⚠️  Warning: failed to decode token account (this is usually normal): %v", err)
-			continue
+			return nil, nil, 0, fmt.Errorf("failed to get %s accounts for wallet %s: %w", program.name, wallet.String(), err)
 		}
+		walletData.observeSlot(accounts.Context.Slot)
+
+		// 处理代币账户
+		for _, acc := range accounts.Value {
+			tokenAccount, extensions, err := token2022.DecodeAccount(acc.Account.Data.GetBinary())
+			if err != nil {
+				log.Printf("⚠️  Warning: failed to decode token account (this is usually normal): %v", err)
+				continue
+			}
 
-		// 仅包含余额为正且通过筛选的账户
-		if tokenAccount.Amount > 0 {
 			mint := tokenAccount.Mint.String()
-			if w.shouldIncludeToken(mint) {
-				walletData.TokenAccounts[mint] = TokenAccountInfo{
-					Balance:     tokenAccount.Amount,
-					LastUpdated: time.Now(),
-					Symbol:      mint[:8] + "...",
-					Decimals:    9,
+			ref := newTokenAccountRef(acc.Pubkey.String(), wallet.String(), program.name, tokenAccount)
+			refs = append(refs, ref)
+
+			// 仅包含余额为正且通过筛选的账户；同一铸币的多个账户累加到同一持仓下
+			if tokenAccount.Amount > 0 && w.shouldIncludeToken(mint) {
+				info, exists := walletData.TokenAccounts[mint]
+				if !exists {
+					// 小数位由 applyMintInfo 填充
+					info = TokenAccountInfo{
+						LastUpdated: time.Now(),
+						Symbol:      mint[:8] + "...",
+						Program:     program.name,
+					}
 				}
+				info.Balance += tokenAccount.Amount
+				info.Accounts = append(info.Accounts, ref.detail())
+				info.Extensions = info.Extensions.Merge(token2022.SummarizeAccountExtensions(extensions))
+				walletData.TokenAccounts[mint] = info
 			}
 		}
 	}
 
-	log.Printf("✅ Wallet %s: found %d token accounts (after filtering)", wallet.String(), len(walletData.TokenAccounts))
-	return walletData, nil
+	// 账户按地址排序，便于比较与展示
+	for _, info := range walletData.TokenAccounts {
+		sort.Slice(info.Accounts, func(i, j int) bool {
+			return info.Accounts[i].Address < info.Accounts[j].Address
+		})
+	}
+	return walletData, refs, lamports, nil
+}
+
+// observeSlot 记录 RPC 响应的上下文 slot，保留最低值
+func (d *WalletData) observeSlot(slot uint64) {
+	if slot > 0 && (d.Slot == 0 || slot < d.Slot) {
+		d.Slot = slot
+	}
+}
+
+// applyNativeBalance 将原生 lamports 与 wSOL 余额合并为 SOL 持仓
+func (w *WalletMonitor) applyNativeBalance(walletData *WalletData, lamports uint64) {
+	walletData.SOLBalance = lamports
+	wrapped, hasWrapped := walletData.TokenAccounts[NativeSOLMint]
+	if hasWrapped {
+		walletData.WrappedSOLBalance = wrapped.Balance
+	}
+
+	total := walletData.SOLBalance + walletData.WrappedSOLBalance
+	if total == 0 || !w.shouldIncludeToken(NativeSOLMint) {
+		delete(walletData.TokenAccounts, NativeSOLMint)
+		return
+	}
+
+	walletData.TokenAccounts[NativeSOLMint] = TokenAccountInfo{
+		Balance:     total,
+		LastUpdated: time.Now(),
+		Symbol:      "SOL",
+		Name:        "Solana",
+		Decimals:    nativeSOLDecimals,
+		Program:     ProgramNative,
+		Accounts:    wrapped.Accounts, // 仅包含 wSOL 代币账户，原生余额不计入
+	}
+}
+
+// applyMintInfo 使用铸币信息填充小数位、供应量与占比
+func (w *WalletMonitor) applyMintInfo(ctx context.Context, walletData *WalletData) error {
+	if len(walletData.TokenAccounts) == 0 {
+		return nil
+	}
+
+	mints := make([]string, 0, len(walletData.TokenAccounts))
+	for mint := range walletData.TokenAccounts {
+		mints = append(mints, mint)
+	}
+
+	// 个别铸币解析失败时其余持仓照常更新，仅在扫描被取消时放弃整个钱包
+	infos, err := w.mintResolver.Resolve(ctx, mints)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		log.Printf("⚠️  Warning: failed to resolve some mints of wallet %s: %v", walletData.WalletAddress, err)
+	}
+
+	now := time.Now()
+	for mint, info := range walletData.TokenAccounts {
+		mintInfo, exists := infos[mint]
+		if !exists {
+			// 铸币的小数位不会改变，沿用之前解析到的值；从未解析过时标记为未知
+			if decimals, known := w.knownDecimals(mint); known {
+				log.Printf("⚠️  Warning: mint %s could not be resolved, using previously resolved decimals", mint)
+				info.Decimals = decimals
+			} else {
+				log.Printf("⚠️  Warning: mint %s could not be resolved, its value is unknown until it is", mint)
+				info.Unresolved = true
+			}
+			walletData.TokenAccounts[mint] = info
+			continue
+		}
+		w.rememberDecimals(mint, mintInfo.Decimals)
+		info.Decimals = mintInfo.Decimals
+		info.Supply = mintInfo.Supply
+		info.SupplyShare = mintInfo.SupplyShare(info.Balance)
+		info.Authorities = w.inspectAuthorities(mintInfo, now)
+		info.MintExtensions = token2022.SummarizeMintExtensions(mintInfo.Extensions)
+		walletData.TokenAccounts[mint] = info
+	}
+	if err := w.authorities.Save(); err != nil {
+		log.Printf("⚠️  Warning: failed to save mint authority history: %v", err)
+	}
+
+	// 解析代币名称、符号与图片
+	w.applyMetadata(ctx, walletData, infos)
+	return nil
+}
+
+// RememberDecimals 记录快照中已解析的小数位，使重启后铸币信息暂时无法解析时仍能沿用上一次的快照
+func (w *WalletMonitor) RememberDecimals(data map[string]*WalletData) {
+	for _, walletData := range data {
+		if walletData == nil {
+			continue
+		}
+		for mint, info := range walletData.TokenAccounts {
+			if !info.Unresolved && info.Program != ProgramNative {
+				w.rememberDecimals(mint, info.Decimals)
+			}
+		}
+	}
+}
+
+func (w *WalletMonitor) rememberDecimals(mint string, decimals uint8) {
+	w.decimalsMutex.Lock()
+	defer w.decimalsMutex.Unlock()
+	if w.decimals == nil {
+		w.decimals = make(map[string]uint8)
+	}
+	w.decimals[mint] = decimals
+}
+
+func (w *WalletMonitor) knownDecimals(mint string) (uint8, bool) {
+	w.decimalsMutex.Lock()
+	defer w.decimalsMutex.Unlock()
+	decimals, known := w.decimals[mint]
+	return decimals, known
+}
+
+// inspectAuthorities 记录铸币的铸币与冻结权限，并标注权限地址是否为受监控的钱包
+func (w *WalletMonitor) inspectAuthorities(info mint.Info, now time.Time) *authority.Authorities {
+	authorities := w.authorities.Observe(info.Mint, info.MintAuthority, info.FreezeAuthority, now)
+	for _, wallet := range w.wallets {
+		address := wallet.String()
+		if address == authorities.MintAuthority {
+			authorities.MintAuthorityMonitored = true
+		}
+		if address == authorities.FreezeAuthority {
+			authorities.FreezeAuthorityMonitored = true
+		}
+	}
+	return &authorities
+}
+
+// applyMetadata 使用 Metaplex 或 Token-2022 元数据填充代币名称与符号
+func (w *WalletMonitor) applyMetadata(ctx context.Context, walletData *WalletData, infos map[string]mint.Info) {
+	mints := make([]string, 0, len(walletData.TokenAccounts))
+	embedded := make(map[string][]token2022.Extension)
+	for mint := range walletData.TokenAccounts {
+		mints = append(mints, mint)
+		if info, exists := infos[mint]; exists && len(info.Extensions) > 0 {
+			embedded[mint] = info.Extensions
+		}
+	}
+
+	// 查询失败时仍使用已解析的元数据
+	resolved, err := w.metadata.Resolve(ctx, mints, embedded)
+	if err != nil {
+		log.Printf("⚠️  Warning: failed to resolve token metadata: %v", err)
+	}
+
+	for mint, tokenMetadata := range resolved {
+		info, exists := walletData.TokenAccounts[mint]
+		if !exists {
+			continue
+		}
+		if tokenMetadata.Symbol != "" {
+			info.Symbol = tokenMetadata.Symbol
+		}
+		info.Name = tokenMetadata.Name
+		info.ImageURI = tokenMetadata.Image
+		walletData.TokenAccounts[mint] = info
+	}
+}
+
+// applyPrices 为扫描结果填充价格与美元价值
+func (w *WalletMonitor) applyPrices(results map[string]*WalletData) {
+	seen := make(map[string]bool)
+	mints := make([]string, 0)
+	for _, walletData := range results {
+		for mint := range walletData.TokenAccounts {
+			if !seen[mint] {
+				seen[mint] = true
+				mints = append(mints, mint)
+			}
+		}
+	}
+	if len(mints) == 0 {
+		return
+	}
+
+	if err := w.priceService.UpdatePrices(mints); err != nil {
+		log.Printf("⚠️  Warning: failed to update prices: %v", err)
+	}
+
+	for _, walletData := range results {
+		for mint, info := range walletData.TokenAccounts {
+			priceData, exists := w.priceService.GetPrice(mint)
+			if !exists || info.Unresolved {
+				continue
+			}
+			info.USDPrice = priceData.Price
+			info.USDValue = info.UIAmount() * priceData.Price
+			info.ConfidenceLevel = priceData.ConfidenceLevel
+			walletData.TokenAccounts[mint] = info
+		}
+	}
 }
 
 // 添加以下类型定义
 type Change struct {
 	WalletAddress string
 	TokenMint     string
-	TokenSymbol   string // 代币符号
-	TokenDecimals uint8  // 代币小数位
+	TokenSymbol   string   // 代币符号
+	TokenName     string   // 代币名称（来自元数据）
+	TokenImage    string   // 代币图片地址
+	TokenDecimals uint8    // 代币小数位
+	TokenProgram  string   // 代币所属程序（spl-token 或 token-2022）
+	TokenFlags    []string `json:",omitempty"` // Token-2022 扩展标记
 	ChangeType    string
 	OldBalance    uint64
 	NewBalance    uint64
 	ChangePercent float64
+	USDPrice      float64           // 代币单价（美元）
+	SupplyShare   float64           // 新余额占总供应量的百分比
+	LastUSDValue  float64           `json:",omitempty"` // token_exit 变化中清仓前最后一次记录的美元价值
 	TokenBalances map[string]uint64 `json:",omitempty"`
+	// new_wallet 变化中各代币的小数位、符号与美元价值
+	TokenDecimalsMap map[string]uint8   `json:",omitempty"`
+	TokenSymbols     map[string]string  `json:",omitempty"`
+	TokenValues      map[string]float64 `json:",omitempty"`
+	// 余额发生变化的代币账户（同一铸币可能由多个账户持有）
+	AccountChanges []AccountChange `json:",omitempty"`
+	// account_state 变化中代币账户委托、冻结或关闭权限的变化
+	AccountState *AccountStateChange `json:",omitempty"`
+	// new_token 与 authority_change 变化中铸币的当前权限，以及 authority_change 的权限变化描述
+	Authorities         *authority.Authorities `json:",omitempty"`
+	PreviousAuthorities *authority.Authorities `json:",omitempty"`
+	AuthorityChanges    []string               `json:",omitempty"`
+	// 钱包当前的 InsiderScore（0–100）与代币的上线时间（未知时为零值），由 UpdateScores 填充
+	InsiderScore float64   `json:",omitempty"`
+	TokenLaunch  time.Time `json:",omitempty"`
+	// 主要持有者变化中的排名与前 10 名集中度（%），排名 0 表示不在前 N 名内
+	HolderRank            int     `json:",omitempty"`
+	PreviousRank          int     `json:",omitempty"`
+	Concentration         float64 `json:",omitempty"`
+	PreviousConcentration float64 `json:",omitempty"`
+	// 导致该变化的交易（由 AttributeChanges 填充，按时间从新到旧）
+	Transactions []attribution.Attribution `json:",omitempty"`
+	// wallet_failing 变化中的连续失败次数、最后一次错误与最后一次成功扫描时间
+	FailureCount int       `json:",omitempty"`
+	LastError    string    `json:",omitempty"`
+	LastSuccess  time.Time `json:",omitempty"`
+	// 检测到变化的快照的上下文 slot 与承诺级别
+	Slot       uint64 `json:",omitempty"`
+	Commitment string `json:",omitempty"`
+	// 尚未最终确认即已告警（retract 模式）；change_retracted 变化中为被撤回变化的类型
+	Unfinalized   bool   `json:",omitempty"`
+	RetractedType string `json:",omitempty"`
+	// accumulation 与 distribution 变化的窗口长度、基线时间、窗口内的流入流出量与余额变化次数
+	Window      time.Duration `json:",omitempty"`
+	WindowStart time.Time     `json:",omitempty"`
+	Inflow      uint64        `json:",omitempty"`
+	Outflow     uint64        `json:",omitempty"`
+	Movements   int           `json:",omitempty"`
+	// coordinated_buy 与 coordinated_sell 变化中的参与钱包，按开始变动的时间排序
+	Participants []correlate.Participant `json:",omitempty"`
+	// 变化类型（buy、sell、transfer_in 等），取自影响最大的归因交易
+	Kind          string `json:",omitempty"`
+	Venue         string `json:",omitempty"`
+	QuoteMint     string `json:",omitempty"`
+	QuoteAmount   uint64 `json:",omitempty"`
+	QuoteDecimals uint8  `json:",omitempty"`
+}
+
+// USDValue 返回新余额对应的美元价值
+func (c Change) USDValue() float64 {
+	return float64(c.NewBalance) / math.Pow(10, float64(c.TokenDecimals)) * c.USDPrice
 }
 
 func calculatePercentageChange(old, new uint64) float64 {
@@ -266,9 +836,9 @@ func abs(x float64) float64 {
 	return x
 }
 
-func (w *WalletMonitor) checkConnection() error {
+func (w *WalletMonitor) checkConnection(ctx context.Context) error {
 	// 尝试获取 slot 号作为简单的连接测试
-	_, err := w.client.GetSlot(context.Background(), rpc.CommitmentFinalized)
+	_, err := w.client.GetSlot(ctx, w.commitment)
 	w.isConnected = err == nil
 
 	if err != nil {
@@ -289,53 +859,96 @@ func (w *WalletMonitor) checkConnection() error {
 	return nil
 }
 
-// 更新 ScanAllWallets 以处理批量
-func (w *WalletMonitor) ScanAllWallets() (map[string]*WalletData, error) {
+// ScanAllWallets 使用有界工作池并发扫描全部钱包，所有请求共享客户端的速率限制。
+// 单个钱包失败不会影响其他钱包，失败原因记录在结果的 Errors 中；
+// 仅在连接检查失败或 ctx 被取消时返回错误。超过截止时间时返回已完成的部分结果
+func (w *WalletMonitor) ScanAllWallets(ctx context.Context) (*ScanResult, error) {
 	// 先检查连接
-	if err := w.checkConnection(); err != nil {
+	if err := w.checkConnection(ctx); err != nil {
 		return nil, err
 	}
 
-	results := make(map[string]*WalletData)
-	batchSize := 2
-
-	for i := 0; i < len(w.wallets); i += batchSize {
-		end := i + batchSize
-		if end > len(w.wallets) {
-			end = len(w.wallets)
-		}
+	workers := w.workers
+	if workers > len(w.wallets) {
+		workers = len(w.wallets)
+	}
+	log.Printf("📊 Scanning %d wallets with %d workers", len(w.wallets), workers)
 
-		log.Printf("📊 Processing wallets %d-%d of %d", i+1, end, len(w.wallets))
+	result := &ScanResult{
+		Wallets: make(map[string]*WalletData, len(w.wallets)),
+		Errors:  make(map[string]error),
+	}
+	var (
+		mutex sync.Mutex
+		wg    sync.WaitGroup
+	)
 
-		// 处理一批钱包
-		for _, wallet := range w.wallets[i:end] {
-			data, err := w.GetWalletData(wallet)
-			if err != nil {
-				log.Printf("❌ Error scanning wallet %s: %v", wallet.String(), err)
-				// 返回错误以传递增强的错误信息
-				return nil, fmt.Errorf("failed to scan wallet %s: %w", wallet.String(), err)
+	jobs := make(chan solana.PublicKey)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for wallet := range jobs {
+				data, err := w.GetWalletData(ctx, wallet)
+
+				mutex.Lock()
+				if err != nil {
+					if ctx.Err() == nil {
+						log.Printf("❌ Error scanning wallet %s: %v", wallet.String(), err)
+					}
+					result.Errors[wallet.String()] = err
+				} else {
+					result.Wallets[wallet.String()] = data
+				}
+				mutex.Unlock()
 			}
-			results[wallet.String()] = data
-		}
+		}()
+	}
 
-		// 批次之间短暂延迟以照顾 RPC
-		if end < len(w.wallets) {
-			time.Sleep(500 * time.Millisecond)
+	dispatched := 0
+feed:
+	for _, wallet := range w.wallets {
+		select {
+		case jobs <- wallet:
+			dispatched++
+		case <-ctx.Done():
+			break feed
 		}
 	}
+	close(jobs)
+	wg.Wait()
+
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return nil, fmt.Errorf("scan aborted: %w", ctx.Err())
+	}
 
-	return results, nil
+	// 超时后未能开始扫描的钱包同样记为失败
+	for _, wallet := range w.wallets[dispatched:] {
+		result.Errors[wallet.String()] = fmt.Errorf("not scanned: %w", ctx.Err())
+	}
+	if len(result.Errors) > 0 {
+		log.Printf("⚠️  Scan finished with %d of %d wallets failing", len(result.Errors), len(w.wallets))
+	}
+
+	// 使用真实小数位计算美元价值
+	w.applyPrices(result.Wallets)
+
+	return result, nil
 }
 
-func DetectChanges(oldData, newData map[string]*WalletData, significantChange float64) []Change {
+// DetectChanges 比较两次快照，余额变化的绝对百分比达到 minChangePercent（20 表示 20%）时记为 balance_change，
+// minChangePercent 为 0 时记录所有非零的余额变化
+func DetectChanges(oldData, newData map[string]*WalletData, minChangePercent float64) []Change {
 	var changes []Change
 
 	// 检查现有钱包的变化
 	for walletAddr, newWalletData := range newData {
 		oldWalletData, existed := oldData[walletAddr]
 
-		if !existed {
-			continue // 暂时跳过新钱包检测
+		if !existed || oldWalletData == nil {
+			// 新加入监控的钱包：发送包含全部持仓的基线告警
+			changes = append(changes, newWalletChange(walletAddr, newWalletData))
+			continue
 		}
 
 		// 检查现有钱包的变化
@@ -345,38 +958,160 @@ func DetectChanges(oldData, newData map[string]*WalletData, significantChange fl
 			if !existed {
 				// 检测到新代币
 				changes = append(changes, Change{
-					WalletAddress: walletAddr,
-					TokenMint:     mint,
-					TokenSymbol:   newInfo.Symbol,
-					TokenDecimals: newInfo.Decimals,
-					ChangeType:    "new_token",
-					NewBalance:    newInfo.Balance,
+					WalletAddress:  walletAddr,
+					TokenMint:      mint,
+					TokenSymbol:    newInfo.Symbol,
+					TokenName:      newInfo.Name,
+					TokenImage:     newInfo.ImageURI,
+					TokenDecimals:  newInfo.Decimals,
+					TokenProgram:   newInfo.Program,
+					TokenFlags:     newInfo.Flags(),
+					USDPrice:       newInfo.USDPrice,
+					SupplyShare:    newInfo.SupplyShare,
+					ChangeType:     "new_token",
+					NewBalance:     newInfo.Balance,
+					AccountChanges: diffAccounts(nil, newInfo.Accounts),
+					Authorities:    newInfo.Authorities,
 				})
 				continue
 			}
 
+			// 小数位未知的持仓无法与另一份快照可靠比较，待解析后再比较
+			if newInfo.Unresolved || oldInfo.Unresolved {
+				continue
+			}
+
 			// 检查显著的余额变化
 			pctChange := calculatePercentageChange(oldInfo.Balance, newInfo.Balance)
 			absChange := abs(pctChange)
 
-			if absChange >= significantChange {
+			if newInfo.Balance != oldInfo.Balance && absChange >= minChangePercent {
 				changes = append(changes, Change{
-					WalletAddress: walletAddr,
-					TokenMint:     mint,
-					TokenSymbol:   newInfo.Symbol,
-					TokenDecimals: newInfo.Decimals,
-					ChangeType:    "balance_change",
-					OldBalance:    oldInfo.Balance,
-					NewBalance:    newInfo.Balance,
-					ChangePercent: pctChange,
+					WalletAddress:  walletAddr,
+					TokenMint:      mint,
+					TokenSymbol:    newInfo.Symbol,
+					TokenName:      newInfo.Name,
+					TokenImage:     newInfo.ImageURI,
+					TokenDecimals:  newInfo.Decimals,
+					TokenProgram:   newInfo.Program,
+					TokenFlags:     newInfo.Flags(),
+					USDPrice:       newInfo.USDPrice,
+					SupplyShare:    newInfo.SupplyShare,
+					ChangeType:     "balance_change",
+					OldBalance:     oldInfo.Balance,
+					NewBalance:     newInfo.Balance,
+					ChangePercent:  pctChange,
+					AccountChanges: diffAccounts(oldInfo.Accounts, newInfo.Accounts),
 				})
 			}
+
+			// 委托、冻结与关闭权限的变化
+			changes = append(changes, accountStateChanges(walletAddr, mint, oldInfo, newInfo)...)
+
+			// 铸币或冻结权限的变化
+			if change, changed := authorityChange(walletAddr, mint, oldInfo, newInfo); changed {
+				changes = append(changes, change)
+			}
+		}
+
+		// 旧快照中存在而新快照中消失的持仓：已清仓、销毁或关闭了代币账户
+		for mint, oldInfo := range oldWalletData.TokenAccounts {
+			if _, exists := newWalletData.TokenAccounts[mint]; exists {
+				continue
+			}
+			changes = append(changes, Change{
+				WalletAddress:  walletAddr,
+				TokenMint:      mint,
+				TokenSymbol:    oldInfo.Symbol,
+				TokenName:      oldInfo.Name,
+				TokenImage:     oldInfo.ImageURI,
+				TokenDecimals:  oldInfo.Decimals,
+				TokenProgram:   oldInfo.Program,
+				TokenFlags:     oldInfo.Flags(),
+				USDPrice:       oldInfo.USDPrice,
+				SupplyShare:    oldInfo.SupplyShare,
+				LastUSDValue:   oldInfo.USDValue,
+				ChangeType:     "token_exit",
+				OldBalance:     oldInfo.Balance,
+				ChangePercent:  -100.0,
+				AccountChanges: diffAccounts(oldInfo.Accounts, nil),
+			})
+		}
+	}
+
+	// 记录检测到变化的快照所在的 slot，用于最终确认
+	for i := range changes {
+		if data := newData[changes[i].WalletAddress]; data != nil {
+			changes[i].Slot = data.Slot
+			changes[i].Commitment = data.Commitment
 		}
 	}
 
 	return changes
 }
 
+// AccountChange 描述单个代币账户的余额变化，账户被关闭时 NewBalance 为 0
+type AccountChange struct {
+	Address    string
+	OldBalance uint64
+	NewBalance uint64
+}
+
+// diffAccounts 比较同一持仓下各代币账户的余额，返回发生变化的账户（按地址排序）
+func diffAccounts(oldAccounts, newAccounts []TokenAccountDetail) []AccountChange {
+	balances := make(map[string]*AccountChange)
+	for _, account := range oldAccounts {
+		balances[account.Address] = &AccountChange{Address: account.Address, OldBalance: account.Balance}
+	}
+	for _, account := range newAccounts {
+		change, exists := balances[account.Address]
+		if !exists {
+			change = &AccountChange{Address: account.Address}
+			balances[account.Address] = change
+		}
+		change.NewBalance = account.Balance
+	}
+
+	var changes []AccountChange
+	for _, change := range balances {
+		if change.OldBalance != change.NewBalance {
+			changes = append(changes, *change)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Address < changes[j].Address
+	})
+	return changes
+}
+
+// newWalletChange 以钱包当前的全部持仓构造 new_wallet 变化
+func newWalletChange(walletAddr string, data *WalletData) Change {
+	change := Change{
+		WalletAddress:    walletAddr,
+		ChangeType:       "new_wallet",
+		TokenBalances:    make(map[string]uint64, len(data.TokenAccounts)),
+		TokenDecimalsMap: make(map[string]uint8, len(data.TokenAccounts)),
+		TokenSymbols:     make(map[string]string, len(data.TokenAccounts)),
+		TokenValues:      make(map[string]float64, len(data.TokenAccounts)),
+	}
+	for mint, info := range data.TokenAccounts {
+		change.TokenBalances[mint] = info.Balance
+		change.TokenDecimalsMap[mint] = info.Decimals
+		change.TokenSymbols[mint] = info.Symbol
+		change.TokenValues[mint] = info.USDValue
+	}
+	return change
+}
+
+// TotalUSDValue 返回 new_wallet 变化中全部持仓的美元总价值
+func (c Change) TotalUSDValue() float64 {
+	var total float64
+	for _, value := range c.TokenValues {
+		total += value
+	}
+	return total
+}
+
 // 添加此辅助函数
 func formatTokenAmount(amount uint64, decimals uint8) string {
 	if decimals == 0 {
@@ -474,13 +1209,14 @@ func formatTokenValue(value float64, confidence string) string {
 // 添加结构体以存储带有美元价值的代币数据
 type tokenHolding struct {
 	Mint     string
-	Amount   float64
+	Amount   float64 // 按小数位换算后的数量
 	USDValue float64
 	Symbol   string
 }
 
-// 更新 DisplayWalletOverview 函数以提供更美观的输出
-func (m *WalletMonitor) DisplayWalletOverview(walletDataMap map[string]*WalletData) {
+// 更新 DisplayWalletOverview 函数以提供更美观的输出。
+// statuses 为各钱包的扫描健康状况，本次扫描失败的钱包显示连续失败次数与数据的陈旧时长
+func (m *WalletMonitor) DisplayWalletOverview(walletDataMap map[string]*WalletData, statuses map[string]*WalletStatus) {
 	// 终端颜色代码
 	const (
 		colorReset  = "\033[0m"
@@ -507,19 +1243,6 @@ func (m *WalletMonitor) DisplayWalletOverview(walletDataMap map[string]*WalletDa
 	fmt.Printf("%s%s SOLANA WALLET MONITOR %s\n", colorBold, colorPurple, colorReset)
 	fmt.Printf("%s%s %s\n\n", colorPurple, divider, colorReset)
 
-	// 收集所有唯一的铸币地址
-	mints := make([]string, 0)
-	for _, walletData := range walletDataMap {
-		for mint := range walletData.TokenAccounts {
-			mints = append(mints, mint)
-		}
-	}
-
-	// 更新所有代币的价格
-	if err := m.priceService.UpdatePrices(mints); err != nil {
-		log.Printf("Error updating prices: %v", err)
-	}
-
 	// 总价值计数器
 	totalPortfolioValue := 0.0
 
@@ -527,7 +1250,17 @@ func (m *WalletMonitor) DisplayWalletOverview(walletDataMap map[string]*WalletDa
 		fmt.Printf("%s%s %s %s%s\n", colorBold, colorBlue, walletSymbol, wallet.String(), colorReset)
 		walletData, exists := walletDataMap[wallet.String()]
 		if !exists {
-			fmt.Printf("   %sNo data available%s\n\n", colorYellow, colorReset)
+			status := statuses[wallet.String()]
+			switch {
+			case status == nil || status.ConsecutiveFailures == 0:
+				fmt.Printf("   %sNo data available%s\n\n", colorYellow, colorReset)
+			case status.LastSuccess.IsZero():
+				fmt.Printf("   %s⚠️  Scan failing (%d in a row), never scanned successfully: %s%s\n\n",
+					colorRed, status.ConsecutiveFailures, status.LastError, colorReset)
+			default:
+				fmt.Printf("   %s⚠️  Scan failing (%d in a row), data is %s old: %s%s\n\n",
+					colorRed, status.ConsecutiveFailures, utils.FormatDuration(status.Staleness(time.Now()).Round(time.Second)), status.LastError, colorReset)
+			}
 			continue
 		}
 
@@ -536,33 +1269,48 @@ func (m *WalletMonitor) DisplayWalletOverview(walletDataMap map[string]*WalletDa
 		walletTotalValue := 0.0
 
 		for mint, info := range walletData.TokenAccounts {
-			// 从 Jupiter 获取价格数据
-			priceData, exists := m.priceService.GetPrice(mint)
-
-			usdValue := 0.0
-			if exists {
-				// 考虑小数位将余额转换为浮点数
-				actualAmount := float64(info.Balance) / math.Pow(10, float64(info.Decimals))
-				usdValue = actualAmount * priceData.Price
-				walletTotalValue += usdValue
-			}
+			// 价格与美元价值已在扫描时按真实小数位计算
+			walletTotalValue += info.USDValue
 
-			// 尝试查找常见代币地址以获得更好的名称
+			// 元数据缺失时尝试查找常见代币地址以获得更好的名称
 			symbol := info.Symbol
-			if tokenName, found := getKnownTokenName(mint); found {
-				symbol = tokenName
+			if info.Name == "" {
+				if tokenName, found := getKnownTokenName(mint); found {
+					symbol = tokenName
+				}
+			}
+			if info.Unresolved {
+				symbol += " (raw amount, decimals unknown)"
+			}
+			if flags := info.Flags(); len(flags) > 0 {
+				symbol += " [" + strings.Join(flags, ", ") + "]"
 			}
 
 			holdings = append(holdings, tokenHolding{
 				Mint:     mint,
-				Amount:   float64(info.Balance),
-				USDValue: usdValue,
+				Amount:   info.UIAmount(),
+				USDValue: info.USDValue,
 				Symbol:   symbol,
 			})
 		}
 
 		totalPortfolioValue += walletTotalValue
 
+		// 显示 InsiderScore 及其变化
+		if result, exists := m.scorer.Get(wallet.String()); exists {
+			scoreColor := colorGreen
+			if result.Score >= 70 {
+				scoreColor = colorRed
+			} else if result.Score >= 40 {
+				scoreColor = colorYellow
+			}
+			trend := ""
+			if delta := result.Score - result.Previous; result.Previous > 0 && math.Abs(delta) >= 0.5 {
+				trend = fmt.Sprintf(" (%+.0f)", delta)
+			}
+			fmt.Printf("   %sInsider Score: %s%.0f%s%s\n", colorBold, scoreColor, result.Score, trend, colorReset)
+		}
+
 		// 按美元价值降序排序
 		sort.Slice(holdings, func(i, j int) bool {
 			return holdings[i].USDValue > holdings[j].USDValue
@@ -596,7 +1344,7 @@ func (m *WalletMonitor) DisplayWalletOverview(walletDataMap map[string]*WalletDa
 			}
 
 			// 格式化数量
-			actualAmount := holding.Amount / math.Pow(10, float64(9)) // 假设 9 位小数
+			actualAmount := holding.Amount
 			amountStr := ""
 			if actualAmount >= 1000000 {
 				amountStr = fmt.Sprintf("%.2fM", actualAmount/1000000)
@@ -653,6 +1401,25 @@ func (m *WalletMonitor) DisplayWalletOverview(walletDataMap map[string]*WalletDa
 		}
 	}
 
+	// 使用多个端点时显示各端点的健康状况
+	if stats := m.EndpointStats(); len(stats) > 1 {
+		fmt.Printf("%s%s %s\n", colorPurple, divider, colorReset)
+		fmt.Printf("%sRPC ENDPOINTS%s\n", colorBold, colorReset)
+		for _, endpoint := range stats {
+			status := colorGreen + "healthy" + colorReset
+			if !endpoint.Healthy {
+				status = colorRed + "unhealthy" + colorReset
+			}
+			fmt.Printf("   %-40s %s  lag %d  %v  %d req  %.1f%% errors\n",
+				endpoint.URL,
+				status,
+				endpoint.SlotLag,
+				endpoint.Latency.Round(time.Millisecond),
+				endpoint.Requests,
+				endpoint.ErrorRate*100)
+		}
+	}
+
 	fmt.Printf("%s%s %s\n", colorPurple, divider, colorReset)
 	fmt.Printf("%sLast updated: %s%s\n\n", colorYellow, time.Now().Format("2006-01-02 15:04:05"), colorReset)
 }
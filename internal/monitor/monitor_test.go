@@ -1,10 +1,20 @@
 package monitor
 
 import (
+	"context"
+	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/accursedgalaxy/insider-monitor/internal/attribution"
+	"github.com/accursedgalaxy/insider-monitor/internal/authority"
+	"github.com/accursedgalaxy/insider-monitor/internal/config"
+	"github.com/accursedgalaxy/insider-monitor/internal/correlate"
+	"github.com/accursedgalaxy/insider-monitor/internal/token2022"
+	"github.com/accursedgalaxy/insider-monitor/internal/window"
+	"github.com/gagliardetto/solana-go"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewWalletMonitor(t *testing.T) {
@@ -127,6 +137,72 @@ func TestDetectChanges(t *testing.T) {
 	assert.Equal(t, 100.0, changes[0].ChangePercent)
 }
 
+func TestDetectChangesNewWallet(t *testing.T) {
+	oldData := map[string]*WalletData{
+		"wallet1": {WalletAddress: "wallet1", TokenAccounts: map[string]TokenAccountInfo{}},
+	}
+	newData := map[string]*WalletData{
+		"wallet1": {WalletAddress: "wallet1", TokenAccounts: map[string]TokenAccountInfo{}},
+		"wallet2": {
+			WalletAddress: "wallet2",
+			TokenAccounts: map[string]TokenAccountInfo{
+				NativeSOLMint: {Balance: 2_000_000_000, Symbol: "SOL", Decimals: 9, USDValue: 300},
+				"token1":      {Balance: 1000, Symbol: "TKN1", Decimals: 6, USDValue: 12.5},
+			},
+		},
+	}
+
+	changes := DetectChanges(oldData, newData, 0.2)
+	require.Len(t, changes, 1)
+
+	change := changes[0]
+	assert.Equal(t, "new_wallet", change.ChangeType)
+	assert.Equal(t, "wallet2", change.WalletAddress)
+	assert.Equal(t, map[string]uint64{NativeSOLMint: 2_000_000_000, "token1": 1000}, change.TokenBalances)
+	assert.Equal(t, map[string]uint8{NativeSOLMint: 9, "token1": 6}, change.TokenDecimalsMap)
+	assert.Equal(t, map[string]string{NativeSOLMint: "SOL", "token1": "TKN1"}, change.TokenSymbols)
+	assert.Equal(t, 312.5, change.TotalUSDValue())
+}
+
+func TestDetectChangesTokenExit(t *testing.T) {
+	oldData := map[string]*WalletData{
+		"wallet1": {
+			WalletAddress: "wallet1",
+			TokenAccounts: map[string]TokenAccountInfo{
+				"token1": {Balance: 1000, Symbol: "TKN1", Decimals: 6, USDPrice: 2, USDValue: 0.002, Program: ProgramSPLToken},
+				"token2": {Balance: 500, Symbol: "TKN2", Decimals: 6},
+			},
+		},
+		// 本次扫描失败的钱包不在新快照中，不应产生清仓告警
+		"wallet2": {
+			WalletAddress: "wallet2",
+			TokenAccounts: map[string]TokenAccountInfo{
+				"token1": {Balance: 1000, Decimals: 6},
+			},
+		},
+	}
+	newData := map[string]*WalletData{
+		"wallet1": {
+			WalletAddress: "wallet1",
+			TokenAccounts: map[string]TokenAccountInfo{
+				"token2": {Balance: 500, Symbol: "TKN2", Decimals: 6},
+			},
+		},
+	}
+
+	changes := DetectChanges(oldData, newData, 0.2)
+	require.Len(t, changes, 1)
+	assert.Equal(t, "token_exit", changes[0].ChangeType)
+	assert.Equal(t, "wallet1", changes[0].WalletAddress)
+	assert.Equal(t, "token1", changes[0].TokenMint)
+	assert.Equal(t, "TKN1", changes[0].TokenSymbol)
+	assert.Equal(t, ProgramSPLToken, changes[0].TokenProgram)
+	assert.Equal(t, uint64(1000), changes[0].OldBalance)
+	assert.Equal(t, uint64(0), changes[0].NewBalance)
+	assert.Equal(t, -100.0, changes[0].ChangePercent)
+	assert.Equal(t, 0.002, changes[0].LastUSDValue)
+}
+
 func TestAbs(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -198,3 +274,470 @@ func TestFormatTokenAmount(t *testing.T) {
 		})
 	}
 }
+
+func TestApplyNativeBalance(t *testing.T) {
+	w := &WalletMonitor{}
+
+	t.Run("Folds wrapped SOL into native balance", func(t *testing.T) {
+		data := &WalletData{
+			TokenAccounts: map[string]TokenAccountInfo{
+				NativeSOLMint: {Balance: 500, Program: ProgramSPLToken, Decimals: 9},
+			},
+		}
+		w.applyNativeBalance(data, 1500)
+
+		assert.Equal(t, uint64(1500), data.SOLBalance)
+		assert.Equal(t, uint64(500), data.WrappedSOLBalance)
+		assert.Equal(t, uint64(2000), data.TokenAccounts[NativeSOLMint].Balance)
+		assert.Equal(t, ProgramNative, data.TokenAccounts[NativeSOLMint].Program)
+		assert.Equal(t, "SOL", data.TokenAccounts[NativeSOLMint].Symbol)
+	})
+
+	t.Run("Empty wallet has no SOL holding", func(t *testing.T) {
+		data := &WalletData{TokenAccounts: map[string]TokenAccountInfo{}}
+		w.applyNativeBalance(data, 0)
+		assert.NotContains(t, data.TokenAccounts, NativeSOLMint)
+	})
+}
+
+func TestScanAllWalletsConcurrently(t *testing.T) {
+	f := newFakeRPC(t)
+	f.delay = 20 * time.Millisecond
+	server := httptest.NewServer(f)
+	defer server.Close()
+
+	wallets := make([]string, 20)
+	for i := range wallets {
+		wallets[i] = solana.NewWallet().PublicKey().String()
+	}
+
+	// 白名单为空时不包含任何代币，避免请求价格接口
+	w, err := NewWalletMonitor(server.URL, wallets, &config.ScanConfig{
+		ScanMode:          "whitelist",
+		Workers:           5,
+		RequestsPerSecond: 1000,
+	})
+	require.NoError(t, err)
+
+	result, err := w.ScanAllWallets(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, result.Wallets, len(wallets))
+	assert.Empty(t, result.Errors)
+	assert.Greater(t, f.peakInFlight.Load(), int32(1))
+	assert.LessOrEqual(t, f.peakInFlight.Load(), int32(5))
+}
+
+func TestScanAllWalletsAbortsOnCancel(t *testing.T) {
+	f := newFakeRPC(t)
+	f.delay = time.Minute
+	server := httptest.NewServer(f)
+	defer server.Close()
+
+	w, err := NewWalletMonitor(server.URL, []string{f.wallet.String()}, nil)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err = w.ScanAllWallets(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestScanAllWalletsReturnsPartialResults(t *testing.T) {
+	f := newFakeRPC(t)
+	server := httptest.NewServer(f)
+	defer server.Close()
+
+	healthy := solana.NewWallet().PublicKey().String()
+	f.failWallet = solana.NewWallet().PublicKey().String()
+
+	w, err := NewWalletMonitor(server.URL, []string{healthy, f.failWallet}, &config.ScanConfig{
+		ScanMode:          "whitelist",
+		RequestsPerSecond: 1000,
+	})
+	require.NoError(t, err)
+
+	result, err := w.ScanAllWallets(context.Background())
+	require.NoError(t, err)
+	assert.Contains(t, result.Wallets, healthy)
+	assert.NotContains(t, result.Wallets, f.failWallet)
+	require.Contains(t, result.Errors, f.failWallet)
+	assert.ErrorContains(t, result.Errors[f.failWallet], "node is unhealthy")
+	assert.False(t, result.AllFailed())
+}
+
+func TestUpdateWalletStatus(t *testing.T) {
+	statuses := make(map[string]*WalletStatus)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	healthy := &ScanResult{
+		Wallets: map[string]*WalletData{"wallet1": {}},
+		Errors:  map[string]error{},
+	}
+	failing := &ScanResult{
+		Wallets: map[string]*WalletData{},
+		Errors:  map[string]error{"wallet1": assert.AnError},
+	}
+
+	assert.Empty(t, UpdateWalletStatus(statuses, healthy, 3, start))
+
+	// 前两次失败不告警，第三次告警，之后不再重复
+	var alerts []Change
+	for i := 1; i <= 4; i++ {
+		alerts = append(alerts, UpdateWalletStatus(statuses, failing, 3, start.Add(time.Duration(i)*time.Minute))...)
+	}
+	require.Len(t, alerts, 1)
+	assert.Equal(t, "wallet_failing", alerts[0].ChangeType)
+	assert.Equal(t, 3, alerts[0].FailureCount)
+	assert.Equal(t, start, alerts[0].LastSuccess)
+
+	status := statuses["wallet1"]
+	assert.Equal(t, 4, status.ConsecutiveFailures)
+	assert.Equal(t, 4*time.Minute, status.Staleness(start.Add(4*time.Minute)))
+
+	// 恢复后重新计数
+	UpdateWalletStatus(statuses, healthy, 3, start.Add(5*time.Minute))
+	assert.Equal(t, 0, status.ConsecutiveFailures)
+	assert.Equal(t, 4, status.TotalFailures)
+}
+
+func TestScanWalletAggregatesTokenAccounts(t *testing.T) {
+	f := newFakeRPC(t)
+	f.extraAccount = solana.NewWallet().PublicKey()
+	f.extraAmount = 250
+	server := httptest.NewServer(f)
+	defer server.Close()
+
+	w, _ := newStreamingMonitor(t, f, server.URL)
+	data, refs, err := w.scanWallet(context.Background(), f.wallet)
+	require.NoError(t, err)
+	assert.Len(t, refs, 2)
+
+	info := data.TokenAccounts[f.mint.String()]
+	assert.Equal(t, uint64(1250), info.Balance)
+	require.Len(t, info.Accounts, 2)
+
+	accounts := make(map[string]TokenAccountDetail)
+	for _, account := range info.Accounts {
+		accounts[account.Address] = account
+	}
+	assert.Equal(t, TokenAccountDetail{
+		Address: f.tokenAccount.String(),
+		Balance: 1000,
+		Program: ProgramSPLToken,
+		State:   AccountStateInitialized,
+	}, accounts[f.tokenAccount.String()])
+	assert.Equal(t, TokenAccountDetail{
+		Address:         f.extraAccount.String(),
+		Balance:         250,
+		Program:         ProgramSPLToken,
+		State:           AccountStateFrozen,
+		Delegate:        f.wallet.String(),
+		DelegatedAmount: 250,
+	}, accounts[f.extraAccount.String()])
+}
+
+func TestScanWalletWithUnresolvedMint(t *testing.T) {
+	f := newFakeRPC(t)
+	f.hideMint.Store(true)
+	server := httptest.NewServer(f)
+	defer server.Close()
+	ctx := context.Background()
+
+	// 从未解析过的铸币标记为未知，不使用默认小数位
+	w, state := newStreamingMonitor(t, f, server.URL)
+	data, _, err := w.scanWallet(ctx, f.wallet)
+	require.NoError(t, err)
+	info := data.TokenAccounts[f.mint.String()]
+	assert.True(t, info.Unresolved)
+	assert.Zero(t, info.Decimals)
+
+	// 未知的持仓不与上一次快照比较余额
+	f.amount.Store(10)
+	data, _, err = w.scanWallet(ctx, f.wallet)
+	require.NoError(t, err)
+	assert.Empty(t, DetectChanges(state, map[string]*WalletData{f.wallet.String(): data}, 20))
+
+	// 沿用上一次快照中的小数位
+	w, state = newStreamingMonitor(t, f, server.URL)
+	w.RememberDecimals(state)
+	data, _, err = w.scanWallet(ctx, f.wallet)
+	require.NoError(t, err)
+	info = data.TokenAccounts[f.mint.String()]
+	assert.False(t, info.Unresolved)
+	assert.Equal(t, uint8(6), info.Decimals)
+	changes := DetectChanges(state, map[string]*WalletData{f.wallet.String(): data}, 20)
+	require.Len(t, changes, 1)
+	assert.Equal(t, uint8(6), changes[0].TokenDecimals)
+}
+
+func TestDiffAccounts(t *testing.T) {
+	tests := []struct {
+		name     string
+		old      []TokenAccountDetail
+		new      []TokenAccountDetail
+		expected []AccountChange
+	}{
+		{
+			name:     "Unchanged",
+			old:      []TokenAccountDetail{{Address: "a", Balance: 10}, {Address: "b", Balance: 5}},
+			new:      []TokenAccountDetail{{Address: "a", Balance: 10}, {Address: "b", Balance: 5}},
+			expected: nil,
+		},
+		{
+			name:     "Auxiliary account moved",
+			old:      []TokenAccountDetail{{Address: "a", Balance: 10}, {Address: "b", Balance: 5}},
+			new:      []TokenAccountDetail{{Address: "a", Balance: 10}, {Address: "b", Balance: 1}},
+			expected: []AccountChange{{Address: "b", OldBalance: 5, NewBalance: 1}},
+		},
+		{
+			name:     "Account closed and another opened",
+			old:      []TokenAccountDetail{{Address: "b", Balance: 5}},
+			new:      []TokenAccountDetail{{Address: "a", Balance: 5}},
+			expected: []AccountChange{{Address: "a", NewBalance: 5}, {Address: "b", OldBalance: 5}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, diffAccounts(tt.old, tt.new))
+		})
+	}
+}
+
+func TestAccountStateEvents(t *testing.T) {
+	base := TokenAccountDetail{Address: "acct", Balance: 100, State: AccountStateInitialized}
+	with := func(modify func(*TokenAccountDetail)) TokenAccountDetail {
+		detail := base
+		modify(&detail)
+		return detail
+	}
+
+	tests := []struct {
+		name     string
+		old      TokenAccountDetail
+		new      TokenAccountDetail
+		expected []string
+	}{
+		{
+			name:     "Unchanged",
+			old:      base,
+			new:      base,
+			expected: nil,
+		},
+		{
+			name:     "Delegate approved",
+			old:      base,
+			new:      with(func(d *TokenAccountDetail) { d.Delegate, d.DelegatedAmount = "bot", 100 }),
+			expected: []string{EventDelegateApproved},
+		},
+		{
+			name:     "Delegate replaced",
+			old:      with(func(d *TokenAccountDetail) { d.Delegate, d.DelegatedAmount = "bot", 100 }),
+			new:      with(func(d *TokenAccountDetail) { d.Delegate, d.DelegatedAmount = "other", 100 }),
+			expected: []string{EventDelegateApproved},
+		},
+		{
+			name:     "Delegate spent part of allowance",
+			old:      with(func(d *TokenAccountDetail) { d.Delegate, d.DelegatedAmount = "bot", 100 }),
+			new:      with(func(d *TokenAccountDetail) { d.Delegate, d.DelegatedAmount = "bot", 40 }),
+			expected: []string{EventDelegatedAmountChanged},
+		},
+		{
+			name:     "Delegate revoked",
+			old:      with(func(d *TokenAccountDetail) { d.Delegate, d.DelegatedAmount = "bot", 100 }),
+			new:      base,
+			expected: []string{EventDelegateRevoked},
+		},
+		{
+			name:     "Frozen with new close authority",
+			old:      base,
+			new:      with(func(d *TokenAccountDetail) { d.State, d.CloseAuthority = AccountStateFrozen, "closer" }),
+			expected: []string{EventFrozen, EventCloseAuthorityChanged},
+		},
+		{
+			name:     "Thawed",
+			old:      with(func(d *TokenAccountDetail) { d.State = AccountStateFrozen }),
+			new:      base,
+			expected: []string{EventThawed},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, accountStateEvents(tt.old, tt.new))
+		})
+	}
+}
+
+func TestDetectChangesAccountState(t *testing.T) {
+	account := TokenAccountDetail{Address: "acct", Balance: 1000, State: AccountStateInitialized}
+	delegated := account
+	delegated.Delegate, delegated.DelegatedAmount = "bot", 1000
+
+	oldData := map[string]*WalletData{
+		"wallet1": {TokenAccounts: map[string]TokenAccountInfo{
+			"token1": {Balance: 1000, Decimals: 6, Accounts: []TokenAccountDetail{account}},
+			// 升级前保存的数据没有账户明细，不应产生告警
+			"token2": {Balance: 1000, Decimals: 6},
+		}},
+	}
+	newData := map[string]*WalletData{
+		"wallet1": {TokenAccounts: map[string]TokenAccountInfo{
+			"token1": {Balance: 1000, Decimals: 6, Accounts: []TokenAccountDetail{delegated}},
+			"token2": {Balance: 1000, Decimals: 6, Accounts: []TokenAccountDetail{delegated}},
+		}},
+	}
+
+	changes := DetectChanges(oldData, newData, 0.2)
+	require.Len(t, changes, 1)
+	assert.Equal(t, "account_state", changes[0].ChangeType)
+	assert.Equal(t, "token1", changes[0].TokenMint)
+	require.NotNil(t, changes[0].AccountState)
+	assert.Equal(t, []string{EventDelegateApproved}, changes[0].AccountState.Events)
+	assert.False(t, changes[0].AccountState.Benign())
+	assert.Equal(t, []string{"Delegate approved: bot may transfer 0.0010"}, changes[0].AccountState.Describe(6))
+}
+
+func TestScanWalletInspectsAuthorities(t *testing.T) {
+	f := newFakeRPC(t)
+	f.mintAuthority = f.wallet
+	server := httptest.NewServer(f)
+	defer server.Close()
+
+	w, _ := newStreamingMonitor(t, f, server.URL)
+	data, _, err := w.scanWallet(context.Background(), f.wallet)
+	require.NoError(t, err)
+
+	info := data.TokenAccounts[f.mint.String()]
+	require.NotNil(t, info.Authorities)
+	assert.Equal(t, f.wallet.String(), info.Authorities.MintAuthority)
+	assert.True(t, info.Authorities.MintAuthorityMonitored)
+	assert.Empty(t, info.Authorities.FreezeAuthority)
+	assert.True(t, info.Authorities.ChangedAt.IsZero(), "first observation is not a change")
+}
+
+func TestDetectChangesAuthorityChange(t *testing.T) {
+	active := &authority.Authorities{MintAuthority: "dev", FreezeAuthority: "dev"}
+	transferred := &authority.Authorities{MintAuthority: "other", FreezeAuthority: "dev"}
+
+	oldData := map[string]*WalletData{
+		"wallet1": {TokenAccounts: map[string]TokenAccountInfo{
+			"token1": {Balance: 1000, Authorities: active},
+			// 升级前保存的数据没有权限信息，不应产生告警
+			"token2": {Balance: 1000},
+		}},
+	}
+	newData := map[string]*WalletData{
+		"wallet1": {TokenAccounts: map[string]TokenAccountInfo{
+			"token1": {Balance: 1000, Authorities: transferred},
+			"token2": {Balance: 1000, Authorities: transferred},
+			"token3": {Balance: 500, Authorities: active},
+		}},
+	}
+
+	changes := DetectChanges(oldData, newData, 0.2)
+	require.Len(t, changes, 2)
+
+	byType := make(map[string]Change)
+	for _, change := range changes {
+		byType[change.ChangeType] = change
+	}
+	change := byType["authority_change"]
+	assert.Equal(t, "token1", change.TokenMint)
+	assert.Equal(t, []string{"Mint authority: dev → other"}, change.AuthorityChanges)
+	assert.Equal(t, active, change.PreviousAuthorities)
+	assert.Equal(t, transferred, change.Authorities)
+
+	assert.Equal(t, active, byType["new_token"].Authorities)
+}
+
+func TestObserveWindows(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	windows := []config.WindowConfig{{Duration: "1h", Threshold: 0.2}}
+	snapshot := func(balance uint64) map[string]*WalletData {
+		data := &WalletData{WalletAddress: "w", TokenAccounts: map[string]TokenAccountInfo{}, Slot: 42}
+		if balance > 0 {
+			data.TokenAccounts["x"] = TokenAccountInfo{Balance: balance, Decimals: 6, Symbol: "X", USDPrice: 2}
+		}
+		return map[string]*WalletData{"w": data}
+	}
+
+	state := make(window.State)
+	assert.Nil(t, ObserveWindows(state, nil, snapshot(1000), nil, start), "disabled without windows")
+	assert.Empty(t, state)
+
+	var changes []Change
+	previous := snapshot(1000)
+	for i, balance := range []uint64{1000, 900, 0} {
+		current := snapshot(balance)
+		changes = append(changes, ObserveWindows(state, previous, current, windows, start.Add(time.Duration(i)*time.Minute))...)
+		previous = current
+	}
+
+	// 清仓后的代币信息取自上一次快照
+	require.Len(t, changes, 1)
+	change := changes[0]
+	assert.Equal(t, window.EventDistribution, change.ChangeType)
+	assert.Equal(t, "X", change.TokenSymbol)
+	assert.Equal(t, uint8(6), change.TokenDecimals)
+	assert.Equal(t, uint64(1000), change.OldBalance)
+	assert.Zero(t, change.NewBalance)
+	assert.Equal(t, time.Hour, change.Window)
+	assert.Equal(t, start, change.WindowStart)
+	assert.Equal(t, uint64(1000), change.Outflow)
+	assert.Equal(t, 2, change.Movements)
+	assert.Equal(t, uint64(42), change.Slot)
+}
+
+func TestCorrelateChanges(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 10, 0, 0, time.UTC)
+	correlator := correlate.New(2, 15*time.Minute)
+
+	changes := []Change{
+		{WalletAddress: "b", TokenMint: "x", TokenSymbol: "X", TokenDecimals: 6, ChangeType: "new_token", NewBalance: 500, Slot: 7},
+		{
+			WalletAddress: "a", TokenMint: "x", TokenSymbol: "X", TokenDecimals: 6, ChangeType: "balance_change", OldBalance: 1000, NewBalance: 1500,
+			Transactions: []attribution.Attribution{{BlockTime: now.Add(-5 * time.Minute)}},
+		},
+		// 原生 SOL 与非持仓变化不参与关联
+		{WalletAddress: "a", TokenMint: NativeSOLMint, TokenProgram: ProgramNative, ChangeType: "balance_change", OldBalance: 10, NewBalance: 5},
+		{WalletAddress: "b", TokenMint: NativeSOLMint, TokenProgram: ProgramNative, ChangeType: "balance_change", OldBalance: 10, NewBalance: 5},
+		{WalletAddress: "a", TokenMint: "x", ChangeType: "account_state"},
+		{WalletAddress: "c", TokenMint: "x", ChangeType: "account_state"},
+	}
+
+	clusters := CorrelateChanges(correlator, changes, now)
+	require.Len(t, clusters, 1)
+	cluster := clusters[0]
+	assert.Equal(t, ChangeCoordinatedBuy, cluster.ChangeType)
+	assert.Empty(t, cluster.WalletAddress)
+	assert.Equal(t, "x", cluster.TokenMint)
+	assert.Equal(t, "X", cluster.TokenSymbol)
+	assert.Equal(t, uint64(7), cluster.Slot)
+	assert.Equal(t, 15*time.Minute, cluster.Window)
+
+	// 归因交易的区块时间决定先后顺序
+	require.Len(t, cluster.Participants, 2)
+	assert.Equal(t, "a", cluster.Participants[0].Wallet)
+	assert.Equal(t, now.Add(-5*time.Minute), cluster.WindowStart)
+	assert.Equal(t, "b", cluster.Participants[1].Wallet)
+	assert.Equal(t, now, cluster.Participants[1].Time)
+
+	// 新旧余额为参与钱包的合计
+	assert.Equal(t, uint64(1000), cluster.OldBalance)
+	assert.Equal(t, uint64(2000), cluster.NewBalance)
+	assert.Equal(t, 100.0, cluster.ChangePercent)
+
+	assert.Empty(t, CorrelateChanges(correlator, changes[2:], now.Add(time.Minute)))
+}
+
+func TestTokenAccountInfoFlags(t *testing.T) {
+	info := TokenAccountInfo{
+		Extensions:     &token2022.AccountExtensions{NonTransferable: true, MemoTransferRequired: true},
+		MintExtensions: &token2022.MintExtensions{Types: []string{"transfer_fee_config", "non_transferable"}, TransferFeeBasisPoints: 100, NonTransferable: true},
+	}
+	assert.Equal(t, []string{"non-transferable", "memo-required", "transfer-fee:100bps"}, info.Flags())
+	assert.Empty(t, TokenAccountInfo{}.Flags())
+}
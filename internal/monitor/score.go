@@ -0,0 +1,78 @@
+package monitor
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/accursedgalaxy/insider-monitor/internal/score"
+)
+
+// UpdateScores 根据本次扫描的持仓与检测到的变化更新各钱包的 InsiderScore，
+// 并将评分写入对应的变化。应在 AttributeChanges 之后调用，以便使用交易的区块时间
+func (w *WalletMonitor) UpdateScores(ctx context.Context, data map[string]*WalletData, changes []Change) {
+	now := time.Now()
+
+	events := make(map[string][]score.Event)
+	for _, change := range changes {
+		var kind string
+		switch {
+		case change.ChangeType == "new_token":
+			kind = score.EventEntry
+		case change.ChangeType == "balance_change" && change.NewBalance > change.OldBalance:
+			kind = score.EventIncrease
+		case change.ChangeType == "token_exit":
+			kind = score.EventExit
+		default:
+			continue
+		}
+		events[change.WalletAddress] = append(events[change.WalletAddress], score.Event{
+			Mint: change.TokenMint,
+			Kind: kind,
+			Time: changeTime(change, now),
+		})
+	}
+
+	results := make(map[string]score.Result, len(data))
+	for walletAddr, walletData := range data {
+		holdings := make([]score.Holding, 0, len(walletData.TokenAccounts))
+		for mint, info := range walletData.TokenAccounts {
+			if info.Program == ProgramNative {
+				continue
+			}
+			holdings = append(holdings, score.Holding{
+				Mint:        mint,
+				USDPrice:    info.USDPrice,
+				SupplyShare: info.SupplyShare,
+			})
+		}
+		results[walletAddr] = w.scorer.Observe(ctx, walletAddr, holdings, events[walletAddr], now)
+	}
+	if err := w.scorer.Save(); err != nil {
+		log.Printf("⚠️  Warning: failed to save insider scores: %v", err)
+	}
+
+	for i := range changes {
+		if result, exists := results[changes[i].WalletAddress]; exists {
+			changes[i].InsiderScore = result.Score
+		} else if result, exists := w.scorer.Get(changes[i].WalletAddress); exists {
+			changes[i].InsiderScore = result.Score
+		}
+		if launch, found := w.scorer.LaunchTime(changes[i].TokenMint); found {
+			changes[i].TokenLaunch = launch
+		}
+	}
+}
+
+// InsiderScore 返回钱包最近一次的评分
+func (w *WalletMonitor) InsiderScore(walletAddr string) (score.Result, bool) {
+	return w.scorer.Get(walletAddr)
+}
+
+// changeTime 返回变化发生的时间：优先使用最早一笔归因交易的区块时间
+func changeTime(change Change, fallback time.Time) time.Time {
+	if n := len(change.Transactions); n > 0 && !change.Transactions[n-1].BlockTime.IsZero() {
+		return change.Transactions[n-1].BlockTime
+	}
+	return fallback
+}
@@ -0,0 +1,85 @@
+package monitor
+
+import (
+	"sort"
+	"time"
+)
+
+// 连续失败达到该次数时发出 wallet_failing 告警
+const DefaultWalletFailureThreshold = 3
+
+// ScanResult 为一次扫描的结果，成功的钱包数据与失败原因分开记录
+type ScanResult struct {
+	Wallets map[string]*WalletData
+	Errors  map[string]error
+}
+
+// AllFailed 判断是否所有钱包都扫描失败
+func (r *ScanResult) AllFailed() bool {
+	return len(r.Wallets) == 0 && len(r.Errors) > 0
+}
+
+// WalletStatus 记录单个钱包的扫描健康状况
+type WalletStatus struct {
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	TotalFailures       int       `json:"total_failures"`
+	LastError           string    `json:"last_error,omitempty"`
+	LastFailure         time.Time `json:"last_failure,omitempty"`
+	LastSuccess         time.Time `json:"last_success,omitempty"`
+}
+
+// Staleness 返回钱包数据距上一次成功扫描的时长，从未成功时返回 0
+func (s *WalletStatus) Staleness(now time.Time) time.Duration {
+	if s.LastSuccess.IsZero() {
+		return 0
+	}
+	return now.Sub(s.LastSuccess)
+}
+
+// UpdateWalletStatus 根据扫描结果更新各钱包状态，
+// 并为连续失败次数刚达到 threshold 的钱包返回 wallet_failing 变化
+func UpdateWalletStatus(statuses map[string]*WalletStatus, result *ScanResult, threshold int, now time.Time) []Change {
+	if threshold <= 0 {
+		threshold = DefaultWalletFailureThreshold
+	}
+
+	for walletAddr := range result.Wallets {
+		status := statusFor(statuses, walletAddr)
+		status.ConsecutiveFailures = 0
+		status.LastSuccess = now
+	}
+
+	var changes []Change
+	for walletAddr, err := range result.Errors {
+		status := statusFor(statuses, walletAddr)
+		status.ConsecutiveFailures++
+		status.TotalFailures++
+		status.LastError = err.Error()
+		status.LastFailure = now
+
+		// 只在刚达到阈值时告警一次，恢复后重新计数
+		if status.ConsecutiveFailures == threshold {
+			changes = append(changes, Change{
+				WalletAddress: walletAddr,
+				ChangeType:    "wallet_failing",
+				FailureCount:  status.ConsecutiveFailures,
+				LastError:     status.LastError,
+				LastSuccess:   status.LastSuccess,
+			})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].WalletAddress < changes[j].WalletAddress
+	})
+	return changes
+}
+
+func statusFor(statuses map[string]*WalletStatus, walletAddr string) *WalletStatus {
+	status, exists := statuses[walletAddr]
+	if !exists {
+		status = &WalletStatus{}
+		statuses[walletAddr] = status
+	}
+	return status
+}
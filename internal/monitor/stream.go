@@ -0,0 +1,356 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/accursedgalaxy/insider-monitor/internal/stream"
+	"github.com/accursedgalaxy/insider-monitor/internal/token2022"
+	"github.com/gagliardetto/solana-go"
+)
+
+// 以下常量用于流式订阅的重连配置
+const (
+	streamInitialBackoff = 2 * time.Second
+	streamMaxBackoff     = time.Minute
+	streamMaxFailures    = 5 // 连续失败达到该次数后放弃，由调用方回退到轮询
+	// 需要完整同步的钱包在该延迟后同步一次，期间同一钱包的多条通知合并，避免繁忙钱包引发扫描风暴
+	streamResyncDelay = 2 * time.Second
+	// 未指定时价格的刷新间隔，流式更新沿用快照中的价格，需要定期刷新
+	streamPriceInterval = time.Minute
+)
+
+// UpdateHandler 在流式更新使某个钱包的数据发生变化时被调用。
+// oldData 为 nil 表示该钱包此前没有数据（新加入监控）
+type UpdateHandler func(walletAddr string, oldData, newData *WalletData)
+
+// streamTarget 描述一个订阅对应的钱包或代币账户
+type streamTarget struct {
+	wallet  solana.PublicKey
+	account string // 代币账户地址，为空表示钱包本身
+}
+
+// Streamer 通过 RPC WebSocket 订阅钱包及其代币账户的变化
+type Streamer struct {
+	monitor        *WalletMonitor
+	url            string
+	commitment     string
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	maxFailures    int
+	resyncDelay    time.Duration
+	priceInterval  time.Duration
+	applyPrices    func(map[string]*WalletData) // 获取并写入价格，测试中可替换
+	// 启动时已有历史数据，此后首次出现的钱包需要通知基线
+	notifyNewWallets bool
+}
+
+// NewStreamer 创建一个连接到指定 WebSocket 端点的流式订阅器，持仓价格每 priceInterval 刷新一次
+func (w *WalletMonitor) NewStreamer(wsURL string, priceInterval time.Duration) *Streamer {
+	if priceInterval <= 0 {
+		priceInterval = streamPriceInterval
+	}
+	return &Streamer{
+		monitor:        w,
+		url:            wsURL,
+		commitment:     string(w.commitment),
+		initialBackoff: streamInitialBackoff,
+		maxBackoff:     streamMaxBackoff,
+		maxFailures:    streamMaxFailures,
+		resyncDelay:    streamResyncDelay,
+		priceInterval:  priceInterval,
+		applyPrices:    w.applyPrices,
+	}
+}
+
+// streamSession 保存一次连接内的订阅状态
+type streamSession struct {
+	client   *stream.Client
+	targets  map[uint64]streamTarget
+	accounts map[string]tokenAccountRef // 代币账户地址 -> 最新状态
+	wallets  map[string]solana.PublicKey
+}
+
+// Run 持续订阅并将更新应用到 state（就地修改），断线后按指数回退重新订阅。
+// 连续失败达到上限时返回错误，调用方应回退到轮询模式
+func (s *Streamer) Run(ctx context.Context, state map[string]*WalletData, handle UpdateHandler) error {
+	backoff := s.initialBackoff
+	failures := 0
+
+	// 与轮询模式一致：没有任何历史数据时首次同步仅作为基线，不发出告警
+	s.notifyNewWallets = len(state) > 0
+
+	for {
+		established, err := s.runSession(ctx, state, handle)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		// 成功建立过订阅的连接断开时重置计数
+		if established {
+			failures = 0
+			backoff = s.initialBackoff
+		}
+		failures++
+		if failures >= s.maxFailures {
+			return fmt.Errorf("websocket unavailable after %d attempts: %w", failures, err)
+		}
+
+		log.Printf("⚠️  WebSocket stream disconnected: %v, resubscribing in %v", err, backoff)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		// 指数回退并设置最大值
+		backoff *= 2
+		if backoff > s.maxBackoff {
+			backoff = s.maxBackoff
+		}
+	}
+}
+
+// runSession 建立连接、订阅全部钱包并处理通知，直到连接断开
+func (s *Streamer) runSession(ctx context.Context, state map[string]*WalletData, handle UpdateHandler) (bool, error) {
+	client, err := stream.Dial(ctx, s.url)
+	if err != nil {
+		return false, err
+	}
+	defer client.Close()
+
+	session := &streamSession{
+		client:   client,
+		targets:  make(map[uint64]streamTarget),
+		accounts: make(map[string]tokenAccountRef),
+		wallets:  make(map[string]solana.PublicKey),
+	}
+
+	for _, wallet := range s.monitor.wallets {
+		subID, err := client.AccountSubscribe(ctx, wallet.String(), s.commitment)
+		if err != nil {
+			return false, fmt.Errorf("failed to subscribe wallet %s: %w", wallet.String(), err)
+		}
+		session.targets[subID] = streamTarget{wallet: wallet}
+		session.wallets[wallet.String()] = wallet
+
+		// 重新同步钱包，以弥补断线期间错过的变化并订阅其代币账户
+		if err := s.resync(ctx, session, wallet, state, handle); err != nil {
+			return false, err
+		}
+	}
+
+	log.Printf("📡 Streaming updates for %d wallets and %d token accounts", len(session.wallets), len(session.accounts))
+
+	// 等待完整同步的钱包，resyncTimer 在有待同步钱包时触发
+	pendingResync := make(map[string]solana.PublicKey)
+	var resyncTimer <-chan time.Time
+
+	// 与轮询模式每次扫描刷新价格一致，定期刷新全部持仓的价格
+	priceTicker := time.NewTicker(s.priceInterval)
+	defer priceTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return true, ctx.Err()
+		case <-client.Done():
+			return true, client.Err()
+		case notification := <-client.Notifications():
+			target, exists := session.targets[notification.Subscription]
+			if !exists {
+				continue
+			}
+			needsResync, err := s.apply(session, target, notification, state, handle)
+			if err != nil {
+				log.Printf("⚠️  Warning: failed to apply stream update for %s: %v", target.wallet.String(), err)
+			}
+			if needsResync {
+				pendingResync[target.wallet.String()] = target.wallet
+				if resyncTimer == nil {
+					resyncTimer = time.After(s.resyncDelay)
+				}
+			}
+		case <-resyncTimer:
+			resyncTimer = nil
+			for walletAddr, wallet := range pendingResync {
+				delete(pendingResync, walletAddr)
+				if err := s.resync(ctx, session, wallet, state, handle); err != nil {
+					log.Printf("⚠️  Warning: failed to resync %s: %v", walletAddr, err)
+				}
+			}
+		case <-priceTicker.C:
+			s.refreshPrices(state, handle)
+		}
+	}
+}
+
+// refreshPrices 重新获取全部钱包持仓的价格，并通知价格发生变化的钱包
+func (s *Streamer) refreshPrices(state map[string]*WalletData, handle UpdateHandler) {
+	updated := make(map[string]*WalletData, len(state))
+	for walletAddr, data := range state {
+		if data != nil {
+			updated[walletAddr] = data.clone()
+		}
+	}
+	s.applyPrices(updated)
+
+	for walletAddr, newData := range updated {
+		oldData := state[walletAddr]
+		if !pricesChanged(oldData, newData) {
+			continue
+		}
+		state[walletAddr] = newData
+		handle(walletAddr, oldData, newData)
+	}
+}
+
+// pricesChanged 判断两份钱包数据中是否有代币的价格不同
+func pricesChanged(oldData, newData *WalletData) bool {
+	for mint, info := range newData.TokenAccounts {
+		if oldInfo := oldData.TokenAccounts[mint]; oldInfo.USDPrice != info.USDPrice {
+			return true
+		}
+	}
+	return false
+}
+
+// apply 将一条账户通知应用到钱包数据，无法仅凭通知更新时返回 true，由调用方稍后完整同步该钱包
+func (s *Streamer) apply(session *streamSession, target streamTarget, notification stream.AccountNotification, state map[string]*WalletData, handle UpdateHandler) (bool, error) {
+	// 钱包本身的 lamports 变化通常伴随代币账户的创建或关闭，需要完整同步
+	if target.account == "" {
+		return true, nil
+	}
+
+	ref := session.accounts[target.account]
+	// 账户被关闭时数据为空，视为余额清零
+	ref.Amount = 0
+	ref.Delegate = ""
+	ref.DelegatedAmount = 0
+	ref.CloseAuthority = ""
+	if len(notification.Data) > 0 {
+		tokenAccount, _, err := token2022.DecodeAccount(notification.Data)
+		if err != nil {
+			return false, fmt.Errorf("failed to decode token account %s: %w", target.account, err)
+		}
+		ref.update(tokenAccount)
+	}
+	session.accounts[target.account] = ref
+
+	walletAddr := target.wallet.String()
+	oldData := state[walletAddr]
+	if oldData == nil || ref.Mint == NativeSOLMint {
+		return true, nil
+	}
+
+	// 汇总该钱包在此铸币下所有代币账户的余额
+	var total uint64
+	var accounts []TokenAccountDetail
+	for _, account := range session.accounts {
+		if account.Mint == ref.Mint && account.Owner == walletAddr && account.Amount > 0 {
+			total += account.Amount
+			accounts = append(accounts, account.detail())
+		}
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		return accounts[i].Address < accounts[j].Address
+	})
+
+	info, held := oldData.TokenAccounts[ref.Mint]
+	if !held {
+		if total == 0 || !s.monitor.shouldIncludeToken(ref.Mint) {
+			return false, nil
+		}
+		// 新出现的持仓需要铸币信息与元数据，完整同步一次
+		return true, nil
+	}
+
+	newData := oldData.clone()
+	if total == 0 {
+		delete(newData.TokenAccounts, ref.Mint)
+	} else {
+		info.Balance = total
+		info.Accounts = accounts
+		info.LastUpdated = time.Now()
+		info.USDValue = info.UIAmount() * info.USDPrice
+		if info.Supply > 0 {
+			info.SupplyShare = float64(total) / float64(info.Supply) * 100.0
+		}
+		newData.TokenAccounts[ref.Mint] = info
+	}
+	newData.LastScanned = time.Now()
+	newData.Slot = notification.Slot
+	newData.Commitment = s.commitment
+
+	state[walletAddr] = newData
+	handle(walletAddr, oldData, newData)
+	return false, nil
+}
+
+// resync 重新扫描钱包，订阅新出现的代币账户并通知变化
+func (s *Streamer) resync(ctx context.Context, session *streamSession, wallet solana.PublicKey, state map[string]*WalletData, handle UpdateHandler) error {
+	newData, refs, err := s.monitor.scanWallet(ctx, wallet)
+	if err != nil {
+		return err
+	}
+
+	walletAddr := wallet.String()
+	oldData := state[walletAddr]
+
+	// 沿用旧快照中的价格，仅在出现新代币时请求价格，其余价格由 refreshPrices 定期刷新
+	if missing := carryPrices(oldData, newData); missing {
+		s.applyPrices(map[string]*WalletData{walletAddr: newData})
+	}
+
+	for _, ref := range refs {
+		if _, subscribed := session.accounts[ref.Address]; !subscribed {
+			subID, err := session.client.AccountSubscribe(ctx, ref.Address, s.commitment)
+			if err != nil {
+				return fmt.Errorf("failed to subscribe token account %s: %w", ref.Address, err)
+			}
+			session.targets[subID] = streamTarget{wallet: wallet, account: ref.Address}
+		}
+		session.accounts[ref.Address] = ref
+	}
+
+	state[walletAddr] = newData
+	if oldData != nil || s.notifyNewWallets {
+		handle(walletAddr, oldData, newData)
+	}
+	return nil
+}
+
+// carryPrices 将旧快照中的价格复制到新数据，返回是否存在缺少价格的代币
+func carryPrices(oldData, newData *WalletData) bool {
+	missing := false
+	for mint, info := range newData.TokenAccounts {
+		// 小数位未知的持仓不计算价值
+		if info.Unresolved {
+			continue
+		}
+		if oldData != nil {
+			if oldInfo, exists := oldData.TokenAccounts[mint]; exists && oldInfo.USDPrice > 0 {
+				info.USDPrice = oldInfo.USDPrice
+				info.ConfidenceLevel = oldInfo.ConfidenceLevel
+				info.USDValue = info.UIAmount() * info.USDPrice
+				newData.TokenAccounts[mint] = info
+				continue
+			}
+		}
+		missing = true
+	}
+	return missing
+}
+
+// clone 返回钱包数据的副本，避免修改旧快照
+func (d *WalletData) clone() *WalletData {
+	copied := *d
+	copied.TokenAccounts = make(map[string]TokenAccountInfo, len(d.TokenAccounts))
+	for mint, info := range d.TokenAccounts {
+		copied.TokenAccounts[mint] = info
+	}
+	return &copied
+}
@@ -0,0 +1,461 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/accursedgalaxy/insider-monitor/internal/authority"
+	"github.com/accursedgalaxy/insider-monitor/internal/metadata"
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRPC 同时模拟 HTTP JSON-RPC 与 WebSocket 订阅接口
+type fakeRPC struct {
+	t             *testing.T
+	wallet        solana.PublicKey
+	tokenAccount  solana.PublicKey
+	mint          solana.PublicKey
+	amount        atomic.Uint64
+	connections   atomic.Int32
+	dropFirst     bool          // 第一条连接在订阅完成后立即断开
+	delay         time.Duration // 除 getSlot 外每个 HTTP 请求的处理延迟
+	failWallet    string        // 对该钱包的 getBalance 请求返回错误
+	extraAccount  solana.PublicKey
+	extraAmount   uint64           // 非零时额外返回一个持有同一铸币的辅助代币账户
+	mintAuthority solana.PublicKey // 非零时作为铸币的铸币权限
+	inFlight      atomic.Int32
+	peakInFlight  atomic.Int32
+	balanceCalls  atomic.Int32
+	hideMint      atomic.Bool // 为 true 时铸币账户返回 null，铸币信息无法解析
+
+	// 非空时作为以 finalized 承诺级别查询到的代币余额
+	finalizedAmount *uint64
+	// 签名 -> 确认状态，未列出的签名返回 null
+	signatureStatuses map[string]string
+
+	mutex      sync.Mutex
+	subscribed chan *websocket.Conn
+	nextSubID  uint64
+	subIDs     map[string]uint64
+}
+
+func newFakeRPC(t *testing.T) *fakeRPC {
+	f := &fakeRPC{
+		t:            t,
+		wallet:       solana.NewWallet().PublicKey(),
+		tokenAccount: solana.NewWallet().PublicKey(),
+		mint:         solana.NewWallet().PublicKey(),
+		subscribed:   make(chan *websocket.Conn, 4),
+		subIDs:       make(map[string]uint64),
+	}
+	f.amount.Store(1000)
+	return f
+}
+
+func (f *fakeRPC) encodeTokenAccount(amount uint64) string {
+	return f.encodeAccount(token.Account{
+		Mint:   f.mint,
+		Owner:  f.wallet,
+		Amount: amount,
+		State:  token.Initialized,
+	})
+}
+
+func (f *fakeRPC) encodeAccount(account token.Account) string {
+	var buf bytes.Buffer
+	require.NoError(f.t, bin.NewBinEncoder(&buf).Encode(account))
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+// encodeExtraAccount 编码被冻结且设置了委托的辅助代币账户
+func (f *fakeRPC) encodeExtraAccount() string {
+	delegate := f.wallet
+	return f.encodeAccount(token.Account{
+		Mint:            f.mint,
+		Owner:           f.wallet,
+		Amount:          f.extraAmount,
+		Delegate:        &delegate,
+		DelegatedAmount: f.extraAmount,
+		State:           token.Frozen,
+	})
+}
+
+func (f *fakeRPC) encodeMint() string {
+	mint := token.Mint{
+		Supply:        1_000_000,
+		Decimals:      6,
+		IsInitialized: true,
+	}
+	if !f.mintAuthority.IsZero() {
+		mint.MintAuthority = &f.mintAuthority
+	}
+
+	var buf bytes.Buffer
+	require.NoError(f.t, bin.NewBinEncoder(&buf).Encode(mint))
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func (f *fakeRPC) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if websocket.IsWebSocketUpgrade(r) {
+		f.serveWebSocket(w, r)
+		return
+	}
+
+	var req struct {
+		ID     json.RawMessage   `json:"id"`
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+	require.NoError(f.t, json.NewDecoder(r.Body).Decode(&req))
+
+	if req.Method != "getSlot" {
+		current := f.inFlight.Add(1)
+		defer f.inFlight.Add(-1)
+		for {
+			peak := f.peakInFlight.Load()
+			if current <= peak || f.peakInFlight.CompareAndSwap(peak, current) {
+				break
+			}
+		}
+
+		select {
+		case <-time.After(f.delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if req.Method == "getBalance" {
+		f.balanceCalls.Add(1)
+	}
+	if req.Method == "getBalance" && f.failWallet != "" && strings.Contains(string(req.Params[0]), f.failWallet) {
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(f.t, json.NewEncoder(w).Encode(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      req.ID,
+			"error":   map[string]interface{}{"code": -32000, "message": "node is unhealthy"},
+		}))
+		return
+	}
+
+	context := map[string]interface{}{"slot": 100}
+	var result interface{}
+	switch req.Method {
+	case "getSlot":
+		result = 100
+	case "getBalance":
+		result = map[string]interface{}{"context": context, "value": 0}
+	case "getTokenAccountsByOwner":
+		accounts := []interface{}{}
+		amount := f.amount.Load()
+		if f.finalizedAmount != nil && strings.Contains(string(req.Params[2]), "finalized") {
+			amount = *f.finalizedAmount
+		}
+		if strings.Contains(string(req.Params[1]), solana.TokenProgramID.String()) {
+			accounts = append(accounts, map[string]interface{}{
+				"pubkey": f.tokenAccount.String(),
+				"account": map[string]interface{}{
+					"data":     []string{f.encodeTokenAccount(amount), "base64"},
+					"lamports": 2039280,
+					"owner":    solana.TokenProgramID.String(),
+				},
+			})
+			if f.extraAmount > 0 {
+				accounts = append(accounts, map[string]interface{}{
+					"pubkey": f.extraAccount.String(),
+					"account": map[string]interface{}{
+						"data":     []string{f.encodeExtraAccount(), "base64"},
+						"lamports": 2039280,
+						"owner":    solana.TokenProgramID.String(),
+					},
+				})
+			}
+		}
+		result = map[string]interface{}{"context": context, "value": accounts}
+	case "getMultipleAccounts":
+		var keys []string
+		require.NoError(f.t, json.Unmarshal(req.Params[0], &keys))
+		values := make([]interface{}, len(keys))
+		for i, key := range keys {
+			if key == f.mint.String() && !f.hideMint.Load() {
+				values[i] = map[string]interface{}{
+					"data":     []string{f.encodeMint(), "base64"},
+					"lamports": 1461600,
+					"owner":    solana.TokenProgramID.String(),
+				}
+			}
+		}
+		result = map[string]interface{}{"context": context, "value": values}
+	case "getSignatureStatuses":
+		var signatures []string
+		require.NoError(f.t, json.Unmarshal(req.Params[0], &signatures))
+		values := make([]interface{}, len(signatures))
+		for i, signature := range signatures {
+			if status, exists := f.signatureStatuses[signature]; exists {
+				values[i] = map[string]interface{}{"slot": 100, "confirmationStatus": status, "err": nil}
+			}
+		}
+		result = map[string]interface{}{"context": context, "value": values}
+	default:
+		f.t.Errorf("unexpected RPC method %s", req.Method)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	require.NoError(f.t, json.NewEncoder(w).Encode(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      req.ID,
+		"result":  result,
+	}))
+}
+
+func (f *fakeRPC) serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+	require.NoError(f.t, err)
+	defer conn.Close()
+	connection := f.connections.Add(1)
+
+	for {
+		var req struct {
+			ID     uint64        `json:"id"`
+			Method string        `json:"method"`
+			Params []interface{} `json:"params"`
+		}
+		if err := conn.ReadJSON(&req); err != nil {
+			return
+		}
+
+		account := req.Params[0].(string)
+		f.mutex.Lock()
+		f.nextSubID++
+		f.subIDs[account] = f.nextSubID
+		subID := f.nextSubID
+		f.mutex.Unlock()
+
+		if err := conn.WriteJSON(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": subID}); err != nil {
+			return
+		}
+
+		if account == f.tokenAccount.String() {
+			if f.dropFirst && connection == 1 {
+				return
+			}
+			f.subscribed <- conn
+		}
+	}
+}
+
+func (f *fakeRPC) notifyTokenAccount(conn *websocket.Conn, amount uint64) {
+	f.mutex.Lock()
+	subID := f.subIDs[f.tokenAccount.String()]
+	f.mutex.Unlock()
+
+	f.amount.Store(amount)
+	require.NoError(f.t, conn.WriteJSON(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  "accountNotification",
+		"params": map[string]interface{}{
+			"subscription": subID,
+			"result": map[string]interface{}{
+				"context": map[string]interface{}{"slot": 101},
+				"value": map[string]interface{}{
+					"data":     []string{f.encodeTokenAccount(amount), "base64"},
+					"lamports": 2039280,
+					"owner":    solana.TokenProgramID.String(),
+				},
+			},
+		},
+	}))
+}
+
+// notifyWallet 推送钱包本身的 lamports 变化
+func (f *fakeRPC) notifyWallet(conn *websocket.Conn, lamports uint64) {
+	f.mutex.Lock()
+	subID := f.subIDs[f.wallet.String()]
+	f.mutex.Unlock()
+
+	require.NoError(f.t, conn.WriteJSON(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  "accountNotification",
+		"params": map[string]interface{}{
+			"subscription": subID,
+			"result": map[string]interface{}{
+				"context": map[string]interface{}{"slot": 101},
+				"value": map[string]interface{}{
+					"data":     []string{"", "base64"},
+					"lamports": lamports,
+					"owner":    solana.SystemProgramID.String(),
+				},
+			},
+		},
+	}))
+}
+
+func newStreamingMonitor(t *testing.T, f *fakeRPC, serverURL string) (*WalletMonitor, map[string]*WalletData) {
+	w, err := NewWalletMonitor(serverURL, []string{f.wallet.String()}, nil)
+	require.NoError(t, err)
+	w.metadata = metadata.NewResolver(w.client, t.TempDir(), false)
+	w.authorities = authority.NewTracker(t.TempDir())
+
+	state := map[string]*WalletData{
+		f.wallet.String(): {
+			WalletAddress: f.wallet.String(),
+			TokenAccounts: map[string]TokenAccountInfo{
+				f.mint.String(): {Balance: 1000, Decimals: 6, USDPrice: 1.0},
+			},
+		},
+	}
+	return w, state
+}
+
+func runStreamer(t *testing.T, s *Streamer, state map[string]*WalletData) (<-chan *WalletData, context.CancelFunc) {
+	updates := make(chan *WalletData, 16)
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		_ = s.Run(ctx, state, func(walletAddr string, oldData, newData *WalletData) {
+			updates <- newData
+		})
+	}()
+	return updates, cancel
+}
+
+func waitForBalance(t *testing.T, updates <-chan *WalletData, mint string, balance uint64) *WalletData {
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case data := <-updates:
+			if data.TokenAccounts[mint].Balance == balance {
+				return data
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for balance %d", balance)
+		}
+	}
+}
+
+func TestStreamerAppliesAccountNotifications(t *testing.T) {
+	f := newFakeRPC(t)
+	server := httptest.NewServer(f)
+	defer server.Close()
+
+	w, state := newStreamingMonitor(t, f, server.URL)
+	updates, cancel := runStreamer(t, w.NewStreamer("ws"+strings.TrimPrefix(server.URL, "http"), time.Minute), state)
+	defer cancel()
+
+	conn := <-f.subscribed
+	f.notifyTokenAccount(conn, 400)
+
+	data := waitForBalance(t, updates, f.mint.String(), 400)
+	info := data.TokenAccounts[f.mint.String()]
+	assert.Equal(t, uint8(6), info.Decimals)
+	assert.InDelta(t, 0.0004, info.USDValue, 1e-9)
+}
+
+func TestStreamerResubscribesAfterDisconnect(t *testing.T) {
+	f := newFakeRPC(t)
+	f.dropFirst = true
+	server := httptest.NewServer(f)
+	defer server.Close()
+
+	w, state := newStreamingMonitor(t, f, server.URL)
+	s := w.NewStreamer("ws"+strings.TrimPrefix(server.URL, "http"), time.Minute)
+	s.initialBackoff = 10 * time.Millisecond
+	updates, cancel := runStreamer(t, s, state)
+	defer cancel()
+
+	conn := <-f.subscribed
+	assert.Equal(t, int32(2), f.connections.Load())
+
+	f.notifyTokenAccount(conn, 2500)
+	waitForBalance(t, updates, f.mint.String(), 2500)
+}
+
+func TestStreamerDebouncesWalletResyncs(t *testing.T) {
+	f := newFakeRPC(t)
+	server := httptest.NewServer(f)
+	defer server.Close()
+
+	w, state := newStreamingMonitor(t, f, server.URL)
+	s := w.NewStreamer("ws"+strings.TrimPrefix(server.URL, "http"), time.Minute)
+	s.resyncDelay = 100 * time.Millisecond
+	updates, cancel := runStreamer(t, s, state)
+	defer cancel()
+
+	conn := <-f.subscribed
+	waitForBalance(t, updates, f.mint.String(), 1000)
+	before := f.balanceCalls.Load()
+
+	// 繁忙钱包的多条 lamports 通知只触发一次完整同步
+	f.amount.Store(700)
+	for lamports := uint64(1); lamports <= 20; lamports++ {
+		f.notifyWallet(conn, lamports)
+	}
+	waitForBalance(t, updates, f.mint.String(), 700)
+	time.Sleep(3 * s.resyncDelay)
+	assert.Equal(t, before+1, f.balanceCalls.Load())
+}
+
+func TestStreamerGivesUpWhenSocketUnavailable(t *testing.T) {
+	w, err := NewWalletMonitor("http://127.0.0.1:1", []string{"DYw8jCTfwHNRJhhmFcbXvVDTqWMEVFBX6ZKUmG5CNSKK"}, nil)
+	require.NoError(t, err)
+
+	s := w.NewStreamer("ws://127.0.0.1:1", time.Minute)
+	s.initialBackoff = time.Millisecond
+	s.maxFailures = 2
+
+	err = s.Run(context.Background(), map[string]*WalletData{}, func(string, *WalletData, *WalletData) {})
+	assert.ErrorContains(t, err, "websocket unavailable")
+}
+
+func TestStreamerRefreshesPrices(t *testing.T) {
+	f := newFakeRPC(t)
+	server := httptest.NewServer(f)
+	defer server.Close()
+
+	w, state := newStreamingMonitor(t, f, server.URL)
+	s := w.NewStreamer("ws"+strings.TrimPrefix(server.URL, "http"), 50*time.Millisecond)
+	var price atomic.Int64
+	price.Store(1)
+	s.applyPrices = func(results map[string]*WalletData) {
+		for _, walletData := range results {
+			for mint, info := range walletData.TokenAccounts {
+				info.USDPrice = float64(price.Load())
+				info.USDValue = info.UIAmount() * info.USDPrice
+				walletData.TokenAccounts[mint] = info
+			}
+		}
+	}
+	updates, cancel := runStreamer(t, s, state)
+	defer cancel()
+	<-f.subscribed
+
+	// 余额不变时价格变化同样更新持仓价值
+	price.Store(3)
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case data := <-updates:
+			info := data.TokenAccounts[f.mint.String()]
+			if info.USDPrice == 3 {
+				assert.Equal(t, uint64(1000), info.Balance)
+				assert.InDelta(t, 0.003, info.USDValue, 1e-9)
+				return
+			}
+		case <-timeout:
+			t.Fatal("timed out waiting for the price refresh")
+		}
+	}
+}
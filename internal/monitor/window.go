@@ -0,0 +1,69 @@
+package monitor
+
+import (
+	"time"
+
+	"github.com/accursedgalaxy/insider-monitor/internal/config"
+	"github.com/accursedgalaxy/insider-monitor/internal/window"
+)
+
+// ObserveWindows 记录 newData 中各钱包本次扫描的持仓，返回滚动窗口内累计变化越过阈值的
+// accumulation 与 distribution 变化。oldData 用于补全已清仓代币的符号等信息
+func ObserveWindows(state window.State, oldData, newData map[string]*WalletData, windows []config.WindowConfig, now time.Time) []Change {
+	if len(windows) == 0 {
+		return nil
+	}
+
+	settings := make([]window.Window, 0, len(windows))
+	for _, w := range windows {
+		duration, err := time.ParseDuration(w.Duration)
+		if err != nil || duration <= 0 {
+			continue
+		}
+		settings = append(settings, window.Window{Duration: duration, Threshold: w.Threshold, MinUSD: w.MinUSD})
+	}
+
+	var changes []Change
+	for walletAddr, data := range newData {
+		if data == nil {
+			continue
+		}
+		holdings := make(map[string]window.Holding, len(data.TokenAccounts))
+		for mint, info := range data.TokenAccounts {
+			holdings[mint] = window.Holding{Balance: info.Balance, Decimals: info.Decimals, USDPrice: info.USDPrice}
+		}
+
+		for _, event := range state.Observe(walletAddr, holdings, settings, now) {
+			info, held := data.TokenAccounts[event.Mint]
+			if !held {
+				if previous := oldData[walletAddr]; previous != nil {
+					info = previous.TokenAccounts[event.Mint]
+				}
+			}
+			changes = append(changes, Change{
+				WalletAddress: walletAddr,
+				TokenMint:     event.Mint,
+				TokenSymbol:   info.Symbol,
+				TokenName:     info.Name,
+				TokenImage:    info.ImageURI,
+				TokenDecimals: info.Decimals,
+				TokenProgram:  info.Program,
+				TokenFlags:    info.Flags(),
+				USDPrice:      info.USDPrice,
+				SupplyShare:   info.SupplyShare,
+				ChangeType:    event.Type,
+				OldBalance:    event.OldBalance,
+				NewBalance:    event.NewBalance,
+				ChangePercent: event.ChangePercent,
+				Window:        event.Window,
+				WindowStart:   event.Start,
+				Inflow:        event.Inflow,
+				Outflow:       event.Outflow,
+				Movements:     event.Movements,
+				Slot:          data.Slot,
+				Commitment:    data.Commitment,
+			})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,162 @@
+package replay
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/accursedgalaxy/insider-monitor/internal/backfill"
+	"github.com/accursedgalaxy/insider-monitor/internal/monitor"
+)
+
+// 原生 SOL 的小数位，交易中没有 wSOL 账户时无法从余额变化中得到
+const nativeSOLDecimals = 9
+
+// event 为一笔已回填交易对某钱包余额的变化
+type event struct {
+	wallet    string
+	time      time.Time
+	movements []backfill.Movement
+}
+
+// FromHistory 由回填的交易记录重建各钱包在每个模拟扫描时刻的持仓。
+// 以 current（最近一次保存的快照）为终点，减去之后发生的变化得到较早时刻的余额；
+// 扫描每隔 interval 进行一次，只为发生了交易的扫描生成快照，没有交易的扫描不会产生变化。
+// 价格、元数据与权限沿用 current 中的值；原生 SOL 不含手续费，因此会有少量偏差
+func FromHistory(current map[string]*monitor.WalletData, histories []*backfill.History, interval time.Duration) []Frame {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	var events []event
+	decimals := make(map[string]uint8)
+	for _, history := range histories {
+		for _, record := range history.Transactions {
+			if record.Failed || len(record.Movements) == 0 || record.BlockTime.IsZero() {
+				continue
+			}
+			events = append(events, event{wallet: history.Wallet, time: record.BlockTime, movements: record.Movements})
+			for _, movement := range record.Movements {
+				decimals[movement.Mint] = movement.Decimals
+			}
+		}
+	}
+	if len(events) == 0 {
+		return nil
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].time.Before(events[j].time)
+	})
+
+	// 起点余额 = 当前余额 - 全部已记录的变化
+	balances := make(map[string]map[string]int64)
+	touched := make(map[string]map[string]bool)
+	for wallet, data := range current {
+		balances[wallet] = make(map[string]int64)
+		touched[wallet] = make(map[string]bool)
+		if data == nil {
+			continue
+		}
+		for mint, info := range data.TokenAccounts {
+			balances[wallet][mint] = clampInt64(info.Balance)
+		}
+	}
+	for _, e := range events {
+		if balances[e.wallet] == nil {
+			balances[e.wallet] = make(map[string]int64)
+			touched[e.wallet] = make(map[string]bool)
+		}
+		for _, movement := range e.movements {
+			balances[e.wallet][movement.Mint] -= movement.Delta
+			touched[e.wallet][movement.Mint] = true
+		}
+	}
+
+	build := func(wallet string, at time.Time) *monitor.WalletData {
+		return walletAt(current[wallet], wallet, balances[wallet], touched[wallet], decimals, at)
+	}
+
+	// 第一帧为第一笔交易之前的基线
+	baseline := Frame{Time: events[0].time.Truncate(interval), Wallets: make(map[string]*monitor.WalletData)}
+	for wallet := range balances {
+		baseline.Wallets[wallet] = build(wallet, baseline.Time)
+	}
+	frames := []Frame{baseline}
+
+	for i := 0; i < len(events); {
+		// 同一扫描间隔内的交易由该间隔结束时的扫描一并观察到
+		tick := events[i].time.Truncate(interval).Add(interval)
+		changed := make(map[string]bool)
+		for ; i < len(events) && events[i].time.Before(tick); i++ {
+			for _, movement := range events[i].movements {
+				balances[events[i].wallet][movement.Mint] += movement.Delta
+			}
+			changed[events[i].wallet] = true
+		}
+
+		// 未变化的钱包沿用上一帧的数据
+		previous := frames[len(frames)-1].Wallets
+		frame := Frame{Time: tick, Wallets: make(map[string]*monitor.WalletData, len(previous))}
+		for wallet, data := range previous {
+			frame.Wallets[wallet] = data
+		}
+		for wallet := range changed {
+			frame.Wallets[wallet] = build(wallet, tick)
+		}
+		frames = append(frames, frame)
+	}
+	return frames
+}
+
+// walletAt 以 template 为模板生成钱包在 at 时刻的快照，余额为零的持仓视为不存在
+func walletAt(template *monitor.WalletData, wallet string, balances map[string]int64, touched map[string]bool, decimals map[string]uint8, at time.Time) *monitor.WalletData {
+	data := &monitor.WalletData{
+		WalletAddress: wallet,
+		TokenAccounts: make(map[string]monitor.TokenAccountInfo),
+		LastScanned:   at,
+	}
+	if template != nil {
+		data.SOLBalance = template.SOLBalance
+		data.WrappedSOLBalance = template.WrappedSOLBalance
+	}
+
+	for mint, balance := range balances {
+		var info monitor.TokenAccountInfo
+		if template != nil {
+			info = template.TokenAccounts[mint]
+		}
+		if !touched[mint] {
+			if balance > 0 {
+				data.TokenAccounts[mint] = info
+			}
+			continue
+		}
+		if balance <= 0 {
+			continue
+		}
+
+		if info.Symbol == "" && mint == monitor.NativeSOLMint {
+			info.Symbol = "SOL"
+			info.Decimals = nativeSOLDecimals
+		}
+		if recorded, known := decimals[mint]; known && info.Decimals == 0 {
+			info.Decimals = recorded
+		}
+		info.Balance = uint64(balance)
+		info.LastUpdated = at
+		info.Accounts = nil // 单个代币账户的余额无法从交易中重建
+		info.USDValue = info.UIAmount() * info.USDPrice
+		if info.Supply > 0 {
+			info.SupplyShare = float64(info.Balance) / float64(info.Supply) * 100
+		}
+		data.TokenAccounts[mint] = info
+	}
+	return data
+}
+
+func clampInt64(value uint64) int64 {
+	if value > math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return int64(value)
+}
@@ -0,0 +1,69 @@
+package replay
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/accursedgalaxy/insider-monitor/internal/monitor"
+)
+
+// Frame 为某一模拟时刻全部钱包的快照
+type Frame struct {
+	Time    time.Time
+	Wallets map[string]*monitor.WalletData
+}
+
+// Summary 为一次回放的统计
+type Summary struct {
+	Frames  int
+	Changes int
+	ByType  map[string]int
+	Start   time.Time
+	End     time.Time
+}
+
+// Run 按时间顺序比较相邻帧，与实时监控一样以第一帧为静默基线。
+// 每帧检测到的变化连同该帧的模拟时间交给 handle，变化按钱包、铸币与类型排序以便比较不同配置的结果
+func Run(frames []Frame, minChangePercent float64, handle func(now time.Time, changes []monitor.Change)) (Summary, error) {
+	if len(frames) < 2 {
+		return Summary{}, fmt.Errorf("replay needs at least 2 snapshots, got %d", len(frames))
+	}
+
+	sort.SliceStable(frames, func(i, j int) bool {
+		return frames[i].Time.Before(frames[j].Time)
+	})
+
+	summary := Summary{
+		Frames: len(frames),
+		ByType: make(map[string]int),
+		Start:  frames[0].Time,
+		End:    frames[len(frames)-1].Time,
+	}
+	for i := 1; i < len(frames); i++ {
+		changes := monitor.DetectChanges(frames[i-1].Wallets, frames[i].Wallets, minChangePercent)
+		if len(changes) == 0 {
+			continue
+		}
+		sortChanges(changes)
+		for _, change := range changes {
+			summary.ByType[change.ChangeType]++
+		}
+		summary.Changes += len(changes)
+		handle(frames[i].Time, changes)
+	}
+	return summary, nil
+}
+
+func sortChanges(changes []monitor.Change) {
+	sort.SliceStable(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		if a.WalletAddress != b.WalletAddress {
+			return a.WalletAddress < b.WalletAddress
+		}
+		if a.TokenMint != b.TokenMint {
+			return a.TokenMint < b.TokenMint
+		}
+		return a.ChangeType < b.ChangeType
+	})
+}
@@ -0,0 +1,172 @@
+package replay
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/accursedgalaxy/insider-monitor/internal/alerts"
+	"github.com/accursedgalaxy/insider-monitor/internal/backfill"
+	"github.com/accursedgalaxy/insider-monitor/internal/monitor"
+	"github.com/accursedgalaxy/insider-monitor/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func snapshot(at time.Time, balances map[string]map[string]uint64) map[string]*monitor.WalletData {
+	wallets := make(map[string]*monitor.WalletData)
+	for wallet, mints := range balances {
+		data := &monitor.WalletData{
+			WalletAddress: wallet,
+			TokenAccounts: make(map[string]monitor.TokenAccountInfo),
+			LastScanned:   at,
+		}
+		for mint, balance := range mints {
+			data.TokenAccounts[mint] = monitor.TokenAccountInfo{Balance: balance, Decimals: 6, Symbol: mint}
+		}
+		wallets[wallet] = data
+	}
+	return wallets
+}
+
+func TestRunDetectsChangesBetweenFrames(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	frames := []Frame{
+		// 顺序打乱，Run 按时间排序
+		{Time: start.Add(2 * time.Minute), Wallets: snapshot(start, map[string]map[string]uint64{
+			"a": {"x": 150, "y": 10},
+			"b": {"x": 100},
+		})},
+		{Time: start, Wallets: snapshot(start, map[string]map[string]uint64{
+			"a": {"x": 100},
+			"b": {"x": 100},
+		})},
+		{Time: start.Add(time.Minute), Wallets: snapshot(start, map[string]map[string]uint64{
+			"a": {"x": 100},
+			"b": {"x": 100},
+		})},
+	}
+
+	var times []time.Time
+	var changes []monitor.Change
+	summary, err := Run(frames, 0.2, func(now time.Time, detected []monitor.Change) {
+		times = append(times, now)
+		changes = append(changes, detected...)
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []time.Time{start.Add(2 * time.Minute)}, times)
+	require.Len(t, changes, 2)
+	assert.Equal(t, "balance_change", changes[0].ChangeType)
+	assert.Equal(t, "x", changes[0].TokenMint)
+	assert.Equal(t, "new_token", changes[1].ChangeType)
+	assert.Equal(t, 3, summary.Frames)
+	assert.Equal(t, 2, summary.Changes)
+	assert.Equal(t, map[string]int{"balance_change": 1, "new_token": 1}, summary.ByType)
+
+	// 阈值更高时不再产生余额变化
+	summary, err = Run(frames, 60, func(time.Time, []monitor.Change) {})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"new_token": 1}, summary.ByType)
+}
+
+func TestRunNeedsTwoFrames(t *testing.T) {
+	_, err := Run([]Frame{{}}, 0.2, func(time.Time, []monitor.Change) {})
+	assert.Error(t, err)
+}
+
+func TestLoadSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	store := storage.New(dir)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// 与监控写入 ./data 的方式一致：当前数据、每次扫描的快照以及其他状态文件
+	require.NoError(t, store.SaveSnapshot(snapshot(start, map[string]map[string]uint64{"a": {"x": 100}}), start))
+	require.NoError(t, store.SaveSnapshot(snapshot(start.Add(time.Minute), map[string]map[string]uint64{"a": {"x": 150}}), start.Add(time.Minute)))
+	require.NoError(t, store.SaveWalletData(snapshot(start.Add(2*time.Minute), map[string]map[string]uint64{"a": {"x": 200}})))
+	require.NoError(t, store.SaveWalletStatus(map[string]*monitor.WalletStatus{"a": {ConsecutiveFailures: 1}}))
+	require.NoError(t, store.SaveSuppressionState(alerts.SuppressionState{}))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "token_metadata.json"), []byte(`{"x":{"symbol":"X"}}`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("ignored"), 0644))
+
+	// 超过保留时长的快照在保存新快照时被删除
+	expired := start.Add(-storage.SnapshotRetention - time.Hour)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, fmt.Sprintf("wallet_data_%d.json", expired.Unix())), []byte(`{}`), 0644))
+	require.NoError(t, store.SaveSnapshot(snapshot(start, map[string]map[string]uint64{"a": {"x": 100}}), start))
+
+	frames, err := LoadSnapshots(dir)
+	require.NoError(t, err)
+	require.Len(t, frames, 3)
+
+	summary, err := Run(frames, 0.2, func(time.Time, []monitor.Change) {})
+	require.NoError(t, err)
+	assert.Equal(t, 2, summary.Changes)
+	assert.Equal(t, start, summary.Start)
+	assert.Equal(t, start.Add(2*time.Minute), summary.End)
+
+	_, err = LoadSnapshots(filepath.Join(dir, "wallet_data.json"))
+	assert.ErrorContains(t, err, "not a directory")
+}
+
+func TestFromHistory(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	current := map[string]*monitor.WalletData{
+		"a": {
+			WalletAddress: "a",
+			TokenAccounts: map[string]monitor.TokenAccountInfo{
+				"x": {Balance: 300, Decimals: 6, Symbol: "X", USDPrice: 2},
+				"z": {Balance: 50, Decimals: 6, Symbol: "Z"},
+			},
+		},
+		"b": {WalletAddress: "b", TokenAccounts: map[string]monitor.TokenAccountInfo{}},
+	}
+	history := &backfill.History{Wallet: "a", Transactions: map[string]*backfill.Record{
+		// 首次买入，同一分钟内加仓
+		"s1": {BlockTime: start.Add(10 * time.Second), Movements: []backfill.Movement{{Mint: "x", Delta: 100, Decimals: 6}}},
+		"s2": {BlockTime: start.Add(40 * time.Second), Movements: []backfill.Movement{{Mint: "x", Delta: 100, Decimals: 6}}},
+		// 五分钟后再次买入，同时清仓 y
+		"s3": {BlockTime: start.Add(5 * time.Minute), Movements: []backfill.Movement{
+			{Mint: "x", Delta: 100, Decimals: 6},
+			{Mint: "y", Delta: -7, Decimals: 2},
+		}},
+		"failed": {BlockTime: start.Add(6 * time.Minute), Failed: true},
+	}}
+
+	frames := FromHistory(current, []*backfill.History{history}, time.Minute)
+	require.Len(t, frames, 3)
+
+	assert.Equal(t, start, frames[0].Time)
+	assert.Equal(t, start.Add(time.Minute), frames[1].Time)
+	assert.Equal(t, start.Add(6*time.Minute), frames[2].Time)
+
+	// 基线：x 尚未买入，y 仍持有，未受交易影响的 z 保持不变
+	baseline := frames[0].Wallets["a"].TokenAccounts
+	assert.NotContains(t, baseline, "x")
+	assert.Equal(t, uint64(7), baseline["y"].Balance)
+	assert.Equal(t, uint8(2), baseline["y"].Decimals)
+	assert.Equal(t, uint64(50), baseline["z"].Balance)
+	assert.Contains(t, frames[0].Wallets, "b")
+
+	assert.Equal(t, uint64(200), frames[1].Wallets["a"].TokenAccounts["x"].Balance)
+	assert.InDelta(t, 0.0004, frames[1].Wallets["a"].TokenAccounts["x"].USDValue, 1e-9)
+
+	last := frames[2].Wallets["a"].TokenAccounts
+	assert.Equal(t, uint64(300), last["x"].Balance)
+	assert.NotContains(t, last, "y")
+	assert.Same(t, frames[1].Wallets["b"], frames[2].Wallets["b"], "unchanged wallets are shared between frames")
+
+	var types []string
+	_, err := Run(frames, 0.2, func(now time.Time, changes []monitor.Change) {
+		for _, change := range changes {
+			types = append(types, change.ChangeType)
+		}
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"new_token", "balance_change", "token_exit"}, types)
+}
+
+func TestFromHistoryWithoutEvents(t *testing.T) {
+	assert.Nil(t, FromHistory(nil, []*backfill.History{{Wallet: "a"}}, time.Minute))
+}
@@ -0,0 +1,73 @@
+package replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/accursedgalaxy/insider-monitor/internal/monitor"
+)
+
+// LoadSnapshots 读取目录下以 wallet_data.json 格式保存的快照：当前数据 wallet_data.json、
+// 监控每次扫描保存的 wallet_data_<unix>.json 以及 wallet_data_backup_*.json 备份，目录下的其他文件被忽略。
+// 每个快照的时间取其中最晚的扫描时间，缺失时使用文件修改时间
+func LoadSnapshots(dir string) ([]Frame, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshots: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory: replay needs a directory of wallet_data*.json snapshots such as ./data", dir)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot directory: %w", err)
+	}
+	var files []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() && strings.HasPrefix(name, "wallet_data") && strings.HasSuffix(name, ".json") {
+			files = append(files, filepath.Join(dir, name))
+		}
+	}
+
+	var frames []Frame
+	for _, file := range files {
+		frame, err := loadSnapshot(file)
+		if err != nil {
+			return nil, err
+		}
+		frames = append(frames, frame)
+	}
+	return frames, nil
+}
+
+func loadSnapshot(path string) (Frame, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Frame{}, fmt.Errorf("failed to read snapshot %s: %w", path, err)
+	}
+
+	wallets := make(map[string]*monitor.WalletData)
+	if err := json.Unmarshal(content, &wallets); err != nil {
+		return Frame{}, fmt.Errorf("failed to unmarshal snapshot %s: %w", path, err)
+	}
+
+	frame := Frame{Wallets: wallets}
+	for _, data := range wallets {
+		if data != nil && data.LastScanned.After(frame.Time) {
+			frame.Time = data.LastScanned
+		}
+	}
+	if frame.Time.IsZero() {
+		stat, err := os.Stat(path)
+		if err != nil {
+			return Frame{}, fmt.Errorf("failed to read snapshot %s: %w", path, err)
+		}
+		frame.Time = stat.ModTime()
+	}
+	return frame, nil
+}
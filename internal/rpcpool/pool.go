@@ -0,0 +1,427 @@
+package rpcpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+	"golang.org/x/time/rate"
+)
+
+// 以下常量为健康检查与故障转移的默认配置
+const (
+	DefaultProbeInterval = 15 * time.Second
+	DefaultMaxSlotLag    = 50               // 落后最高 slot 超过该值视为不健康
+	DefaultCooldown      = 30 * time.Second // 不健康的端点在冷却期内不参与路由
+	failureThreshold     = 3                // 连续失败达到该次数后标记为不健康
+	requestTimeout       = 30 * time.Second
+	probeTimeout         = 5 * time.Second
+	latencySmoothing     = 0.2 // 延迟指数移动平均的权重
+)
+
+// JSON-RPC 层面表示需要换节点重试的错误码
+const (
+	rpcCodeRateLimited   = 429
+	rpcCodeNodeUnhealthy = -32005
+	rpcCodeInternalError = -32603
+)
+
+// ErrNoEndpoints 表示没有可用的端点
+var ErrNoEndpoints = errors.New("no RPC endpoints configured")
+
+// Endpoint 描述一个 RPC 端点
+type Endpoint struct {
+	URL               string
+	Weight            int     // 加权轮询权重，默认 1
+	RequestsPerSecond float64 // 该端点的速率上限，0 表示不限速
+	Burst             int     // 速率限制的突发量，默认 1
+}
+
+// EndpointStats 为端点的运行统计，URL 已去除查询参数以免泄露 API Key
+type EndpointStats struct {
+	URL       string
+	Weight    int
+	Healthy   bool
+	Slot      uint64
+	SlotLag   uint64
+	Latency   time.Duration // 平均请求延迟
+	Requests  uint64
+	Failures  uint64
+	ErrorRate float64
+	LastError string
+}
+
+type endpoint struct {
+	url           string
+	label         string
+	weight        int
+	currentWeight int
+	client        rpc.JSONRPCClient
+	limiter       *rate.Limiter
+
+	healthy             bool
+	unhealthyUntil      time.Time
+	consecutiveFailures int
+	slot                uint64
+	slotLag             uint64
+	latency             time.Duration
+	requests            uint64
+	failures            uint64
+	lastError           string
+}
+
+// Pool 将请求按权重分发到多个 RPC 端点，并在失败时切换到其他端点。
+// Pool 实现 rpc.JSONRPCClient，可直接传给 rpc.NewWithCustomRPCClient
+type Pool struct {
+	endpoints     []*endpoint
+	probeInterval time.Duration
+	maxSlotLag    uint64
+	cooldown      time.Duration
+	now           func() time.Time
+	mutex         sync.Mutex
+}
+
+// New 创建端点池，所有端点初始视为健康
+func New(endpoints []Endpoint) (*Pool, error) {
+	if len(endpoints) == 0 {
+		return nil, ErrNoEndpoints
+	}
+
+	p := &Pool{
+		probeInterval: DefaultProbeInterval,
+		maxSlotLag:    DefaultMaxSlotLag,
+		cooldown:      DefaultCooldown,
+		now:           time.Now,
+	}
+
+	for _, config := range endpoints {
+		if config.URL == "" {
+			return nil, fmt.Errorf("RPC endpoint URL is required")
+		}
+		weight := config.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		burst := config.Burst
+		if burst <= 0 {
+			burst = 1
+		}
+		limit := rate.Inf
+		if config.RequestsPerSecond > 0 {
+			limit = rate.Limit(config.RequestsPerSecond)
+		}
+
+		p.endpoints = append(p.endpoints, &endpoint{
+			url:    config.URL,
+			label:  redact(config.URL),
+			weight: weight,
+			client: jsonrpc.NewClientWithOpts(config.URL, &jsonrpc.RPCClientOpts{
+				HTTPClient: &http.Client{Timeout: requestTimeout},
+			}),
+			limiter: rate.NewLimiter(limit, burst),
+			healthy: true,
+		})
+	}
+	return p, nil
+}
+
+// Start 在后台定期探测端点健康状况，直到 ctx 被取消
+func (p *Pool) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(p.probeInterval)
+		defer ticker.Stop()
+
+		p.Probe(ctx)
+		for {
+			select {
+			case <-ticker.C:
+				p.Probe(ctx)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Probe 并发查询各端点的 slot 与延迟，落后过多或无法访问的端点标记为不健康
+func (p *Pool) Probe(ctx context.Context) {
+	type probeResult struct {
+		slot    uint64
+		latency time.Duration
+		err     error
+	}
+
+	results := make([]probeResult, len(p.endpoints))
+	var wg sync.WaitGroup
+	for i, e := range p.endpoints {
+		wg.Add(1)
+		go func(i int, e *endpoint) {
+			defer wg.Done()
+			probeCtx, cancel := context.WithTimeout(ctx, probeTimeout)
+			defer cancel()
+
+			start := time.Now()
+			slot, err := rpc.NewWithCustomRPCClient(e.client).GetSlot(probeCtx, rpc.CommitmentProcessed)
+			results[i] = probeResult{slot: slot, latency: time.Since(start), err: err}
+		}(i, e)
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return
+	}
+
+	var highest uint64
+	for _, result := range results {
+		if result.err == nil && result.slot > highest {
+			highest = result.slot
+		}
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for i, e := range p.endpoints {
+		result := results[i]
+		if result.err != nil {
+			e.lastError = result.err.Error()
+			p.markUnhealthy(e, fmt.Sprintf("health probe failed: %v", result.err))
+			continue
+		}
+
+		e.slot = result.slot
+		e.slotLag = highest - result.slot
+		e.observeLatency(result.latency)
+		if e.slotLag > p.maxSlotLag {
+			p.markUnhealthy(e, fmt.Sprintf("%d slots behind", e.slotLag))
+			continue
+		}
+		p.markHealthy(e)
+	}
+}
+
+// Stats 返回各端点的运行统计
+func (p *Pool) Stats() []EndpointStats {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	stats := make([]EndpointStats, 0, len(p.endpoints))
+	for _, e := range p.endpoints {
+		var errorRate float64
+		if e.requests > 0 {
+			errorRate = float64(e.failures) / float64(e.requests)
+		}
+		stats = append(stats, EndpointStats{
+			URL:       e.label,
+			Weight:    e.weight,
+			Healthy:   p.available(e),
+			Slot:      e.slot,
+			SlotLag:   e.slotLag,
+			Latency:   e.latency,
+			Requests:  e.requests,
+			Failures:  e.failures,
+			ErrorRate: errorRate,
+			LastError: e.lastError,
+		})
+	}
+	return stats
+}
+
+// CallForInto 实现 rpc.JSONRPCClient
+func (p *Pool) CallForInto(ctx context.Context, out interface{}, method string, params []interface{}) error {
+	return p.do(ctx, func(client rpc.JSONRPCClient) error {
+		return client.CallForInto(ctx, out, method, params)
+	})
+}
+
+// CallWithCallback 实现 rpc.JSONRPCClient
+func (p *Pool) CallWithCallback(ctx context.Context, method string, params []interface{}, callback func(*http.Request, *http.Response) error) error {
+	return p.do(ctx, func(client rpc.JSONRPCClient) error {
+		return client.CallWithCallback(ctx, method, params, callback)
+	})
+}
+
+// CallBatch 实现 rpc.JSONRPCClient
+func (p *Pool) CallBatch(ctx context.Context, requests jsonrpc.RPCRequests) (jsonrpc.RPCResponses, error) {
+	var responses jsonrpc.RPCResponses
+	err := p.do(ctx, func(client rpc.JSONRPCClient) error {
+		var err error
+		responses, err = client.CallBatch(ctx, requests)
+		return err
+	})
+	return responses, err
+}
+
+// do 依次在不同端点上执行请求，遇到可重试的错误时切换到下一个端点
+func (p *Pool) do(ctx context.Context, call func(client rpc.JSONRPCClient) error) error {
+	tried := make(map[*endpoint]bool, len(p.endpoints))
+	var lastErr error
+
+	for len(tried) < len(p.endpoints) {
+		e := p.next(tried)
+		tried[e] = true
+
+		if err := e.limiter.Wait(ctx); err != nil {
+			return err
+		}
+
+		start := time.Now()
+		err := call(e.client)
+		if ctx.Err() != nil {
+			return err
+		}
+		p.record(e, time.Since(start), err)
+
+		if err == nil || !retryable(err) {
+			return err
+		}
+		lastErr = err
+	}
+
+	return fmt.Errorf("all %d RPC endpoints failed: %w", len(p.endpoints), lastErr)
+}
+
+// next 使用平滑加权轮询选择一个尚未尝试的端点，优先选择健康端点
+func (p *Pool) next(tried map[*endpoint]bool) *endpoint {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	var candidates []*endpoint
+	for _, e := range p.endpoints {
+		if !tried[e] && p.available(e) {
+			candidates = append(candidates, e)
+		}
+	}
+	// 没有健康端点时仍然尝试其余端点，而不是直接失败
+	if len(candidates) == 0 {
+		for _, e := range p.endpoints {
+			if !tried[e] {
+				candidates = append(candidates, e)
+			}
+		}
+	}
+
+	var selected *endpoint
+	total := 0
+	for _, e := range candidates {
+		e.currentWeight += e.weight
+		total += e.weight
+		if selected == nil || e.currentWeight > selected.currentWeight {
+			selected = e
+		}
+	}
+	selected.currentWeight -= total
+	return selected
+}
+
+// record 更新端点的请求统计与健康状态
+func (p *Pool) record(e *endpoint, latency time.Duration, err error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	e.requests++
+	e.observeLatency(latency)
+
+	if err == nil || !retryable(err) {
+		// 请求参数错误等应用层错误说明端点本身可用
+		e.consecutiveFailures = 0
+		return
+	}
+
+	e.failures++
+	e.consecutiveFailures++
+	e.lastError = err.Error()
+
+	// 被限速时立即冷却，其他错误连续出现多次后冷却
+	if isRateLimited(err) {
+		p.markUnhealthy(e, "rate limited")
+	} else if e.consecutiveFailures >= failureThreshold {
+		p.markUnhealthy(e, fmt.Sprintf("%d consecutive failures", e.consecutiveFailures))
+	}
+}
+
+// available 判断端点当前是否参与路由，冷却期结束后自动恢复
+func (p *Pool) available(e *endpoint) bool {
+	return e.healthy || !p.now().Before(e.unhealthyUntil)
+}
+
+func (p *Pool) markUnhealthy(e *endpoint, reason string) {
+	if e.healthy {
+		log.Printf("⚠️  RPC endpoint %s marked unhealthy: %s", e.label, reason)
+	}
+	e.healthy = false
+	e.unhealthyUntil = p.now().Add(p.cooldown)
+}
+
+func (p *Pool) markHealthy(e *endpoint) {
+	if !e.healthy {
+		log.Printf("✅ RPC endpoint %s is healthy again", e.label)
+	}
+	e.healthy = true
+	e.consecutiveFailures = 0
+}
+
+func (e *endpoint) observeLatency(latency time.Duration) {
+	if e.latency == 0 {
+		e.latency = latency
+		return
+	}
+	e.latency = time.Duration(float64(e.latency)*(1-latencySmoothing) + float64(latency)*latencySmoothing)
+}
+
+// retryable 判断错误是否应切换到其他端点重试：限速、服务端错误与网络错误
+func retryable(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var httpErr *jsonrpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Code == http.StatusTooManyRequests || httpErr.Code >= 500
+	}
+
+	var rpcErr *jsonrpc.RPCError
+	if errors.As(err, &rpcErr) {
+		switch rpcErr.Code {
+		case rpcCodeRateLimited, rpcCodeNodeUnhealthy, rpcCodeInternalError:
+			return true
+		}
+		return false
+	}
+
+	// 其余为超时、连接失败等传输层错误
+	return true
+}
+
+func isRateLimited(err error) bool {
+	var httpErr *jsonrpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Code == http.StatusTooManyRequests
+	}
+	var rpcErr *jsonrpc.RPCError
+	if errors.As(err, &rpcErr) {
+		return rpcErr.Code == rpcCodeRateLimited
+	}
+	return false
+}
+
+// redact 仅保留 URL 的协议与主机，避免在日志中泄露 API Key。
+// QuickNode、Triton 等服务商将 API Key 放在路径中，因此路径整体以 *** 代替
+func redact(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "invalid-url"
+	}
+	label := parsed.Scheme + "://" + parsed.Host
+	if strings.Trim(parsed.Path, "/") != "" {
+		label += "/***"
+	}
+	return label
+}
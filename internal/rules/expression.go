@@ -0,0 +1,101 @@
+package rules
+
+import (
+	"math"
+	"time"
+
+	"github.com/accursedgalaxy/insider-monitor/internal/monitor"
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+)
+
+// Env 为告警表达式可使用的变量。数量均已按小数位换算，美元值在价格未知时为 0
+type Env struct {
+	Wallet     string   `expr:"wallet"`
+	Mint       string   `expr:"mint"`
+	Type       string   `expr:"type"` // 变化类型，例如 balance_change
+	Symbol     string   `expr:"symbol"`
+	Name       string   `expr:"name"`
+	Program    string   `expr:"program"`    // spl-token、token-2022 或 native
+	Extensions []string `expr:"extensions"` // Token-2022 扩展标记
+
+	OldBalance    float64 `expr:"old_balance"`
+	NewBalance    float64 `expr:"new_balance"`
+	TokenDelta    float64 `expr:"token_delta"` // 余额变化量，减少时为负
+	ChangePercent float64 `expr:"change_percent"`
+	USDPrice      float64 `expr:"usd_price"`
+	USDDelta      float64 `expr:"usd_delta"`      // 余额变化的美元价值，减少时为负
+	PositionValue float64 `expr:"position_value"` // 变化后持仓的美元价值
+	SupplyShare   float64 `expr:"supply_share"`   // 变化后持仓占总供应量的百分比
+
+	TokenAgeHours   float64 `expr:"token_age_hours"` // 代币上线至今的小时数，未知时为 -1
+	MintAuthority   string  `expr:"mint_authority"`  // 为空表示已放弃或未知
+	FreezeAuthority string  `expr:"freeze_authority"`
+
+	InsiderScore float64 `expr:"insider_score"`
+	Kind         string  `expr:"kind"`  // buy、sell、transfer_in 等，未归因时为空
+	Venue        string  `expr:"venue"` // 兑换所经的 DEX
+	QuoteMint    string  `expr:"quote_mint"`
+	QuoteAmount  float64 `expr:"quote_amount"` // 兑换支付或收到的报价资产数量
+
+	HolderRank    int     `expr:"holder_rank"`   // 主要持有者变化中的排名
+	Concentration float64 `expr:"concentration"` // 前 10 名持仓占比（%）
+	ClusterSize   int     `expr:"cluster_size"`  // 关联集群中的钱包数
+	Unfinalized   bool    `expr:"unfinalized"`
+}
+
+// compileExpression 编译规则的表达式，结果必须为布尔值
+func compileExpression(source string) (*vm.Program, error) {
+	return expr.Compile(source, expr.Env(Env{}), expr.AsBool())
+}
+
+// matchExpression 对变化求值表达式，求值出错时视为不匹配
+func matchExpression(program *vm.Program, change monitor.Change, now time.Time) (bool, error) {
+	result, err := expr.Run(program, newEnv(change, now))
+	if err != nil {
+		return false, err
+	}
+	matched, _ := result.(bool)
+	return matched, nil
+}
+
+// newEnv 由变化构建表达式的变量
+func newEnv(change monitor.Change, now time.Time) Env {
+	scale := math.Pow(10, float64(change.TokenDecimals))
+	env := Env{
+		Wallet:        change.WalletAddress,
+		Mint:          change.TokenMint,
+		Type:          change.ChangeType,
+		Symbol:        change.TokenSymbol,
+		Name:          change.TokenName,
+		Program:       change.TokenProgram,
+		Extensions:    change.TokenFlags,
+		OldBalance:    float64(change.OldBalance) / scale,
+		NewBalance:    float64(change.NewBalance) / scale,
+		ChangePercent: change.ChangePercent,
+		USDPrice:      change.USDPrice,
+		PositionValue: positionValue(change),
+		SupplyShare:   change.SupplyShare,
+		TokenAgeHours: -1,
+		InsiderScore:  change.InsiderScore,
+		Kind:          change.Kind,
+		Venue:         change.Venue,
+		QuoteMint:     change.QuoteMint,
+		QuoteAmount:   float64(change.QuoteAmount) / math.Pow(10, float64(change.QuoteDecimals)),
+		HolderRank:    change.HolderRank,
+		Concentration: change.Concentration,
+		ClusterSize:   len(change.Participants),
+		Unfinalized:   change.Unfinalized,
+	}
+	env.TokenDelta = env.NewBalance - env.OldBalance
+	env.USDDelta = env.TokenDelta * change.USDPrice
+
+	if !change.TokenLaunch.IsZero() && !now.Before(change.TokenLaunch) {
+		env.TokenAgeHours = now.Sub(change.TokenLaunch).Hours()
+	}
+	if change.Authorities != nil {
+		env.MintAuthority = change.Authorities.MintAuthority
+		env.FreezeAuthority = change.Authorities.FreezeAuthority
+	}
+	return env
+}
@@ -0,0 +1,285 @@
+package rules
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/accursedgalaxy/insider-monitor/internal/alerts"
+	"github.com/accursedgalaxy/insider-monitor/internal/config"
+	"github.com/accursedgalaxy/insider-monitor/internal/monitor"
+	"github.com/expr-lang/expr/vm"
+)
+
+// 默认阈值下余额变化达到 significant_change 的倍数时提升的告警级别
+const (
+	warningMultiple  = 2
+	criticalMultiple = 5
+)
+
+// Decision 为规则引擎对一个变化的判定
+type Decision struct {
+	Level  alerts.AlertLevel
+	Ignore bool   // 被忽略列表或规则丢弃，既不告警也不记录日志
+	Rule   string // 决定告警级别的规则名，未匹配规则时为空
+	Reason string // 告警被降级为日志的原因
+}
+
+// Engine 按告警规则、忽略列表与（按钱包、铸币覆盖后的）阈值计算变化的告警级别
+type Engine struct {
+	cfg           config.AlertConfig
+	ignoreWallets map[string]bool
+	ignoreMints   map[string]bool
+	rules         []rule
+}
+
+type rule struct {
+	config.AlertRule
+	wallets map[string]bool
+	mints   map[string]bool
+	types   map[string]bool
+	program *vm.Program // 编译后的表达式，未设置表达式时为空
+}
+
+// thresholds 为某个钱包与铸币生效的默认阈值
+type thresholds struct {
+	minimumBalance    uint64
+	significantChange float64
+	minUSDChange      float64
+	ignore            bool
+}
+
+// New 由告警配置创建规则引擎并编译规则中的表达式，配置应已通过 Config.Validate 校验。
+// 表达式有误时返回的错误包含规则序号与出错位置
+func New(cfg config.AlertConfig) (*Engine, error) {
+	e := &Engine{
+		cfg:           cfg,
+		ignoreWallets: toSet(cfg.IgnoreWallets),
+		ignoreMints:   toSet(cfg.IgnoreTokens),
+	}
+	for i, r := range cfg.Rules {
+		compiled := rule{
+			AlertRule: r,
+			wallets:   toSet(r.Wallets),
+			mints:     toSet(r.Mints),
+			types:     toSet(r.ChangeTypes),
+		}
+		if r.Expression != "" {
+			program, err := compileExpression(r.Expression)
+			if err != nil {
+				return nil, fmt.Errorf("invalid expression in alert rule %d (%s):\n%w", i, r.Name, err)
+			}
+			compiled.program = program
+		}
+		e.rules = append(e.rules, compiled)
+	}
+	return e, nil
+}
+
+// Evaluate 返回变化的告警级别。level 为该变化类型的默认级别，余额变化的级别由阈值计算；
+// now 用于计算表达式中的代币年龄。第一条匹配的规则直接决定级别，未匹配规则时按默认阈值与 min_insider_score 降级
+func (e *Engine) Evaluate(change monitor.Change, level alerts.AlertLevel, now time.Time) Decision {
+	limits := e.thresholds(change)
+	if limits.ignore {
+		return Decision{Ignore: true}
+	}
+
+	for _, r := range e.rules {
+		if !r.matches(change, now) {
+			continue
+		}
+		if r.Level == "ignore" {
+			return Decision{Ignore: true, Rule: r.Name}
+		}
+		return Decision{Level: parseLevel(r.Level), Rule: r.Name}
+	}
+
+	if change.ChangeType == "balance_change" {
+		var significant bool
+		level, significant = changeLevel(math.Abs(change.ChangePercent), limits.significantChange)
+		if !significant {
+			return Decision{Ignore: true}
+		}
+	}
+
+	decision := Decision{Level: level}
+	if !tradeChange(change) || !level.AtLeast(alerts.Warning) {
+		return decision
+	}
+
+	switch {
+	case limits.minimumBalance > 0 && position(change) < float64(limits.minimumBalance):
+		decision.Reason = fmt.Sprintf("position below minimum balance of %d", limits.minimumBalance)
+	case limits.minUSDChange > 0 && change.USDPrice > 0 && usdDelta(change) < limits.minUSDChange:
+		decision.Reason = fmt.Sprintf("$%.2f change below $%.2f", usdDelta(change), limits.minUSDChange)
+	case e.cfg.MinInsiderScore > 0 && change.InsiderScore < e.cfg.MinInsiderScore:
+		decision.Reason = fmt.Sprintf("insider score %.0f below %.0f", change.InsiderScore, e.cfg.MinInsiderScore)
+	}
+	if decision.Reason != "" {
+		decision.Level = alerts.Info
+	}
+	return decision
+}
+
+// MinChangePercent 返回余额变化需要达到的最低百分比（20 表示 20%），即全局与各覆盖设置中最小的
+// significant_change，以及可匹配余额变化的规则中最小的 min_change_percent。
+// 存在不按百分比、而按代币数量、美元变化、持仓价值或表达式匹配余额变化的规则时返回 0，
+// 所有非零的余额变化都交给 Evaluate 判定。变化检测以此为阈值，低于它的变化不会被任何阈值或规则告警
+func (e *Engine) MinChangePercent() float64 {
+	minimum := e.cfg.SignificantChange * 100
+	for _, overrides := range []map[string]config.AlertOverride{e.cfg.WalletOverrides, e.cfg.MintOverrides} {
+		for _, override := range overrides {
+			if override.SignificantChange != nil && !override.Ignore {
+				minimum = math.Min(minimum, *override.SignificantChange*100)
+			}
+		}
+	}
+	for _, r := range e.rules {
+		if r.Level == "ignore" || (len(r.types) > 0 && !r.types["balance_change"]) {
+			continue
+		}
+		switch {
+		case r.MinChangePercent > 0:
+			minimum = math.Min(minimum, r.MinChangePercent)
+		case r.MinTokenDelta > 0 || r.MinUSDDelta > 0 || r.MinPositionValue > 0 || r.program != nil:
+			return 0
+		}
+	}
+	return minimum
+}
+
+// thresholds 返回变化所属钱包与铸币生效的阈值，铸币的覆盖设置优先于钱包
+func (e *Engine) thresholds(change monitor.Change) thresholds {
+	limits := thresholds{
+		minimumBalance:    e.cfg.MinimumBalance,
+		significantChange: e.cfg.SignificantChange,
+		minUSDChange:      e.cfg.MinUSDChange,
+		ignore:            e.ignoreWallets[change.WalletAddress] || e.ignoreMints[change.TokenMint],
+	}
+
+	apply := func(override config.AlertOverride, exists bool) {
+		if !exists {
+			return
+		}
+		if override.MinimumBalance != nil {
+			limits.minimumBalance = *override.MinimumBalance
+		}
+		if override.SignificantChange != nil {
+			limits.significantChange = *override.SignificantChange
+		}
+		if override.MinUSDChange != nil {
+			limits.minUSDChange = *override.MinUSDChange
+		}
+		limits.ignore = limits.ignore || override.Ignore
+	}
+	override, exists := e.cfg.WalletOverrides[change.WalletAddress]
+	apply(override, exists)
+	if change.TokenMint != "" {
+		override, exists = e.cfg.MintOverrides[change.TokenMint]
+		apply(override, exists)
+	}
+	return limits
+}
+
+// matches 判断变化是否满足规则的全部条件，表达式最后求值
+func (r rule) matches(change monitor.Change, now time.Time) bool {
+	if len(r.wallets) > 0 && !r.wallets[change.WalletAddress] {
+		return false
+	}
+	if len(r.mints) > 0 && !r.mints[change.TokenMint] {
+		return false
+	}
+	if len(r.types) > 0 && !r.types[change.ChangeType] {
+		return false
+	}
+	if r.MinTokenDelta > 0 && tokenDelta(change) < r.MinTokenDelta {
+		return false
+	}
+	// 价格未知的代币不满足美元条件
+	if r.MinUSDDelta > 0 && usdDelta(change) < r.MinUSDDelta {
+		return false
+	}
+	if r.MinChangePercent > 0 && math.Abs(change.ChangePercent) < r.MinChangePercent {
+		return false
+	}
+	if r.MinPositionValue > 0 && positionValue(change) < r.MinPositionValue {
+		return false
+	}
+	if r.program != nil {
+		matched, err := matchExpression(r.program, change, now)
+		if err != nil {
+			log.Printf("⚠️  Warning: failed to evaluate alert rule %s: %v", r.Name, err)
+		}
+		return matched
+	}
+	return true
+}
+
+// changeLevel 按余额变化百分比相对 significant_change（0.20 表示 20%）的倍数计算告警级别，
+// 未达到阈值时 significant 为 false
+func changeLevel(percent, significantChange float64) (level alerts.AlertLevel, significant bool) {
+	threshold := significantChange * 100
+	switch {
+	case percent < threshold:
+		return alerts.Info, false
+	case percent >= threshold*criticalMultiple:
+		return alerts.Critical, true
+	case percent >= threshold*warningMultiple:
+		return alerts.Warning, true
+	default:
+		return alerts.Info, true
+	}
+}
+
+// tradeChange 判断变化是否为持仓数量的变化，只有这些变化受最小余额、美元与 InsiderScore 阈值限制
+func tradeChange(change monitor.Change) bool {
+	switch change.ChangeType {
+	case "balance_change", "new_token", "token_exit":
+		return true
+	}
+	return false
+}
+
+// tokenDelta 返回按小数位换算后的余额变化量
+func tokenDelta(change monitor.Change) float64 {
+	delta := float64(change.NewBalance) - float64(change.OldBalance)
+	return math.Abs(delta) / math.Pow(10, float64(change.TokenDecimals))
+}
+
+// usdDelta 返回余额变化的美元价值，价格未知时为 0
+func usdDelta(change monitor.Change) float64 {
+	return tokenDelta(change) * change.USDPrice
+}
+
+// position 返回变化前后较大的持仓数量，使清仓与大额卖出同样受最小余额限制
+func position(change monitor.Change) float64 {
+	balance := math.Max(float64(change.OldBalance), float64(change.NewBalance))
+	return balance / math.Pow(10, float64(change.TokenDecimals))
+}
+
+// positionValue 返回变化后持仓的美元价值，new_wallet 变化为全部持仓的价值
+func positionValue(change monitor.Change) float64 {
+	if change.ChangeType == "new_wallet" {
+		return change.TotalUSDValue()
+	}
+	return change.USDValue()
+}
+
+func parseLevel(level string) alerts.AlertLevel {
+	switch level {
+	case "critical":
+		return alerts.Critical
+	case "warning":
+		return alerts.Warning
+	}
+	return alerts.Info
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
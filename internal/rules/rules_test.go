@@ -0,0 +1,357 @@
+package rules
+
+import (
+	"testing"
+	"time"
+
+	"github.com/accursedgalaxy/insider-monitor/internal/alerts"
+	"github.com/accursedgalaxy/insider-monitor/internal/config"
+	"github.com/accursedgalaxy/insider-monitor/internal/monitor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func balanceChange(wallet, mint string, oldBalance, newBalance uint64, price float64) monitor.Change {
+	change := monitor.Change{
+		WalletAddress: wallet,
+		TokenMint:     mint,
+		TokenDecimals: 6,
+		ChangeType:    "balance_change",
+		OldBalance:    oldBalance,
+		NewBalance:    newBalance,
+		USDPrice:      price,
+		InsiderScore:  50,
+	}
+	if oldBalance > 0 {
+		change.ChangePercent = (float64(newBalance) - float64(oldBalance)) / float64(oldBalance) * 100
+	}
+	return change
+}
+
+func TestEvaluateDefaults(t *testing.T) {
+	tenK := uint64(10_000)
+	loose := 0.05
+	cfg := config.AlertConfig{
+		MinimumBalance:    100,
+		SignificantChange: 0.20,
+		MinUSDChange:      50,
+		IgnoreTokens:      []string{"spam"},
+		IgnoreWallets:     []string{"muted"},
+		MinInsiderScore:   40,
+		WalletOverrides:   map[string]config.AlertOverride{"whale": {MinimumBalance: &tenK}},
+		MintOverrides:     map[string]config.AlertOverride{"volatile": {SignificantChange: &loose}},
+	}
+	lowScore := balanceChange("w", "x", 1_000_000_000, 500_000_000, 1)
+	lowScore.InsiderScore = 10
+
+	tests := []struct {
+		name   string
+		change monitor.Change
+		level  alerts.AlertLevel
+		want   Decision
+	}{
+		{
+			name:   "below significant change",
+			change: balanceChange("w", "x", 1_000_000_000, 1_100_000_000, 1),
+			want:   Decision{Ignore: true},
+		},
+		{
+			name:   "significant but below warning",
+			change: balanceChange("w", "x", 1_000_000_000, 1_300_000_000, 1),
+			want:   Decision{Level: alerts.Info},
+		},
+		{
+			name:   "warning",
+			change: balanceChange("w", "x", 1_000_000_000, 500_000_000, 1),
+			want:   Decision{Level: alerts.Warning},
+		},
+		{
+			name:   "critical",
+			change: balanceChange("w", "x", 1_000_000_000, 2_500_000_000, 1),
+			want:   Decision{Level: alerts.Critical},
+		},
+		{
+			name:   "mint override lowers threshold",
+			change: balanceChange("w", "volatile", 1_000_000_000, 1_100_000_000, 1),
+			want:   Decision{Level: alerts.Warning},
+		},
+		{
+			name:   "below minimum balance",
+			change: balanceChange("w", "x", 50_000_000, 10_000_000, 100),
+			want:   Decision{Level: alerts.Info, Reason: "position below minimum balance of 100"},
+		},
+		{
+			name:   "wallet override raises minimum balance",
+			change: balanceChange("whale", "x", 1_000_000_000, 500_000_000, 1),
+			want:   Decision{Level: alerts.Info, Reason: "position below minimum balance of 10000"},
+		},
+		{
+			name:   "below usd change",
+			change: balanceChange("w", "x", 1_000_000_000, 500_000_000, 0.01),
+			want:   Decision{Level: alerts.Info, Reason: "$5.00 change below $50.00"},
+		},
+		{
+			name:   "unknown price is not usd gated",
+			change: balanceChange("w", "x", 1_000_000_000, 500_000_000, 0),
+			want:   Decision{Level: alerts.Warning},
+		},
+		{
+			name:   "low insider score",
+			change: lowScore,
+			want:   Decision{Level: alerts.Info, Reason: "insider score 10 below 40"},
+		},
+		{
+			name:   "ignored token",
+			change: balanceChange("w", "spam", 1_000_000_000, 500_000_000, 1),
+			want:   Decision{Ignore: true},
+		},
+		{
+			name:   "ignored wallet",
+			change: monitor.Change{WalletAddress: "muted", ChangeType: "wallet_failing"},
+			level:  alerts.Warning,
+			want:   Decision{Ignore: true},
+		},
+		{
+			name:   "other change types keep their level",
+			change: monitor.Change{WalletAddress: "w", TokenMint: "x", ChangeType: "account_state"},
+			level:  alerts.Critical,
+			want:   Decision{Level: alerts.Critical},
+		},
+	}
+
+	engine, err := New(cfg)
+	require.NoError(t, err)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, engine.Evaluate(tt.change, tt.level, time.Now()))
+		})
+	}
+}
+
+func TestEvaluateRules(t *testing.T) {
+	engine, err := New(config.AlertConfig{
+		SignificantChange: 0.20,
+		MinimumBalance:    1_000_000,
+		Rules: []config.AlertRule{
+			{Name: "dust", Mints: []string{"dust"}, ChangeTypes: []string{"new_token"}, Level: "ignore"},
+			{Name: "big sells", ChangeTypes: []string{"balance_change", "token_exit"}, MinUSDDelta: 10_000, Level: "critical"},
+			{Name: "watched", Wallets: []string{"vip"}, MinChangePercent: 5, Level: "warning"},
+			{Name: "large delta", MinTokenDelta: 1_000, MinPositionValue: 100, Level: "warning"},
+		},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		change monitor.Change
+		want   Decision
+	}{
+		{
+			name:   "ignore rule",
+			change: monitor.Change{WalletAddress: "w", TokenMint: "dust", ChangeType: "new_token", NewBalance: 1},
+			want:   Decision{Ignore: true, Rule: "dust"},
+		},
+		{
+			// 5% 变化低于默认阈值，且持仓低于最小余额，但金额满足规则
+			name:   "usd delta",
+			change: balanceChange("w", "x", 200_000_000_000, 190_000_000_000, 1),
+			want:   Decision{Level: alerts.Critical, Rule: "big sells"},
+		},
+		{
+			name:   "usd rule needs a price",
+			change: balanceChange("w", "x", 200_000_000_000, 190_000_000_000, 0),
+			want:   Decision{Ignore: true},
+		},
+		{
+			name:   "per wallet percentage",
+			change: balanceChange("vip", "x", 1_000_000, 1_060_000, 1),
+			want:   Decision{Level: alerts.Warning, Rule: "watched"},
+		},
+		{
+			name:   "token delta and position value",
+			change: balanceChange("w", "x", 1_000_000, 2_000_000_000, 0.1),
+			want:   Decision{Level: alerts.Warning, Rule: "large delta"},
+		},
+		{
+			name:   "position value too low",
+			change: balanceChange("w", "x", 1_000_000, 2_000_000_000, 0.01),
+			want:   Decision{Level: alerts.Info, Reason: "position below minimum balance of 1000000"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, engine.Evaluate(tt.change, "", time.Now()))
+		})
+	}
+}
+
+func TestExpressionRules(t *testing.T) {
+	now := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	engine, err := New(config.AlertConfig{
+		SignificantChange: 0.20,
+		Rules: []config.AlertRule{{
+			Name:       "fresh insider dump",
+			Expression: `usd_delta < -10000 && token_age_hours >= 0 && token_age_hours < 24 && insider_score > 70`,
+			Level:      "critical",
+		}},
+	})
+	require.NoError(t, err)
+
+	sell := balanceChange("w", "x", 100_000_000_000, 90_000_000_000, 2)
+	sell.InsiderScore = 80
+
+	fresh := sell
+	fresh.TokenLaunch = now.Add(-6 * time.Hour)
+	assert.Equal(t, Decision{Level: alerts.Critical, Rule: "fresh insider dump"}, engine.Evaluate(fresh, "", now))
+
+	// 上线时间未知或已上线超过一天的代币不匹配，按默认阈值处理
+	assert.Equal(t, Decision{Ignore: true}, engine.Evaluate(sell, "", now))
+	old := sell
+	old.TokenLaunch = now.Add(-48 * time.Hour)
+	assert.Equal(t, Decision{Ignore: true}, engine.Evaluate(old, "", now))
+}
+
+func TestExpressionErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		message    string
+	}{
+		{name: "unknown variable", expression: "usd_delta > 10 && insider > 70", message: "unknown name insider (1:19)"},
+		{name: "not a boolean", expression: "usd_delta * 2", message: "expected bool"},
+		{name: "type mismatch", expression: `symbol > 10`, message: "invalid operation"},
+		{name: "syntax", expression: "usd_delta >", message: "unexpected token EOF (1:11)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(config.AlertConfig{Rules: []config.AlertRule{
+				{Name: "ok", Expression: "true", Level: "info"},
+				{Name: "broken", Expression: tt.expression, Level: "warning"},
+			}})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "alert rule 1 (broken)")
+			assert.Contains(t, err.Error(), tt.message)
+		})
+	}
+}
+
+func TestMinChangePercent(t *testing.T) {
+	loose, tight := 0.05, 0.50
+	cfg := config.AlertConfig{
+		SignificantChange: 0.20,
+		WalletOverrides:   map[string]config.AlertOverride{"whale": {SignificantChange: &tight}},
+		MintOverrides:     map[string]config.AlertOverride{"volatile": {SignificantChange: &loose}},
+	}
+	engine, err := New(cfg)
+	require.NoError(t, err)
+	assert.InDelta(t, 5.0, engine.MinChangePercent(), 1e-9)
+
+	// 覆盖设置低于全局阈值时，该铸币 10% 的变化仍被检测并按覆盖阈值告警，其他铸币则被忽略
+	wallet := func(balances map[string]uint64) map[string]*monitor.WalletData {
+		data := &monitor.WalletData{WalletAddress: "w", TokenAccounts: make(map[string]monitor.TokenAccountInfo)}
+		for mint, balance := range balances {
+			data.TokenAccounts[mint] = monitor.TokenAccountInfo{Balance: balance, Decimals: 6}
+		}
+		return map[string]*monitor.WalletData{"w": data}
+	}
+	changes := monitor.DetectChanges(
+		wallet(map[string]uint64{"volatile": 1_000_000, "x": 1_000_000, "flat": 1_000_000}),
+		wallet(map[string]uint64{"volatile": 1_100_000, "x": 1_100_000, "flat": 1_020_000}),
+		engine.MinChangePercent(),
+	)
+	require.Len(t, changes, 2)
+	for _, change := range changes {
+		decision := engine.Evaluate(change, "", time.Now())
+		if change.TokenMint == "volatile" {
+			assert.Equal(t, Decision{Level: alerts.Warning}, decision)
+		} else {
+			assert.Equal(t, "x", change.TokenMint)
+			assert.Equal(t, Decision{Ignore: true}, decision)
+		}
+	}
+
+	// 可匹配余额变化的规则同样降低检测阈值，被忽略的覆盖设置与其他类型的规则不影响
+	cfg.MintOverrides = map[string]config.AlertOverride{"spam": {SignificantChange: &loose, Ignore: true}}
+	cfg.Rules = []config.AlertRule{
+		{Name: "exits", ChangeTypes: []string{"token_exit"}, MinChangePercent: 1, Level: "warning"},
+		{Name: "team wallet", Wallets: []string{"team"}, MinChangePercent: 10, Level: "warning"},
+	}
+	engine, err = New(cfg)
+	require.NoError(t, err)
+	assert.InDelta(t, 10.0, engine.MinChangePercent(), 1e-9)
+}
+
+func TestMinChangePercentWithAbsoluteRules(t *testing.T) {
+	cfg := config.AlertConfig{
+		SignificantChange: 0.20,
+		Rules: []config.AlertRule{
+			{Name: "large sells", ChangeTypes: []string{"balance_change"}, MinUSDDelta: 10_000, Level: "critical"},
+		},
+	}
+	engine, err := New(cfg)
+	require.NoError(t, err)
+	assert.Zero(t, engine.MinChangePercent())
+
+	// 卖出 2% 的持仓，价值 $50k
+	wallet := func(balances map[string]uint64) map[string]*monitor.WalletData {
+		data := &monitor.WalletData{WalletAddress: "w", TokenAccounts: make(map[string]monitor.TokenAccountInfo)}
+		for mint, balance := range balances {
+			data.TokenAccounts[mint] = monitor.TokenAccountInfo{Balance: balance, Decimals: 6, USDPrice: 1}
+		}
+		return map[string]*monitor.WalletData{"w": data}
+	}
+	changes := monitor.DetectChanges(
+		wallet(map[string]uint64{"stake": 2_500_000_000_000, "dust": 100_000_000, "flat": 1_000_000}),
+		wallet(map[string]uint64{"stake": 2_450_000_000_000, "dust": 99_000_000, "flat": 1_000_000}),
+		engine.MinChangePercent(),
+	)
+	require.Len(t, changes, 2, "unchanged balances are not reported")
+	for _, change := range changes {
+		decision := engine.Evaluate(change, "", time.Now())
+		if change.TokenMint == "stake" {
+			assert.InDelta(t, -2.0, change.ChangePercent, 1e-9)
+			assert.Equal(t, Decision{Level: alerts.Critical, Rule: "large sells"}, decision)
+		} else {
+			// 未匹配规则的小额变化仍按 significant_change 忽略
+			assert.Equal(t, "dust", change.TokenMint)
+			assert.Equal(t, Decision{Ignore: true}, decision)
+		}
+	}
+
+	// 规则同时要求百分比时按该百分比检测
+	cfg.Rules[0].MinChangePercent = 1
+	engine, err = New(cfg)
+	require.NoError(t, err)
+	assert.InDelta(t, 1.0, engine.MinChangePercent(), 1e-9)
+}
+
+func TestExpressionRuleSeesSmallChanges(t *testing.T) {
+	engine, err := New(config.AlertConfig{
+		SignificantChange: 0.20,
+		Rules: []config.AlertRule{
+			{Name: "fresh insider buy", Expression: "usd_delta > 10000 and token_age_hours < 24 and insider_score > 70", Level: "critical"},
+		},
+	})
+	require.NoError(t, err)
+	assert.Zero(t, engine.MinChangePercent())
+
+	// 加仓 5%，价值 $25k，低于 significant_change
+	wallet := func(balance uint64) map[string]*monitor.WalletData {
+		return map[string]*monitor.WalletData{"w": {WalletAddress: "w", TokenAccounts: map[string]monitor.TokenAccountInfo{
+			"fresh": {Balance: balance, Decimals: 6, USDPrice: 0.5},
+		}}}
+	}
+	changes := monitor.DetectChanges(wallet(1_000_000_000_000), wallet(1_050_000_000_000), engine.MinChangePercent())
+	require.Len(t, changes, 1)
+
+	now := time.Now()
+	change := changes[0]
+	change.TokenLaunch = now.Add(-2 * time.Hour)
+	change.InsiderScore = 80
+	assert.Equal(t, Decision{Level: alerts.Critical, Rule: "fresh insider buy"}, engine.Evaluate(change, "", now))
+
+	change.InsiderScore = 50
+	assert.Equal(t, Decision{Ignore: true}, engine.Evaluate(change, "", now))
+}
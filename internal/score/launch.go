@@ -0,0 +1,69 @@
+package score
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+const (
+	signaturesPerPage = 1000 // getSignaturesForAddress 单页上限
+	maxLaunchPages    = 3    // 交易过多的代币视为早已上线，不再向前翻页
+)
+
+// RPCLaunchResolver 以铸币地址最早一笔交易的区块时间作为代币上线时间
+type RPCLaunchResolver struct {
+	client *rpc.Client
+}
+
+func NewRPCLaunchResolver(client *rpc.Client) *RPCLaunchResolver {
+	return &RPCLaunchResolver{client: client}
+}
+
+// LaunchTime 从 cursor 处（为空时从最新的交易）向前翻页查找铸币的第一笔交易。
+// 翻过 maxLaunchPages 页仍未找到时返回 false 与最后一页最早的签名，下一次查找从该签名继续
+func (r *RPCLaunchResolver) LaunchTime(ctx context.Context, mint, cursor string) (time.Time, bool, string, error) {
+	key, err := solana.PublicKeyFromBase58(mint)
+	if err != nil {
+		return time.Time{}, false, "", fmt.Errorf("invalid mint address %s: %w", mint, err)
+	}
+
+	limit := signaturesPerPage
+	var before solana.Signature
+	if cursor != "" {
+		if before, err = solana.SignatureFromBase58(cursor); err != nil {
+			return time.Time{}, false, "", fmt.Errorf("invalid launch cursor %s: %w", cursor, err)
+		}
+	}
+	for page := 0; page < maxLaunchPages; page++ {
+		opts := &rpc.GetSignaturesForAddressOpts{
+			Limit:      &limit,
+			Commitment: rpc.CommitmentConfirmed,
+		}
+		if !before.IsZero() {
+			opts.Before = before
+		}
+
+		results, err := r.client.GetSignaturesForAddressWithOpts(ctx, key, opts)
+		if err != nil {
+			return time.Time{}, false, "", fmt.Errorf("failed to get signatures for %s: %w", mint, err)
+		}
+		if len(results) == 0 {
+			return time.Time{}, false, "", nil
+		}
+
+		// 签名按时间倒序返回，最后一页的最后一笔即为最早的交易
+		oldest := results[len(results)-1]
+		if len(results) < limit {
+			if oldest.BlockTime == nil {
+				return time.Time{}, false, "", nil
+			}
+			return oldest.BlockTime.Time(), true, "", nil
+		}
+		before = oldest.Signature
+	}
+	return time.Time{}, false, before.String(), nil
+}
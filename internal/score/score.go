@@ -0,0 +1,410 @@
+package score
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	historyFileName  = "insider_scores.json"
+	maxHistory       = 500 // 每个钱包保留的评分历史点数
+	minHistoryChange = 0.5 // 评分变化达到该值才记录新的历史点，与显示的整数精度一致
+	maxSamples       = 100 // 每个特征保留的样本数
+	earlyEntryWindow = 7 * 24 * time.Hour
+	quickExitWindow  = 30 * 24 * time.Hour
+	spikeWindow      = 72 * time.Hour
+	spikeMultiple    = 2.0 // 买入后价格翻倍视为拉升
+	concentrationCap = 5.0 // 持有供应量的 5% 及以上记为满分
+	// 没有更早交易可翻页却未找到上线时间时（例如区块时间缺失），间隔该时间后重新查找
+	launchRetryInterval = 24 * time.Hour
+)
+
+// 特征权重，缺少样本的特征不参与计算
+const (
+	weightEntryTiming   = 0.3
+	weightConcentration = 0.2
+	weightAccumulation  = 0.3
+	weightExitSpeed     = 0.2
+)
+
+// 持仓事件类型
+const (
+	EventEntry    = "entry"    // 监控期间新买入的代币
+	EventIncrease = "increase" // 已有持仓加仓
+	EventExit     = "exit"     // 全部清仓
+)
+
+// Feature 为 0–1 之间的特征值及其样本数，样本数为 0 表示无法计算
+type Feature struct {
+	Value   float64 `json:"value"`
+	Samples int     `json:"samples"`
+}
+
+// Features 为计算 InsiderScore 的各项特征
+type Features struct {
+	EntryTiming   Feature `json:"entry_timing"`  // 代币上线后多快买入
+	Concentration Feature `json:"concentration"` // 单一持仓占供应量的最高比例
+	Accumulation  Feature `json:"accumulation"`  // 买入后价格拉升的比例
+	ExitSpeed     Feature `json:"exit_speed"`    // 买入到清仓的速度
+}
+
+// Compute 按权重汇总特征，返回 0–100 的分数
+func Compute(f Features) float64 {
+	var total, weights float64
+	for _, item := range []struct {
+		feature Feature
+		weight  float64
+	}{
+		{f.EntryTiming, weightEntryTiming},
+		{f.Concentration, weightConcentration},
+		{f.Accumulation, weightAccumulation},
+		{f.ExitSpeed, weightExitSpeed},
+	} {
+		if item.feature.Samples == 0 {
+			continue
+		}
+		total += item.feature.Value * item.weight
+		weights += item.weight
+	}
+	if weights == 0 {
+		return 0
+	}
+	return math.Max(0, math.Min(total/weights*100, 100))
+}
+
+// Holding 为钱包某一持仓在本次扫描中的观察值
+type Holding struct {
+	Mint        string
+	USDPrice    float64
+	SupplyShare float64 // 占总供应量的百分比
+}
+
+// Event 为两次扫描之间的持仓变化
+type Event struct {
+	Mint string
+	Kind string
+	Time time.Time
+}
+
+// Point 为某一时刻的评分记录
+type Point struct {
+	Time  time.Time `json:"time"`
+	Score float64   `json:"score"`
+}
+
+// Result 为钱包当前的评分
+type Result struct {
+	Score    float64
+	Previous float64 // 最近一次变化之前的评分，没有历史时为 0
+	Features Features
+}
+
+// LaunchResolver 查询代币的上线时间，无法确定时返回 false。
+// cursor 非空时从该签名之前继续向前查找；未找到且还有更早的交易时返回下一次查找使用的游标
+type LaunchResolver interface {
+	LaunchTime(ctx context.Context, mint, cursor string) (launch time.Time, found bool, next string, err error)
+}
+
+// position 为监控期间观察到的持仓
+type position struct {
+	FirstSeen  time.Time `json:"first_seen"`
+	EntryKnown bool      `json:"entry_known"` // 基线中已有的持仓无法得知买入时间
+}
+
+// accumulation 为一次买入或加仓，用于判断其后是否出现拉升
+type accumulation struct {
+	Mint  string    `json:"mint"`
+	Time  time.Time `json:"time"`
+	Price float64   `json:"price"`
+}
+
+// walletRecord 为单个钱包的评分状态
+type walletRecord struct {
+	Positions     map[string]*position `json:"positions"`
+	Pending       []accumulation       `json:"pending,omitempty"` // 仍在观察窗口内的买入
+	EntryAges     []time.Duration      `json:"entry_ages,omitempty"`
+	HoldDurations []time.Duration      `json:"hold_durations,omitempty"`
+	Settled       int                  `json:"settled"` // 观察窗口已结束的买入次数
+	Spiked        int                  `json:"spiked"`  // 其中出现拉升的次数
+	Features      Features             `json:"features"`
+	History       []Point              `json:"history,omitempty"`
+}
+
+// launchRecord 缓存代币的上线时间。未找到时记录继续查找的游标与查找时间，
+// 之后的查找从游标处继续；没有游标的未命中在 launchRetryInterval 后重新查找
+type launchRecord struct {
+	Time      time.Time `json:"time,omitempty"`
+	Found     bool      `json:"found"`
+	Cursor    string    `json:"cursor,omitempty"`
+	CheckedAt time.Time `json:"checked_at,omitempty"`
+}
+
+// Scorer 根据持仓与持仓变化计算各钱包的 InsiderScore，并持久化评分历史
+type Scorer struct {
+	launches LaunchResolver
+	path     string
+	state    struct {
+		Wallets  map[string]*walletRecord `json:"wallets"`
+		Launches map[string]launchRecord  `json:"launches"`
+	}
+	mutex sync.Mutex
+}
+
+func NewScorer(launches LaunchResolver, dataDir string) *Scorer {
+	s := &Scorer{
+		launches: launches,
+		path:     filepath.Join(dataDir, historyFileName),
+	}
+	s.state.Wallets = make(map[string]*walletRecord)
+	s.state.Launches = make(map[string]launchRecord)
+
+	if err := s.load(); err != nil {
+		log.Printf("⚠️  Warning: failed to load insider scores: %v", err)
+	}
+	return s
+}
+
+// Observe 记录钱包本次扫描的持仓与持仓变化，更新并返回其评分
+func (s *Scorer) Observe(ctx context.Context, wallet string, holdings []Holding, events []Event, now time.Time) Result {
+	// 上线时间查询需要 RPC 请求，在加锁前完成
+	launches := make(map[string]launchRecord)
+	for _, event := range events {
+		if event.Kind == EventEntry {
+			launches[event.Mint] = s.launchTime(ctx, event.Mint, now)
+		}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	record, exists := s.state.Wallets[wallet]
+	if !exists {
+		record = &walletRecord{Positions: make(map[string]*position)}
+		s.state.Wallets[wallet] = record
+	}
+
+	prices := make(map[string]float64, len(holdings))
+	for _, holding := range holdings {
+		prices[holding.Mint] = holding.USDPrice
+	}
+
+	for _, event := range events {
+		switch event.Kind {
+		case EventEntry:
+			record.Positions[event.Mint] = &position{FirstSeen: event.Time, EntryKnown: true}
+			if launch := launches[event.Mint]; launch.Found && !event.Time.Before(launch.Time) {
+				record.EntryAges = appendSample(record.EntryAges, event.Time.Sub(launch.Time))
+			}
+			record.addAccumulation(event.Mint, event.Time, prices[event.Mint])
+		case EventIncrease:
+			record.addAccumulation(event.Mint, event.Time, prices[event.Mint])
+		case EventExit:
+			if held, exists := record.Positions[event.Mint]; exists && held.EntryKnown {
+				record.HoldDurations = appendSample(record.HoldDurations, event.Time.Sub(held.FirstSeen))
+			}
+			delete(record.Positions, event.Mint)
+		}
+	}
+
+	// 基线中已有的持仓只记录首次出现时间
+	for _, holding := range holdings {
+		if _, exists := record.Positions[holding.Mint]; !exists {
+			record.Positions[holding.Mint] = &position{FirstSeen: now}
+		}
+	}
+
+	record.settle(prices, now)
+	record.Features = record.features(holdings)
+
+	// 仅在评分变化时记录历史，评分稳定时历史覆盖更长的时间
+	score := Compute(record.Features)
+	if n := len(record.History); n == 0 || math.Abs(score-record.History[n-1].Score) >= minHistoryChange {
+		record.History = append(record.History, Point{Time: now, Score: score})
+		if len(record.History) > maxHistory {
+			record.History = record.History[len(record.History)-maxHistory:]
+		}
+	}
+	return record.result()
+}
+
+// Get 返回钱包最近一次的评分
+func (s *Scorer) Get(wallet string) (Result, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	record, exists := s.state.Wallets[wallet]
+	if !exists || len(record.History) == 0 {
+		return Result{}, false
+	}
+	return record.result(), true
+}
+
+// History 返回钱包的评分历史
+func (s *Scorer) History(wallet string) []Point {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	record, exists := s.state.Wallets[wallet]
+	if !exists {
+		return nil
+	}
+	return append([]Point(nil), record.History...)
+}
+
+// LaunchTime 返回已缓存的代币上线时间，不发起 RPC 请求
+func (s *Scorer) LaunchTime(mint string) (time.Time, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	launch := s.state.Launches[mint]
+	return launch.Time, launch.Found
+}
+
+// launchTime 查询并缓存代币的上线时间，查询失败时不缓存。
+// 之前未找到的代币从保存的游标处继续向前查找
+func (s *Scorer) launchTime(ctx context.Context, mint string, now time.Time) launchRecord {
+	s.mutex.Lock()
+	cached, exists := s.state.Launches[mint]
+	s.mutex.Unlock()
+	if s.launches == nil || exists && (cached.Found || cached.Cursor == "" && now.Sub(cached.CheckedAt) < launchRetryInterval) {
+		return cached
+	}
+
+	launch, found, cursor, err := s.launches.LaunchTime(ctx, mint, cached.Cursor)
+	if err != nil {
+		log.Printf("⚠️  Warning: failed to resolve launch time of %s: %v", mint, err)
+		return cached
+	}
+
+	record := launchRecord{Time: launch, Found: found, Cursor: cursor, CheckedAt: now}
+	s.mutex.Lock()
+	s.state.Launches[mint] = record
+	s.mutex.Unlock()
+	return record
+}
+
+// result 返回当前评分，历史中的最后一个点与当前评分的差距小于 minHistoryChange
+func (r *walletRecord) result() Result {
+	result := Result{Features: r.Features, Score: Compute(r.Features)}
+	if n := len(r.History); n > 1 {
+		result.Previous = r.History[n-2].Score
+	}
+	return result
+}
+
+func (r *walletRecord) addAccumulation(mint string, at time.Time, price float64) {
+	if price <= 0 {
+		return
+	}
+	r.Pending = append(r.Pending, accumulation{Mint: mint, Time: at, Price: price})
+}
+
+// settle 检查观察窗口内的买入是否已出现拉升，窗口结束或拉升后计入统计
+func (r *walletRecord) settle(prices map[string]float64, now time.Time) {
+	pending := r.Pending[:0]
+	for _, acc := range r.Pending {
+		switch {
+		case prices[acc.Mint] >= acc.Price*spikeMultiple:
+			r.Settled++
+			r.Spiked++
+		case now.Sub(acc.Time) >= spikeWindow:
+			r.Settled++
+		default:
+			pending = append(pending, acc)
+		}
+	}
+	r.Pending = pending
+}
+
+// features 根据记录的样本计算各项特征
+func (r *walletRecord) features(holdings []Holding) Features {
+	var f Features
+
+	for _, age := range r.EntryAges {
+		f.EntryTiming.Value += decay(age, earlyEntryWindow)
+	}
+	if f.EntryTiming.Samples = len(r.EntryAges); f.EntryTiming.Samples > 0 {
+		f.EntryTiming.Value /= float64(f.EntryTiming.Samples)
+	}
+
+	for _, holding := range holdings {
+		f.Concentration.Value = math.Max(f.Concentration.Value, math.Min(holding.SupplyShare/concentrationCap, 1))
+	}
+	if len(holdings) > 0 {
+		f.Concentration.Samples = 1
+	}
+
+	if f.Accumulation.Samples = r.Settled; r.Settled > 0 {
+		f.Accumulation.Value = float64(r.Spiked) / float64(r.Settled)
+	}
+
+	for _, held := range r.HoldDurations {
+		f.ExitSpeed.Value += decay(held, quickExitWindow)
+	}
+	if f.ExitSpeed.Samples = len(r.HoldDurations); f.ExitSpeed.Samples > 0 {
+		f.ExitSpeed.Value /= float64(f.ExitSpeed.Samples)
+	}
+	return f
+}
+
+// decay 将时长线性映射到 1（立即）至 0（达到 window）之间
+func decay(d, window time.Duration) float64 {
+	if d <= 0 {
+		return 1
+	}
+	return math.Max(0, 1-float64(d)/float64(window))
+}
+
+func appendSample(samples []time.Duration, sample time.Duration) []time.Duration {
+	samples = append(samples, sample)
+	if len(samples) > maxSamples {
+		samples = samples[len(samples)-maxSamples:]
+	}
+	return samples
+}
+
+// Save 将评分状态写入磁盘
+func (s *Scorer) Save() error {
+	s.mutex.Lock()
+	file, err := json.MarshalIndent(s.state, "", "  ")
+	s.mutex.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to marshal insider scores: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+	return os.WriteFile(s.path, file, 0644)
+}
+
+// load 从磁盘读取评分状态
+func (s *Scorer) load() error {
+	file, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := json.Unmarshal(file, &s.state); err != nil {
+		return err
+	}
+	if s.state.Wallets == nil {
+		s.state.Wallets = make(map[string]*walletRecord)
+	}
+	if s.state.Launches == nil {
+		s.state.Launches = make(map[string]launchRecord)
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package score
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeLaunches struct {
+	launches map[string]time.Time
+	pages    map[string]int // 找到上线时间前需要翻过的查找次数
+	cursors  []string       // 每次查找传入的游标
+	calls    int
+}
+
+func (f *fakeLaunches) LaunchTime(ctx context.Context, mint, cursor string) (time.Time, bool, string, error) {
+	f.calls++
+	f.cursors = append(f.cursors, cursor)
+	if len(f.cursors) <= f.pages[mint] {
+		return time.Time{}, false, fmt.Sprintf("cursor-%d", len(f.cursors)), nil
+	}
+	launch, found := f.launches[mint]
+	return launch, found, "", nil
+}
+
+func TestCompute(t *testing.T) {
+	tests := []struct {
+		name     string
+		features Features
+		expected float64
+	}{
+		{
+			name:     "no samples",
+			expected: 0,
+		},
+		{
+			name:     "missing features are excluded from the weights",
+			features: Features{Concentration: Feature{Value: 0.5, Samples: 1}},
+			expected: 50,
+		},
+		{
+			name: "weighted sum",
+			features: Features{
+				EntryTiming:   Feature{Value: 1, Samples: 2},
+				Concentration: Feature{Value: 0, Samples: 1},
+				Accumulation:  Feature{Value: 0.5, Samples: 4},
+				ExitSpeed:     Feature{Value: 1, Samples: 1},
+			},
+			expected: 65,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.expected, Compute(tt.features), 0.0001)
+		})
+	}
+}
+
+func TestScorerObserve(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	launches := &fakeLaunches{launches: map[string]time.Time{"early": start.Add(-time.Hour)}}
+	scorer := NewScorer(launches, dir)
+	ctx := context.Background()
+
+	// 基线：已有持仓不计入买入时机与清仓速度
+	result := scorer.Observe(ctx, "wallet", []Holding{{Mint: "old", USDPrice: 1, SupplyShare: 1}}, nil, start)
+	assert.Equal(t, 1, result.Features.Concentration.Samples)
+	assert.InDelta(t, 0.2, result.Features.Concentration.Value, 0.0001)
+	assert.Zero(t, result.Features.EntryTiming.Samples)
+	assert.InDelta(t, 20, result.Score, 0.0001)
+
+	// 上线一小时后买入，随后价格翻倍
+	holdings := []Holding{
+		{Mint: "old", USDPrice: 1, SupplyShare: 1},
+		{Mint: "early", USDPrice: 0.01, SupplyShare: 2},
+	}
+	scorer.Observe(ctx, "wallet", holdings, []Event{{Mint: "early", Kind: EventEntry, Time: start}}, start)
+	holdings[1].USDPrice = 0.03
+	result = scorer.Observe(ctx, "wallet", holdings, nil, start.Add(time.Hour))
+	assert.Equal(t, 1, result.Features.EntryTiming.Samples)
+	assert.Greater(t, result.Features.EntryTiming.Value, 0.99)
+	assert.Equal(t, Feature{Value: 1, Samples: 1}, result.Features.Accumulation)
+
+	// 一天内清仓
+	exitAt := start.Add(24 * time.Hour)
+	result = scorer.Observe(ctx, "wallet", holdings[:1], []Event{{Mint: "early", Kind: EventExit, Time: exitAt}}, exitAt)
+	assert.Equal(t, 1, result.Features.ExitSpeed.Samples)
+	assert.InDelta(t, 1-1.0/30, result.Features.ExitSpeed.Value, 0.0001)
+
+	// 清仓基线持仓不计入清仓速度
+	result = scorer.Observe(ctx, "wallet", nil, []Event{{Mint: "old", Kind: EventExit, Time: exitAt}}, exitAt)
+	assert.Equal(t, 1, result.Features.ExitSpeed.Samples)
+	assert.Zero(t, result.Features.Concentration.Samples)
+	assert.Equal(t, 1, launches.calls)
+
+	require.NoError(t, scorer.Save())
+	reloaded := NewScorer(launches, dir)
+	saved, exists := reloaded.Get("wallet")
+	require.True(t, exists)
+	assert.Equal(t, result.Score, saved.Score)
+	assert.Len(t, reloaded.History("wallet"), 5)
+}
+
+func TestAccumulationWithoutSpike(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	scorer := NewScorer(nil, t.TempDir())
+	ctx := context.Background()
+	holdings := []Holding{{Mint: "token", USDPrice: 1}}
+
+	scorer.Observe(ctx, "wallet", holdings, []Event{{Mint: "token", Kind: EventIncrease, Time: start}}, start)
+	result := scorer.Observe(ctx, "wallet", holdings, nil, start.Add(time.Hour))
+	assert.Zero(t, result.Features.Accumulation.Samples, "still inside the observation window")
+
+	result = scorer.Observe(ctx, "wallet", holdings, nil, start.Add(spikeWindow))
+	assert.Equal(t, Feature{Value: 0, Samples: 1}, result.Features.Accumulation)
+}
+
+func TestHistoryRecordsOnlyScoreChanges(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	scorer := NewScorer(nil, t.TempDir())
+	ctx := context.Background()
+	holdings := []Holding{{Mint: "token", USDPrice: 1, SupplyShare: 1}}
+
+	// 每分钟扫描一次、评分不变时只保留第一个点
+	for i := 0; i < 2*maxHistory; i++ {
+		scorer.Observe(ctx, "wallet", holdings, nil, start.Add(time.Duration(i)*time.Minute))
+	}
+	history := scorer.History("wallet")
+	require.Len(t, history, 1)
+	assert.Equal(t, start, history[0].Time)
+	assert.InDelta(t, 20, history[0].Score, 0.0001)
+
+	// 小于显示精度的波动不记录
+	holdings[0].SupplyShare = 1.01
+	result := scorer.Observe(ctx, "wallet", holdings, nil, start.Add(time.Duration(2*maxHistory)*time.Minute))
+	assert.InDelta(t, 20.2, result.Score, 0.0001)
+	assert.Len(t, scorer.History("wallet"), 1)
+
+	changedAt := start.Add(24 * time.Hour)
+	holdings[0].SupplyShare = 2
+	result = scorer.Observe(ctx, "wallet", holdings, nil, changedAt)
+	assert.InDelta(t, 40, result.Score, 0.0001)
+	assert.InDelta(t, 20, result.Previous, 0.0001)
+	history = scorer.History("wallet")
+	require.Len(t, history, 2)
+	assert.Equal(t, changedAt, history[1].Time)
+
+	// 评分稳定后仍返回最近一次变化之前的评分
+	scorer.Observe(ctx, "wallet", holdings, nil, changedAt.Add(time.Minute))
+	saved, exists := scorer.Get("wallet")
+	require.True(t, exists)
+	assert.InDelta(t, 40, saved.Score, 0.0001)
+	assert.InDelta(t, 20, saved.Previous, 0.0001)
+}
+
+func TestLaunchLookupResumesAfterMiss(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	launches := &fakeLaunches{
+		launches: map[string]time.Time{"busy": start.Add(-time.Hour)},
+		pages:    map[string]int{"busy": 1},
+	}
+	dir := t.TempDir()
+	scorer := NewScorer(launches, dir)
+	ctx := context.Background()
+
+	// 第一次查找未翻到第一笔交易，保存游标
+	scorer.Observe(ctx, "a", nil, []Event{{Mint: "busy", Kind: EventEntry, Time: start}}, start)
+	_, found := scorer.LaunchTime("busy")
+	assert.False(t, found)
+	require.NoError(t, scorer.Save())
+
+	// 重启后的下一次查找从游标处继续
+	scorer = NewScorer(launches, dir)
+	result := scorer.Observe(ctx, "b", nil, []Event{{Mint: "busy", Kind: EventEntry, Time: start}}, start)
+	launch, found := scorer.LaunchTime("busy")
+	assert.True(t, found)
+	assert.Equal(t, start.Add(-time.Hour), launch)
+	assert.Equal(t, []string{"", "cursor-1"}, launches.cursors)
+	assert.Equal(t, 1, result.Features.EntryTiming.Samples)
+
+	// 没有游标的未命中在重试间隔内不再查找，之后重新查找
+	scorer.Observe(ctx, "a", nil, []Event{{Mint: "unknown", Kind: EventEntry, Time: start}}, start)
+	scorer.Observe(ctx, "b", nil, []Event{{Mint: "unknown", Kind: EventEntry, Time: start}}, start.Add(time.Hour))
+	assert.Equal(t, 3, launches.calls)
+	scorer.Observe(ctx, "b", nil, []Event{{Mint: "unknown", Kind: EventEntry, Time: start}}, start.Add(launchRetryInterval))
+	assert.Equal(t, 4, launches.calls)
+}
@@ -8,7 +8,10 @@ import (
 	"path/filepath"
 	"time"
 
+	"github.com/accursedgalaxy/insider-monitor/internal/alerts"
+	"github.com/accursedgalaxy/insider-monitor/internal/holders"
 	"github.com/accursedgalaxy/insider-monitor/internal/monitor"
+	"github.com/accursedgalaxy/insider-monitor/internal/window"
 )
 
 type Storage struct {
@@ -91,3 +94,166 @@ func (s *Storage) BackupCurrentData() error {
 	}
 	return os.WriteFile(backupPath, file, 0644)
 }
+
+// SnapshotRetention 为带时间戳的钱包快照保留时长，更早的快照在保存新快照时删除
+const SnapshotRetention = 7 * 24 * time.Hour
+
+// SaveSnapshot 将钱包数据另存为 wallet_data_<unix>.json 快照，供 -replay 回放，并删除超过保留时长的快照
+func (s *Storage) SaveSnapshot(data map[string]*monitor.WalletData, at time.Time) error {
+	if err := os.MkdirAll(s.dataDir, 0755); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	path := filepath.Join(s.dataDir, fmt.Sprintf("wallet_data_%d.json", at.Unix()))
+	file, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+	if err := os.WriteFile(path, file, 0644); err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(s.dataDir)
+	if err != nil {
+		return fmt.Errorf("failed to read data directory: %w", err)
+	}
+	cutoff := at.Add(-SnapshotRetention).Unix()
+	for _, entry := range entries {
+		var taken int64
+		if _, err := fmt.Sscanf(entry.Name(), "wallet_data_%d.json", &taken); err != nil || taken >= cutoff {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.dataDir, entry.Name())); err != nil {
+			return fmt.Errorf("failed to remove expired snapshot: %w", err)
+		}
+	}
+	return nil
+}
+
+// SaveWalletStatus 保存各钱包的扫描健康状况
+func (s *Storage) SaveWalletStatus(statuses map[string]*monitor.WalletStatus) error {
+	if err := os.MkdirAll(s.dataDir, 0755); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	path := filepath.Join(s.dataDir, "wallet_status.json")
+	file, err := json.MarshalIndent(statuses, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal wallet status: %w", err)
+	}
+	return os.WriteFile(path, file, 0644)
+}
+
+// LoadWalletStatus 读取各钱包的扫描健康状况，文件不存在时返回空映射
+func (s *Storage) LoadWalletStatus() (map[string]*monitor.WalletStatus, error) {
+	statuses := make(map[string]*monitor.WalletStatus)
+
+	file, err := os.ReadFile(filepath.Join(s.dataDir, "wallet_status.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return statuses, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(file, &statuses); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal wallet status: %w", err)
+	}
+	return statuses, nil
+}
+
+// SaveHolderSnapshots 保存各铸币的主要持有者快照
+func (s *Storage) SaveHolderSnapshots(snapshots map[string]*holders.Snapshot) error {
+	if err := os.MkdirAll(s.dataDir, 0755); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	path := filepath.Join(s.dataDir, "holders.json")
+	file, err := json.MarshalIndent(snapshots, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal holder snapshots: %w", err)
+	}
+	return os.WriteFile(path, file, 0644)
+}
+
+// LoadHolderSnapshots 读取各铸币的主要持有者快照，文件不存在时返回空映射
+func (s *Storage) LoadHolderSnapshots() (map[string]*holders.Snapshot, error) {
+	snapshots := make(map[string]*holders.Snapshot)
+
+	file, err := os.ReadFile(filepath.Join(s.dataDir, "holders.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return snapshots, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(file, &snapshots); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal holder snapshots: %w", err)
+	}
+	return snapshots, nil
+}
+
+// SaveWindowState 保存滚动窗口的余额序列
+func (s *Storage) SaveWindowState(state window.State) error {
+	if err := os.MkdirAll(s.dataDir, 0755); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	path := filepath.Join(s.dataDir, "windows.json")
+	file, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal window state: %w", err)
+	}
+	return os.WriteFile(path, file, 0644)
+}
+
+// LoadWindowState 读取滚动窗口的余额序列，文件不存在时返回空状态
+func (s *Storage) LoadWindowState() (window.State, error) {
+	state := make(window.State)
+
+	file, err := os.ReadFile(filepath.Join(s.dataDir, "windows.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(file, &state); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal window state: %w", err)
+	}
+	return state, nil
+}
+
+// SaveSuppressionState 保存告警去重的指纹状态
+func (s *Storage) SaveSuppressionState(state alerts.SuppressionState) error {
+	if err := os.MkdirAll(s.dataDir, 0755); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	path := filepath.Join(s.dataDir, "alert_suppression.json")
+	file, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal alert suppression state: %w", err)
+	}
+	return os.WriteFile(path, file, 0644)
+}
+
+// LoadSuppressionState 读取告警去重的指纹状态，文件不存在时返回空状态
+func (s *Storage) LoadSuppressionState() (alerts.SuppressionState, error) {
+	state := make(alerts.SuppressionState)
+
+	file, err := os.ReadFile(filepath.Join(s.dataDir, "alert_suppression.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(file, &state); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal alert suppression state: %w", err)
+	}
+	return state, nil
+}
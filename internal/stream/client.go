@@ -0,0 +1,342 @@
+package stream
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	writeWait  = 10 * time.Second
+	pongWait   = 60 * time.Second
+	pingPeriod = (pongWait * 9) / 10
+	// 等待订阅回复的最长时间，超时后调用方按断线处理
+	subscribeTimeout = 30 * time.Second
+)
+
+// ErrClosed 表示连接已关闭
+var ErrClosed = errors.New("websocket connection closed")
+
+// AccountNotification 为 accountSubscribe 推送的账户更新
+type AccountNotification struct {
+	Subscription uint64
+	Slot         uint64
+	Lamports     uint64
+	Owner        string
+	Data         []byte
+}
+
+type rpcRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      uint64        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type rpcMessage struct {
+	ID     *uint64         `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+	Method string `json:"method"`
+	Params *struct {
+		Subscription uint64 `json:"subscription"`
+		Result       struct {
+			Context struct {
+				Slot uint64 `json:"slot"`
+			} `json:"context"`
+			Value *struct {
+				Lamports uint64   `json:"lamports"`
+				Owner    string   `json:"owner"`
+				Data     []string `json:"data"`
+			} `json:"value"`
+		} `json:"result"`
+	} `json:"params"`
+}
+
+type subscribeResult struct {
+	id  uint64
+	err error
+}
+
+// Client 是一个精简的 Solana WebSocket 订阅客户端，
+// 所有订阅复用同一连接并通过单一通道推送通知。
+// 通知按订阅合并：消费方处理不及时时只保留每个订阅的最新通知，读取循环从不阻塞，
+// 因此消费方可以在处理通知的同时发起新的订阅
+type Client struct {
+	conn             *websocket.Conn
+	writeMutex       sync.Mutex
+	mutex            sync.Mutex
+	nextID           uint64
+	pending          map[uint64]chan subscribeResult
+	subscribeTimeout time.Duration
+	notifications    chan AccountNotification
+	done             chan struct{}
+	closeOnce        sync.Once
+	err              error
+
+	// 尚未投递的通知：订阅 -> 最新通知，order 为首次排队的顺序
+	queueMutex sync.Mutex
+	queued     map[uint64]AccountNotification
+	order      []uint64
+	wake       chan struct{}
+}
+
+// Dial 连接到 RPC WebSocket 端点
+func Dial(ctx context.Context, url string) (*Client, error) {
+	dialer := &websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: writeWait,
+	}
+
+	conn, _, err := dialer.DialContext(ctx, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial websocket %s: %w", url, err)
+	}
+
+	c := &Client{
+		conn:             conn,
+		pending:          make(map[uint64]chan subscribeResult),
+		subscribeTimeout: subscribeTimeout,
+		notifications:    make(chan AccountNotification),
+		done:             make(chan struct{}),
+		queued:           make(map[uint64]AccountNotification),
+		wake:             make(chan struct{}, 1),
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	go c.readLoop()
+	go c.deliverLoop()
+	go c.pingLoop()
+	return c, nil
+}
+
+// Notifications 返回账户更新通道
+func (c *Client) Notifications() <-chan AccountNotification {
+	return c.notifications
+}
+
+// Done 在连接断开后关闭
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err 返回导致连接断开的错误
+func (c *Client) Err() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.err
+}
+
+// Close 关闭连接
+func (c *Client) Close() {
+	c.shutdown(ErrClosed)
+}
+
+// AccountSubscribe 订阅账户变化并返回订阅 ID，超过 subscribeTimeout 未收到回复时返回错误
+func (c *Client) AccountSubscribe(ctx context.Context, account string, commitment string) (uint64, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.subscribeTimeout)
+	defer cancel()
+
+	config := map[string]interface{}{
+		"encoding": "base64",
+	}
+	if commitment != "" {
+		config["commitment"] = commitment
+	}
+
+	c.mutex.Lock()
+	c.nextID++
+	id := c.nextID
+	result := make(chan subscribeResult, 1)
+	c.pending[id] = result
+	c.mutex.Unlock()
+
+	req := rpcRequest{
+		JSONRPC: "2.0",
+		ID:      id,
+		Method:  "accountSubscribe",
+		Params:  []interface{}{account, config},
+	}
+	if err := c.write(req); err != nil {
+		c.mutex.Lock()
+		delete(c.pending, id)
+		c.mutex.Unlock()
+		return 0, err
+	}
+
+	select {
+	case res := <-result:
+		return res.id, res.err
+	case <-c.done:
+		return 0, c.Err()
+	case <-ctx.Done():
+		c.mutex.Lock()
+		delete(c.pending, id)
+		c.mutex.Unlock()
+		return 0, fmt.Errorf("accountSubscribe %s: %w", account, ctx.Err())
+	}
+}
+
+func (c *Client) write(v interface{}) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+	_ = c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	if err := c.conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+		return fmt.Errorf("failed to write request: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) readLoop() {
+	for {
+		_, payload, err := c.conn.ReadMessage()
+		if err != nil {
+			c.shutdown(err)
+			return
+		}
+
+		var msg rpcMessage
+		if err := json.Unmarshal(payload, &msg); err != nil {
+			continue // 忽略无法识别的消息
+		}
+
+		switch {
+		case msg.ID != nil:
+			c.handleResponse(*msg.ID, msg)
+		case msg.Method == "accountNotification" && msg.Params != nil:
+			c.handleNotification(msg)
+		}
+	}
+}
+
+func (c *Client) handleResponse(id uint64, msg rpcMessage) {
+	c.mutex.Lock()
+	result, exists := c.pending[id]
+	delete(c.pending, id)
+	c.mutex.Unlock()
+	if !exists {
+		return
+	}
+
+	if msg.Error != nil {
+		result <- subscribeResult{err: fmt.Errorf("subscribe failed (%d): %s", msg.Error.Code, msg.Error.Message)}
+		return
+	}
+
+	var subID uint64
+	if err := json.Unmarshal(msg.Result, &subID); err != nil {
+		result <- subscribeResult{err: fmt.Errorf("invalid subscription id: %w", err)}
+		return
+	}
+	result <- subscribeResult{id: subID}
+}
+
+func (c *Client) handleNotification(msg rpcMessage) {
+	notification := AccountNotification{
+		Subscription: msg.Params.Subscription,
+		Slot:         msg.Params.Result.Context.Slot,
+	}
+
+	// 账户被关闭时 value 为空，保持零值通知
+	if value := msg.Params.Result.Value; value != nil {
+		notification.Lamports = value.Lamports
+		notification.Owner = value.Owner
+		if len(value.Data) > 0 {
+			data, err := base64.StdEncoding.DecodeString(value.Data[0])
+			if err == nil {
+				notification.Data = data
+			}
+		}
+	}
+
+	// 只排队不等待消费方，同一订阅尚未投递的旧通知被新通知取代
+	c.queueMutex.Lock()
+	if _, queued := c.queued[notification.Subscription]; !queued {
+		c.order = append(c.order, notification.Subscription)
+	}
+	c.queued[notification.Subscription] = notification
+	c.queueMutex.Unlock()
+
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+}
+
+// deliverLoop 按排队顺序将通知投递给消费方
+func (c *Client) deliverLoop() {
+	for {
+		c.queueMutex.Lock()
+		if len(c.order) == 0 {
+			c.queueMutex.Unlock()
+			select {
+			case <-c.wake:
+				continue
+			case <-c.done:
+				return
+			}
+		}
+		subscription := c.order[0]
+		c.order = c.order[1:]
+		notification := c.queued[subscription]
+		delete(c.queued, subscription)
+		c.queueMutex.Unlock()
+
+		select {
+		case c.notifications <- notification:
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func (c *Client) pingLoop() {
+	ticker := time.NewTicker(pingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				c.shutdown(err)
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func (c *Client) shutdown(err error) {
+	c.closeOnce.Do(func() {
+		c.mutex.Lock()
+		c.err = err
+		for id, result := range c.pending {
+			result <- subscribeResult{err: err}
+			delete(c.pending, id)
+		}
+		c.mutex.Unlock()
+
+		close(c.done)
+		c.conn.Close()
+	})
+}
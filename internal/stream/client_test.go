@@ -0,0 +1,101 @@
+package stream
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveSocket 启动一个 WebSocket 服务端，handle 处理每个连接
+func serveSocket(t *testing.T, handle func(conn *websocket.Conn)) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+		handle(conn)
+	}))
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func notification(subscription, slot uint64) map[string]interface{} {
+	return map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  "accountNotification",
+		"params": map[string]interface{}{
+			"subscription": subscription,
+			"result": map[string]interface{}{
+				"context": map[string]interface{}{"slot": slot},
+				"value":   map[string]interface{}{"lamports": slot, "owner": "o", "data": []string{"", "base64"}},
+			},
+		},
+	}
+}
+
+func TestNotificationsDoNotBlockSubscribe(t *testing.T) {
+	url := serveSocket(t, func(conn *websocket.Conn) {
+		for round := uint64(0); ; round++ {
+			var req rpcRequest
+			if err := conn.ReadJSON(&req); err != nil {
+				return
+			}
+			// 在回复订阅前推送大量通知，消费方此时尚未读取通知
+			for slot := round*5000 + 1; slot <= (round+1)*5000; slot++ {
+				if err := conn.WriteJSON(notification(1+slot%2, slot)); err != nil {
+					return
+				}
+			}
+			if err := conn.WriteJSON(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": req.ID}); err != nil {
+				return
+			}
+		}
+	})
+
+	client, err := Dial(context.Background(), url)
+	require.NoError(t, err)
+	defer client.Close()
+
+	for i := 0; i < 2; i++ {
+		_, err := client.AccountSubscribe(context.Background(), "account", "confirmed")
+		require.NoError(t, err)
+	}
+
+	// 每个订阅的通知被合并，最终只保留最新的一条
+	latest := make(map[uint64]uint64)
+	timeout := time.After(5 * time.Second)
+	for latest[1] != 10000 || latest[2] != 9999 {
+		select {
+		case n := <-client.Notifications():
+			assert.Greater(t, n.Slot, latest[n.Subscription], "notifications are delivered in order")
+			latest[n.Subscription] = n.Slot
+		case <-timeout:
+			t.Fatalf("timed out waiting for the latest notifications, got %v", latest)
+		}
+	}
+}
+
+func TestAccountSubscribeTimesOut(t *testing.T) {
+	url := serveSocket(t, func(conn *websocket.Conn) {
+		// 读取请求但从不回复
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+
+	client, err := Dial(context.Background(), url)
+	require.NoError(t, err)
+	defer client.Close()
+	client.subscribeTimeout = 50 * time.Millisecond
+
+	_, err = client.AccountSubscribe(context.Background(), "account", "")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
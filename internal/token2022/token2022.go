@@ -0,0 +1,428 @@
+package token2022
+
+import (
+	"encoding/binary"
+	"fmt"
+	"slices"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/token"
+)
+
+// 账户布局常量：Token-2022 与旧版 SPL Token 共享基础布局，
+// 扩展数据紧随账户类型字节之后以 TLV 形式存储
+const (
+	AccountLen        = 165
+	MintLen           = 82
+	accountTypeOffset = AccountLen
+	tlvStartOffset    = AccountLen + 1
+	tlvHeaderLen      = 4
+)
+
+// AccountType 标识扩展数据前的账户类型字节
+type AccountType uint8
+
+const (
+	AccountTypeUninitialized AccountType = 0
+	AccountTypeMint          AccountType = 1
+	AccountTypeAccount       AccountType = 2
+)
+
+// ExtensionType 为 Token-2022 扩展类型编号
+type ExtensionType uint16
+
+const (
+	ExtensionUninitialized                 ExtensionType = 0
+	ExtensionTransferFeeConfig             ExtensionType = 1
+	ExtensionTransferFeeAmount             ExtensionType = 2
+	ExtensionMintCloseAuthority            ExtensionType = 3
+	ExtensionConfidentialTransferMint      ExtensionType = 4
+	ExtensionConfidentialTransferAccount   ExtensionType = 5
+	ExtensionDefaultAccountState           ExtensionType = 6
+	ExtensionImmutableOwner                ExtensionType = 7
+	ExtensionMemoTransfer                  ExtensionType = 8
+	ExtensionNonTransferable               ExtensionType = 9
+	ExtensionInterestBearingConfig         ExtensionType = 10
+	ExtensionCpiGuard                      ExtensionType = 11
+	ExtensionPermanentDelegate             ExtensionType = 12
+	ExtensionNonTransferableAccount        ExtensionType = 13
+	ExtensionTransferHook                  ExtensionType = 14
+	ExtensionTransferHookAccount           ExtensionType = 15
+	ExtensionConfidentialTransferFeeConfig ExtensionType = 16
+	ExtensionConfidentialTransferFeeAmount ExtensionType = 17
+	ExtensionMetadataPointer               ExtensionType = 18
+	ExtensionTokenMetadata                 ExtensionType = 19
+	ExtensionGroupPointer                  ExtensionType = 20
+	ExtensionTokenGroup                    ExtensionType = 21
+	ExtensionGroupMemberPointer            ExtensionType = 22
+	ExtensionTokenGroupMember              ExtensionType = 23
+)
+
+var extensionNames = map[ExtensionType]string{
+	ExtensionTransferFeeConfig:             "transfer_fee_config",
+	ExtensionTransferFeeAmount:             "transfer_fee_amount",
+	ExtensionMintCloseAuthority:            "mint_close_authority",
+	ExtensionConfidentialTransferMint:      "confidential_transfer_mint",
+	ExtensionConfidentialTransferAccount:   "confidential_transfer_account",
+	ExtensionDefaultAccountState:           "default_account_state",
+	ExtensionImmutableOwner:                "immutable_owner",
+	ExtensionMemoTransfer:                  "memo_transfer",
+	ExtensionNonTransferable:               "non_transferable",
+	ExtensionInterestBearingConfig:         "interest_bearing_config",
+	ExtensionCpiGuard:                      "cpi_guard",
+	ExtensionPermanentDelegate:             "permanent_delegate",
+	ExtensionNonTransferableAccount:        "non_transferable_account",
+	ExtensionTransferHook:                  "transfer_hook",
+	ExtensionTransferHookAccount:           "transfer_hook_account",
+	ExtensionConfidentialTransferFeeConfig: "confidential_transfer_fee_config",
+	ExtensionConfidentialTransferFeeAmount: "confidential_transfer_fee_amount",
+	ExtensionMetadataPointer:               "metadata_pointer",
+	ExtensionTokenMetadata:                 "token_metadata",
+	ExtensionGroupPointer:                  "group_pointer",
+	ExtensionTokenGroup:                    "token_group",
+	ExtensionGroupMemberPointer:            "group_member_pointer",
+	ExtensionTokenGroupMember:              "token_group_member",
+}
+
+func (t ExtensionType) String() string {
+	if name, ok := extensionNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown_%d", uint16(t))
+}
+
+// Extension 为一条原始 TLV 扩展记录
+type Extension struct {
+	Type ExtensionType
+	Data []byte
+}
+
+// AccountExtensions 汇总代币账户上与监控相关的扩展信息
+type AccountExtensions struct {
+	Types                []string `json:"types"`
+	TransferFeeWithheld  uint64   `json:"transfer_fee_withheld,omitempty"`
+	ConfidentialTransfer bool     `json:"confidential_transfer,omitempty"`
+	ConfidentialApproved bool     `json:"confidential_approved,omitempty"`
+	NonTransferable      bool     `json:"non_transferable,omitempty"`
+	ImmutableOwner       bool     `json:"immutable_owner,omitempty"`
+	MemoTransferRequired bool     `json:"memo_transfer_required,omitempty"`
+	CpiGuardEnabled      bool     `json:"cpi_guard_enabled,omitempty"`
+}
+
+// Flags 返回便于在告警中展示的扩展标记
+func (e *AccountExtensions) Flags() []string {
+	if e == nil {
+		return nil
+	}
+	var flags []string
+	if e.NonTransferable {
+		flags = append(flags, "non-transferable")
+	}
+	if e.ConfidentialTransfer {
+		flags = append(flags, "confidential")
+	}
+	if e.TransferFeeWithheld > 0 {
+		flags = append(flags, fmt.Sprintf("fee-withheld:%d", e.TransferFeeWithheld))
+	}
+	if e.MemoTransferRequired {
+		flags = append(flags, "memo-required")
+	}
+	if e.CpiGuardEnabled {
+		flags = append(flags, "cpi-guard")
+	}
+	return flags
+}
+
+// DecodeAccount 解码代币账户，兼容旧版 SPL Token 与 Token-2022（含扩展）
+func DecodeAccount(data []byte) (*token.Account, []Extension, error) {
+	if len(data) < AccountLen {
+		return nil, nil, fmt.Errorf("token account data too short: %d bytes", len(data))
+	}
+
+	var account token.Account
+	if err := bin.NewBinDecoder(data[:AccountLen]).Decode(&account); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode token account: %w", err)
+	}
+
+	if len(data) == AccountLen {
+		return &account, nil, nil
+	}
+
+	if AccountType(data[accountTypeOffset]) != AccountTypeAccount {
+		return nil, nil, fmt.Errorf("unexpected account type %d", data[accountTypeOffset])
+	}
+
+	extensions, err := parseTLV(data[tlvStartOffset:])
+	if err != nil {
+		return nil, nil, err
+	}
+	return &account, extensions, nil
+}
+
+// DecodeMint 解码铸币账户，兼容旧版 SPL Token 与 Token-2022（含扩展）
+func DecodeMint(data []byte) (*token.Mint, []Extension, error) {
+	if len(data) < MintLen {
+		return nil, nil, fmt.Errorf("mint data too short: %d bytes", len(data))
+	}
+
+	var mint token.Mint
+	if err := bin.NewBinDecoder(data[:MintLen]).Decode(&mint); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode mint: %w", err)
+	}
+
+	// Token-2022 铸币账户会填充到代币账户长度，再跟随账户类型字节与扩展
+	if len(data) <= AccountLen {
+		return &mint, nil, nil
+	}
+
+	if AccountType(data[accountTypeOffset]) != AccountTypeMint {
+		return nil, nil, fmt.Errorf("unexpected account type %d", data[accountTypeOffset])
+	}
+
+	extensions, err := parseTLV(data[tlvStartOffset:])
+	if err != nil {
+		return nil, nil, err
+	}
+	return &mint, extensions, nil
+}
+
+// TokenMetadata 为 Token-2022 内嵌元数据扩展的内容
+type TokenMetadata struct {
+	UpdateAuthority string
+	Mint            string
+	Name            string
+	Symbol          string
+	URI             string
+}
+
+// FindTokenMetadata 从铸币扩展中解析内嵌的代币元数据
+func FindTokenMetadata(extensions []Extension) (*TokenMetadata, error) {
+	for _, ext := range extensions {
+		if ext.Type != ExtensionTokenMetadata {
+			continue
+		}
+
+		data := ext.Data
+		if len(data) < 64 {
+			return nil, fmt.Errorf("token metadata extension too short: %d bytes", len(data))
+		}
+
+		metadata := &TokenMetadata{
+			Mint: solana.PublicKeyFromBytes(data[32:64]).String(),
+		}
+		// 全零公钥表示更新权限已放弃
+		if authority := solana.PublicKeyFromBytes(data[:32]); !authority.IsZero() {
+			metadata.UpdateAuthority = authority.String()
+		}
+
+		offset := 64
+		fields := []*string{&metadata.Name, &metadata.Symbol, &metadata.URI}
+		for _, target := range fields {
+			value, next, err := readString(data, offset)
+			if err != nil {
+				return nil, err
+			}
+			*target = value
+			offset = next
+		}
+		return metadata, nil
+	}
+	return nil, nil
+}
+
+// readString 读取 borsh 编码的字符串（u32 长度前缀）
+func readString(data []byte, offset int) (string, int, error) {
+	if offset+4 > len(data) {
+		return "", 0, fmt.Errorf("string length at offset %d overflows data", offset)
+	}
+	length := int(binary.LittleEndian.Uint32(data[offset:]))
+	offset += 4
+	if offset+length > len(data) {
+		return "", 0, fmt.Errorf("string of length %d at offset %d overflows data", length, offset)
+	}
+	return string(data[offset : offset+length]), offset + length, nil
+}
+
+// SummarizeAccountExtensions 将原始扩展转换为账户扩展摘要
+func SummarizeAccountExtensions(extensions []Extension) *AccountExtensions {
+	if len(extensions) == 0 {
+		return nil
+	}
+
+	summary := &AccountExtensions{}
+	for _, ext := range extensions {
+		summary.Types = append(summary.Types, ext.Type.String())
+
+		switch ext.Type {
+		case ExtensionTransferFeeAmount:
+			if len(ext.Data) >= 8 {
+				summary.TransferFeeWithheld = binary.LittleEndian.Uint64(ext.Data[:8])
+			}
+		case ExtensionConfidentialTransferAccount:
+			summary.ConfidentialTransfer = true
+			if len(ext.Data) >= 1 {
+				summary.ConfidentialApproved = ext.Data[0] == 1
+			}
+		case ExtensionNonTransferableAccount:
+			summary.NonTransferable = true
+		case ExtensionImmutableOwner:
+			summary.ImmutableOwner = true
+		case ExtensionMemoTransfer:
+			summary.MemoTransferRequired = len(ext.Data) >= 1 && ext.Data[0] == 1
+		case ExtensionCpiGuard:
+			summary.CpiGuardEnabled = len(ext.Data) >= 1 && ext.Data[0] == 1
+		}
+	}
+	return summary
+}
+
+// Merge 合并同一铸币下另一个账户的扩展信息：扩展类型取并集，
+// 被扣留的转账手续费累加，其余标记只要任一账户启用即视为启用
+func (e *AccountExtensions) Merge(other *AccountExtensions) *AccountExtensions {
+	if e == nil {
+		return other
+	}
+	if other == nil {
+		return e
+	}
+
+	merged := *e
+	merged.Types = append([]string(nil), e.Types...)
+	for _, extType := range other.Types {
+		if !slices.Contains(merged.Types, extType) {
+			merged.Types = append(merged.Types, extType)
+		}
+	}
+	merged.TransferFeeWithheld += other.TransferFeeWithheld
+	merged.ConfidentialTransfer = e.ConfidentialTransfer || other.ConfidentialTransfer
+	merged.ConfidentialApproved = e.ConfidentialApproved || other.ConfidentialApproved
+	merged.NonTransferable = e.NonTransferable || other.NonTransferable
+	merged.ImmutableOwner = e.ImmutableOwner || other.ImmutableOwner
+	merged.MemoTransferRequired = e.MemoTransferRequired || other.MemoTransferRequired
+	merged.CpiGuardEnabled = e.CpiGuardEnabled || other.CpiGuardEnabled
+	return &merged
+}
+
+// MintExtensions 汇总铸币上与监控相关的扩展配置
+type MintExtensions struct {
+	Types []string `json:"types"`
+	// 转账手续费配置，取较新的一档费率
+	TransferFeeBasisPoints uint16 `json:"transfer_fee_basis_points,omitempty"`
+	TransferFeeMaximum     uint64 `json:"transfer_fee_maximum,omitempty"`
+	TransferFeeAuthority   string `json:"transfer_fee_authority,omitempty"`
+	// 转账钩子程序，为空表示未设置
+	TransferHookProgram   string `json:"transfer_hook_program,omitempty"`
+	TransferHookAuthority string `json:"transfer_hook_authority,omitempty"`
+	DefaultFrozen         bool   `json:"default_frozen,omitempty"` // 新账户默认处于冻结状态
+	NonTransferable       bool   `json:"non_transferable,omitempty"`
+	PermanentDelegate     string `json:"permanent_delegate,omitempty"`
+}
+
+// Flags 返回便于在告警中展示的铸币扩展标记
+func (e *MintExtensions) Flags() []string {
+	if e == nil {
+		return nil
+	}
+	var flags []string
+	if slices.Contains(e.Types, ExtensionTransferFeeConfig.String()) {
+		flags = append(flags, fmt.Sprintf("transfer-fee:%dbps", e.TransferFeeBasisPoints))
+	}
+	if e.TransferHookProgram != "" {
+		flags = append(flags, "transfer-hook:"+e.TransferHookProgram)
+	}
+	if e.DefaultFrozen {
+		flags = append(flags, "default-frozen")
+	}
+	if e.NonTransferable {
+		flags = append(flags, "non-transferable")
+	}
+	if e.PermanentDelegate != "" {
+		flags = append(flags, "permanent-delegate:"+e.PermanentDelegate)
+	}
+	return flags
+}
+
+// 铸币扩展数据布局
+const (
+	// TransferFeeConfig：两个权限地址、被扣留金额、较旧与较新两档费率（epoch、上限、基点）
+	transferFeeNewerOffset = 32 + 32 + 8 + 18
+	transferFeeConfigLen   = transferFeeNewerOffset + 18
+	// TransferHook：权限地址与钩子程序
+	transferHookLen = 64
+	// DefaultAccountState 中表示冻结的账户状态
+	accountStateFrozen = 2
+)
+
+// SummarizeMintExtensions 将原始扩展转换为铸币扩展摘要
+func SummarizeMintExtensions(extensions []Extension) *MintExtensions {
+	if len(extensions) == 0 {
+		return nil
+	}
+
+	summary := &MintExtensions{}
+	for _, ext := range extensions {
+		summary.Types = append(summary.Types, ext.Type.String())
+
+		switch ext.Type {
+		case ExtensionTransferFeeConfig:
+			if len(ext.Data) >= transferFeeConfigLen {
+				summary.TransferFeeAuthority = optionalPubkey(ext.Data[:32])
+				summary.TransferFeeMaximum = binary.LittleEndian.Uint64(ext.Data[transferFeeNewerOffset+8:])
+				summary.TransferFeeBasisPoints = binary.LittleEndian.Uint16(ext.Data[transferFeeNewerOffset+16:])
+			}
+		case ExtensionTransferHook:
+			if len(ext.Data) >= transferHookLen {
+				summary.TransferHookAuthority = optionalPubkey(ext.Data[:32])
+				summary.TransferHookProgram = optionalPubkey(ext.Data[32:64])
+			}
+		case ExtensionDefaultAccountState:
+			summary.DefaultFrozen = len(ext.Data) >= 1 && ext.Data[0] == accountStateFrozen
+		case ExtensionNonTransferable:
+			summary.NonTransferable = true
+		case ExtensionPermanentDelegate:
+			if len(ext.Data) >= 32 {
+				summary.PermanentDelegate = optionalPubkey(ext.Data[:32])
+			}
+		}
+	}
+	return summary
+}
+
+// optionalPubkey 解码可为空的公钥，全零表示未设置
+func optionalPubkey(data []byte) string {
+	key := solana.PublicKeyFromBytes(data[:32])
+	if key.IsZero() {
+		return ""
+	}
+	return key.String()
+}
+
+// parseTLV 解析 Token-2022 的类型-长度-值扩展区
+func parseTLV(data []byte) ([]Extension, error) {
+	var extensions []Extension
+	offset := 0
+
+	for offset+tlvHeaderLen <= len(data) {
+		extType := ExtensionType(binary.LittleEndian.Uint16(data[offset:]))
+		length := int(binary.LittleEndian.Uint16(data[offset+2:]))
+		offset += tlvHeaderLen
+
+		// 未初始化的类型表示扩展区剩余部分为填充
+		if extType == ExtensionUninitialized {
+			break
+		}
+
+		if offset+length > len(data) {
+			return nil, fmt.Errorf("extension %s overflows account data", extType)
+		}
+
+		extensions = append(extensions, Extension{
+			Type: extType,
+			Data: data[offset : offset+length],
+		})
+		offset += length
+	}
+
+	return extensions, nil
+}
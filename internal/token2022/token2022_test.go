@@ -0,0 +1,170 @@
+package token2022
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encodeAccount(t *testing.T, account token.Account) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, bin.NewBinEncoder(&buf).Encode(account))
+	return buf.Bytes()
+}
+
+func appendTLV(data []byte, extType ExtensionType, value []byte) []byte {
+	header := make([]byte, tlvHeaderLen)
+	binary.LittleEndian.PutUint16(header, uint16(extType))
+	binary.LittleEndian.PutUint16(header[2:], uint16(len(value)))
+	data = append(data, header...)
+	return append(data, value...)
+}
+
+func TestDecodeAccount(t *testing.T) {
+	base := token.Account{
+		Mint:   solana.NewWallet().PublicKey(),
+		Owner:  solana.NewWallet().PublicKey(),
+		Amount: 42,
+		State:  token.Initialized,
+	}
+
+	t.Run("Legacy account", func(t *testing.T) {
+		account, extensions, err := DecodeAccount(encodeAccount(t, base))
+		require.NoError(t, err)
+		assert.Equal(t, uint64(42), account.Amount)
+		assert.Empty(t, extensions)
+		assert.Nil(t, SummarizeAccountExtensions(extensions))
+	})
+
+	t.Run("Token-2022 account with extensions", func(t *testing.T) {
+		data := encodeAccount(t, base)
+		data = append(data, byte(AccountTypeAccount))
+
+		withheld := make([]byte, 8)
+		binary.LittleEndian.PutUint64(withheld, 1500)
+		data = appendTLV(data, ExtensionTransferFeeAmount, withheld)
+		data = appendTLV(data, ExtensionNonTransferableAccount, nil)
+		data = appendTLV(data, ExtensionImmutableOwner, nil)
+
+		account, extensions, err := DecodeAccount(data)
+		require.NoError(t, err)
+		assert.Equal(t, base.Mint, account.Mint)
+		assert.Len(t, extensions, 3)
+
+		summary := SummarizeAccountExtensions(extensions)
+		require.NotNil(t, summary)
+		assert.Equal(t, uint64(1500), summary.TransferFeeWithheld)
+		assert.True(t, summary.NonTransferable)
+		assert.True(t, summary.ImmutableOwner)
+		assert.Equal(t, []string{"non-transferable", "fee-withheld:1500"}, summary.Flags())
+	})
+
+	t.Run("Wrong account type", func(t *testing.T) {
+		data := append(encodeAccount(t, base), byte(AccountTypeMint))
+		_, _, err := DecodeAccount(data)
+		assert.Error(t, err)
+	})
+
+	t.Run("Truncated extension", func(t *testing.T) {
+		data := append(encodeAccount(t, base), byte(AccountTypeAccount))
+		data = appendTLV(data, ExtensionTransferFeeAmount, make([]byte, 8))
+		_, _, err := DecodeAccount(data[:len(data)-4])
+		assert.Error(t, err)
+	})
+
+	t.Run("Too short", func(t *testing.T) {
+		_, _, err := DecodeAccount(make([]byte, 10))
+		assert.Error(t, err)
+	})
+}
+
+func TestDecodeMint(t *testing.T) {
+	authority := solana.NewWallet().PublicKey()
+	base := token.Mint{
+		MintAuthority: &authority,
+		Supply:        1_000_000,
+		Decimals:      6,
+		IsInitialized: true,
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, bin.NewBinEncoder(&buf).Encode(base))
+
+	t.Run("Legacy mint", func(t *testing.T) {
+		mint, extensions, err := DecodeMint(buf.Bytes())
+		require.NoError(t, err)
+		assert.Equal(t, uint8(6), mint.Decimals)
+		assert.Equal(t, uint64(1_000_000), mint.Supply)
+		assert.Equal(t, authority, *mint.MintAuthority)
+		assert.Nil(t, mint.FreezeAuthority)
+		assert.Empty(t, extensions)
+	})
+
+	t.Run("Token-2022 mint with extensions", func(t *testing.T) {
+		data := make([]byte, AccountLen)
+		copy(data, buf.Bytes())
+		data = append(data, byte(AccountTypeMint))
+		data = appendTLV(data, ExtensionNonTransferable, nil)
+
+		mint, extensions, err := DecodeMint(data)
+		require.NoError(t, err)
+		assert.Equal(t, uint8(6), mint.Decimals)
+		require.Len(t, extensions, 1)
+		assert.Equal(t, ExtensionNonTransferable, extensions[0].Type)
+	})
+}
+
+func TestMergeAccountExtensions(t *testing.T) {
+	first := &AccountExtensions{Types: []string{"transfer_fee_amount"}, TransferFeeWithheld: 100}
+	second := &AccountExtensions{
+		Types:                []string{"transfer_fee_amount", "memo_transfer", "non_transferable_account"},
+		TransferFeeWithheld:  50,
+		NonTransferable:      true,
+		MemoTransferRequired: true,
+	}
+
+	merged := first.Merge(second)
+	assert.Equal(t, []string{"transfer_fee_amount", "memo_transfer", "non_transferable_account"}, merged.Types)
+	assert.Equal(t, uint64(150), merged.TransferFeeWithheld)
+	assert.True(t, merged.NonTransferable)
+	assert.True(t, merged.MemoTransferRequired)
+	assert.Equal(t, []string{"transfer_fee_amount"}, first.Types, "merging must not modify the first summary")
+
+	var none *AccountExtensions
+	assert.Same(t, second, none.Merge(second))
+	assert.Same(t, first, first.Merge(nil))
+}
+
+func TestSummarizeMintExtensions(t *testing.T) {
+	feeAuthority := solana.NewWallet().PublicKey()
+	hookProgram := solana.NewWallet().PublicKey()
+
+	transferFee := make([]byte, transferFeeConfigLen)
+	copy(transferFee, feeAuthority[:])
+	binary.LittleEndian.PutUint64(transferFee[transferFeeNewerOffset+8:], 5_000)
+	binary.LittleEndian.PutUint16(transferFee[transferFeeNewerOffset+16:], 250)
+	transferHook := make([]byte, transferHookLen)
+	copy(transferHook[32:], hookProgram[:])
+
+	summary := SummarizeMintExtensions([]Extension{
+		{Type: ExtensionTransferFeeConfig, Data: transferFee},
+		{Type: ExtensionTransferHook, Data: transferHook},
+		{Type: ExtensionDefaultAccountState, Data: []byte{accountStateFrozen}},
+	})
+	require.NotNil(t, summary)
+	assert.Equal(t, uint16(250), summary.TransferFeeBasisPoints)
+	assert.Equal(t, uint64(5_000), summary.TransferFeeMaximum)
+	assert.Equal(t, feeAuthority.String(), summary.TransferFeeAuthority)
+	assert.Equal(t, hookProgram.String(), summary.TransferHookProgram)
+	assert.Empty(t, summary.TransferHookAuthority)
+	assert.True(t, summary.DefaultFrozen)
+	assert.Equal(t, []string{"transfer-fee:250bps", "transfer-hook:" + hookProgram.String(), "default-frozen"}, summary.Flags())
+
+	assert.Nil(t, SummarizeMintExtensions(nil))
+}
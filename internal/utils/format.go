@@ -3,6 +3,8 @@ package utils
 import (
 	"fmt"
 	"math"
+	"strings"
+	"time"
 )
 
 // FormatTokenAmount 将代币数量格式化为带适当后缀（K、M）及小数的字符串
@@ -27,3 +29,15 @@ func FormatTokenAmount(amount uint64, decimals uint8) string {
 	// 使用标准格式，最多保留 4 位小数
 	return fmt.Sprintf("%.4f", value)
 }
+
+// FormatDuration 将时长格式化为去掉零值单位的字符串，例如 1h0m0s 显示为 1h
+func FormatDuration(d time.Duration) string {
+	formatted := d.String()
+	if strings.HasSuffix(formatted, "m0s") {
+		formatted = strings.TrimSuffix(formatted, "0s")
+	}
+	if strings.HasSuffix(formatted, "h0m") {
+		formatted = strings.TrimSuffix(formatted, "0m")
+	}
+	return formatted
+}
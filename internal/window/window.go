@@ -0,0 +1,210 @@
+package window
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// 滚动窗口事件类型
+const (
+	EventAccumulation = "accumulation"
+	EventDistribution = "distribution"
+)
+
+// Window 为一个滚动窗口及其告警阈值
+type Window struct {
+	Duration  time.Duration
+	Threshold float64 // 窗口内累计变化达到该比例时告警，例如 0.20 表示 20%
+	MinUSD    float64 // 累计变化的美元价值下限，0 或价格未知时不限制
+}
+
+// Holding 为本次观察到的一个持仓
+type Holding struct {
+	Balance  uint64
+	Decimals uint8
+	USDPrice float64
+}
+
+// Sample 记录余额变为 Balance 的时间，只在余额变化时记录
+type Sample struct {
+	Time    time.Time `json:"time"`
+	Balance uint64    `json:"balance"`
+}
+
+// Series 为某钱包持有某铸币的余额变化序列
+type Series struct {
+	Samples []Sample `json:"samples"`
+	// 窗口长度 -> 已告警的事件类型。累计变化回落到阈值以下后清除，避免持续越过阈值时重复告警
+	Fired map[string]string `json:"fired,omitempty"`
+}
+
+// State 为全部钱包的窗口状态：钱包 -> 铸币 -> 余额序列
+type State map[string]map[string]*Series
+
+// Event 描述一个窗口内越过阈值的累计变化
+type Event struct {
+	Type          string
+	Wallet        string
+	Mint          string
+	Window        time.Duration
+	Start         time.Time // 基线余额的时间，观察时间不足一个窗口时晚于窗口起点
+	OldBalance    uint64
+	NewBalance    uint64
+	ChangePercent float64
+	Inflow        uint64 // 窗口内增加的数量之和
+	Outflow       uint64 // 窗口内减少的数量之和
+	Movements     int    // 窗口内余额变化的次数
+}
+
+// Observe 记录钱包本次扫描的持仓并返回越过阈值的窗口事件。
+// 已记录但本次不再持有的铸币按余额为 0 处理。只有窗口内至少两次余额变化的累计才会告警，
+// 单次变化由逐次扫描的检测负责
+func (s State) Observe(wallet string, holdings map[string]Holding, windows []Window, now time.Time) []Event {
+	series, exists := s[wallet]
+	if !exists {
+		series = make(map[string]*Series)
+		s[wallet] = series
+	}
+
+	mints := make(map[string]bool, len(holdings)+len(series))
+	for mint := range holdings {
+		mints[mint] = true
+	}
+	for mint := range series {
+		mints[mint] = true
+	}
+
+	var events []Event
+	for mint := range mints {
+		holding := holdings[mint]
+		current, exists := series[mint]
+		if !exists {
+			if holding.Balance == 0 {
+				continue
+			}
+			current = &Series{}
+			series[mint] = current
+		}
+		current.record(holding.Balance, now)
+
+		for _, w := range windows {
+			event, crossed := current.evaluate(w, holding, now)
+			key := w.Duration.String()
+			if !crossed {
+				delete(current.Fired, key)
+				continue
+			}
+			if current.Fired[key] == event.Type {
+				continue
+			}
+			if current.Fired == nil {
+				current.Fired = make(map[string]string)
+			}
+			current.Fired[key] = event.Type
+
+			event.Wallet = wallet
+			event.Mint = mint
+			events = append(events, event)
+		}
+		current.prune(longest(windows), now)
+		if len(current.Samples) == 0 {
+			delete(series, mint)
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].Mint != events[j].Mint {
+			return events[i].Mint < events[j].Mint
+		}
+		return events[i].Window < events[j].Window
+	})
+	return events
+}
+
+// record 在余额变化时追加样本
+func (s *Series) record(balance uint64, now time.Time) {
+	if n := len(s.Samples); n > 0 && s.Samples[n-1].Balance == balance {
+		return
+	}
+	s.Samples = append(s.Samples, Sample{Time: now, Balance: balance})
+}
+
+// evaluate 计算窗口内的累计变化，判断是否越过阈值
+func (s *Series) evaluate(w Window, holding Holding, now time.Time) (Event, bool) {
+	// 基线为窗口起点时的余额，即起点之前最后一个样本；观察时间不足一个窗口时为第一个样本
+	start := now.Add(-w.Duration)
+	base := 0
+	for i, sample := range s.Samples {
+		if sample.Time.After(start) {
+			break
+		}
+		base = i
+	}
+
+	event := Event{
+		Window:     w.Duration,
+		Start:      s.Samples[base].Time,
+		OldBalance: s.Samples[base].Balance,
+		NewBalance: s.Samples[len(s.Samples)-1].Balance,
+	}
+	previous := event.OldBalance
+	for _, sample := range s.Samples[base+1:] {
+		if sample.Balance > previous {
+			event.Inflow += sample.Balance - previous
+		} else {
+			event.Outflow += previous - sample.Balance
+		}
+		previous = sample.Balance
+		event.Movements++
+	}
+	if event.Movements < 2 || event.NewBalance == event.OldBalance {
+		return event, false
+	}
+
+	event.Type = EventAccumulation
+	if event.NewBalance < event.OldBalance {
+		event.Type = EventDistribution
+	}
+	if event.OldBalance == 0 {
+		event.ChangePercent = 100
+	} else {
+		event.ChangePercent = (float64(event.NewBalance) - float64(event.OldBalance)) / float64(event.OldBalance) * 100
+	}
+	if math.Abs(event.ChangePercent) < w.Threshold*100 {
+		return event, false
+	}
+
+	if w.MinUSD > 0 && holding.USDPrice > 0 {
+		delta := math.Abs(float64(event.NewBalance) - float64(event.OldBalance))
+		if delta/math.Pow(10, float64(holding.Decimals))*holding.USDPrice < w.MinUSD {
+			return event, false
+		}
+	}
+	return event, true
+}
+
+// prune 删除最长窗口之外的样本，保留窗口起点之前的最后一个样本作为基线。
+// 余额已为 0 且在整个窗口内未再变化的序列被清空
+func (s *Series) prune(longest time.Duration, now time.Time) {
+	start := now.Add(-longest)
+	drop := 0
+	for i := 1; i < len(s.Samples) && !s.Samples[i].Time.After(start); i++ {
+		drop = i
+	}
+	s.Samples = s.Samples[drop:]
+
+	if len(s.Samples) == 1 && s.Samples[0].Balance == 0 && !s.Samples[0].Time.After(start) {
+		s.Samples = nil
+	}
+}
+
+func longest(windows []Window) time.Duration {
+	var max time.Duration
+	for _, w := range windows {
+		if w.Duration > max {
+			max = w.Duration
+		}
+	}
+	return max
+}
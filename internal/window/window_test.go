@@ -0,0 +1,121 @@
+package window
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObserveSlowSelling(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	windows := []Window{
+		{Duration: 15 * time.Minute, Threshold: 0.20},
+		{Duration: time.Hour, Threshold: 0.50},
+	}
+
+	// 每分钟卖出 4%，单次变化从不越过 20%
+	state := make(State)
+	balance := 1_000_000.0
+	fired := make(map[time.Duration]int)
+	var first []Event
+	for minute := 0; minute <= 60; minute++ {
+		events := state.Observe("w", map[string]Holding{"x": {Balance: uint64(balance)}}, windows, start.Add(time.Duration(minute)*time.Minute))
+		for _, event := range events {
+			if fired[event.Window] == 0 {
+				first = append(first, event)
+			}
+			fired[event.Window]++
+		}
+		balance *= 0.96
+	}
+
+	// 每个窗口在越过阈值时只告警一次
+	assert.Equal(t, map[time.Duration]int{15 * time.Minute: 1, time.Hour: 1}, fired)
+	require.Len(t, first, 2)
+
+	short := first[0]
+	assert.Equal(t, EventDistribution, short.Type)
+	assert.Equal(t, "w", short.Wallet)
+	assert.Equal(t, "x", short.Mint)
+	assert.Equal(t, 15*time.Minute, short.Window)
+	assert.LessOrEqual(t, short.ChangePercent, -20.0)
+	assert.Greater(t, short.ChangePercent, -25.0)
+	assert.Equal(t, short.OldBalance-short.NewBalance, short.Outflow)
+	assert.Zero(t, short.Inflow)
+	assert.GreaterOrEqual(t, short.Movements, 2)
+
+	long := first[1]
+	assert.Equal(t, time.Hour, long.Window)
+	assert.LessOrEqual(t, long.ChangePercent, -50.0)
+	assert.Equal(t, start, long.Start)
+}
+
+func TestObserveThresholds(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	hour := []Window{{Duration: time.Hour, Threshold: 0.20, MinUSD: 100}}
+
+	tests := []struct {
+		name     string
+		balances []uint64
+		price    float64
+		want     []string
+	}{
+		{name: "single step is left to per-scan detection", balances: []uint64{1_000_000, 500_000}, price: 1},
+		{name: "accumulation", balances: []uint64{1_000_000, 1_100_000, 1_300_000}, price: 1000, want: []string{EventAccumulation}},
+		{name: "below min usd", balances: []uint64{1_000_000, 1_100_000, 1_300_000}, price: 1},
+		{name: "unknown price is not usd gated", balances: []uint64{1_000_000, 1_100_000, 1_300_000}, want: []string{EventAccumulation}},
+		{name: "round trip", balances: []uint64{1_000_000, 500_000, 1_000_000}, price: 1000},
+		{name: "exit", balances: []uint64{1_000_000, 800_000, 0}, price: 1000, want: []string{EventDistribution}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := make(State)
+			var got []string
+			for i, balance := range tt.balances {
+				holdings := map[string]Holding{}
+				if balance > 0 {
+					holdings["x"] = Holding{Balance: balance, Decimals: 6, USDPrice: tt.price}
+				}
+				for _, event := range state.Observe("w", holdings, hour, start.Add(time.Duration(i)*time.Minute)) {
+					got = append(got, event.Type)
+				}
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestObservePersistsAcrossRestarts(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	windows := []Window{{Duration: time.Hour, Threshold: 0.20}}
+
+	state := make(State)
+	assert.Empty(t, state.Observe("w", map[string]Holding{"x": {Balance: 1000}}, windows, start))
+	assert.Empty(t, state.Observe("w", map[string]Holding{"x": {Balance: 900}}, windows, start.Add(10*time.Minute)))
+
+	content, err := json.Marshal(state)
+	require.NoError(t, err)
+	restored := make(State)
+	require.NoError(t, json.Unmarshal(content, &restored))
+
+	events := restored.Observe("w", map[string]Holding{"x": {Balance: 700}}, windows, start.Add(20*time.Minute))
+	require.Len(t, events, 1)
+	assert.Equal(t, uint64(1000), events[0].OldBalance)
+	assert.Equal(t, 2, events[0].Movements)
+
+	// 已告警的状态同样被保存，重启后不会重复告警
+	content, err = json.Marshal(restored)
+	require.NoError(t, err)
+	state = make(State)
+	require.NoError(t, json.Unmarshal(content, &state))
+	assert.Empty(t, state.Observe("w", map[string]Holding{"x": {Balance: 700}}, windows, start.Add(30*time.Minute)))
+
+	// 窗口之外的样本被清理，清仓且超出窗口的序列被删除
+	state.Observe("w", map[string]Holding{}, windows, start.Add(40*time.Minute))
+	state.Observe("w", map[string]Holding{}, windows, start.Add(3*time.Hour))
+	assert.Empty(t, state["w"])
+}